@@ -44,6 +44,10 @@ type BundleBuilder struct {
 	// ComponentFiles represent ComponentBuilder or Component types that are
 	// referenced via file urls.
 	ComponentFiles []File `json:"componentFiles,omitempty"`
+
+	// PatchTemplates are copied to the resulting Bundle. See
+	// Bundle.PatchTemplates for more details.
+	PatchTemplates []*PatchTemplate `json:"patchTemplates,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -46,4 +46,10 @@ type Bundle struct {
 	// components must be unique based on the combination of ComponentName +
 	// Version.
 	Components []*Component `json:"components,omitempty"`
+
+	// PatchTemplates are applied to the objects of every component in the
+	// bundle, after the PatchTemplates of the components themselves. Their
+	// selectors may use ComponentNames and ComponentVersions to limit which
+	// components are patched.
+	PatchTemplates []*PatchTemplate `json:"patchTemplates,omitempty"`
 }
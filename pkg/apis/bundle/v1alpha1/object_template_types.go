@@ -31,6 +31,10 @@ const (
 
 	// TemplateTypeJsonnet represents a jsonnet type.
 	TemplateTypeJsonnet TemplateType = "jsonnet"
+
+	// TemplateTypeStarlark represents a Starlark script that generates a list
+	// of objects from the options.
+	TemplateTypeStarlark TemplateType = "starlark"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -21,23 +21,44 @@ import (
 
 // ObjectSelector is used for identifying Objects on which to apply the patch template.
 type ObjectSelector struct {
-	// Kinds represent the Kinds to match.
+	// Kinds represent the Kinds to match. A kind can be unqualified
+	// ("Deployment"), qualified by group ("Deployment.apps") or qualified by
+	// apiVersion ("apps/v1,Deployment"). Each part may be a glob or a regular
+	// expression, as described for Names.
 	Kinds []string `json:"kinds,omitempty"`
 
-	// Names represent the metadata.names to match.
+	// Names represent the metadata.names to match. Names containing '*', '?'
+	// or '[' are globs, and names with the prefix "regex:" are regular
+	// expressions that must match the whole name.
 	Names []string `json:"names,omitempty"`
 
-	// Annotations contain key/value pairs to match. An empty string value matches
-	// all annotation-values for a particular key.
+	// Annotations contain key/value pairs to match. All of the pairs must
+	// match. An empty string value matches all annotation-values for a
+	// particular key.
 	Annotations map[string]string `json:"annotations,omitempty"`
 
-	// Labels contain key/value pairs to match. An empty string value matches
-	// all label-values for a particular key.
+	// Labels contain key/value pairs to match. All of the pairs must match. An
+	// empty string value matches all label-values for a particular key.
 	Labels map[string]string `json:"labels,omitempty"`
 
+	// MatchExpressions are Kubernetes label selector requirements, using the
+	// In, NotIn, Exists and DoesNotExist operators. All of the requirements
+	// must match.
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+
 	// Namespaces to match.
 	Namespaces []string `json:"namespaces,omitempty"`
 
+	// ComponentNames represent the names of the components whose objects
+	// should match, and may be patterns as described for Names. They are
+	// mostly useful for PatchTemplates in a Bundle, which apply to the objects
+	// of every component.
+	ComponentNames []string `json:"componentNames,omitempty"`
+
+	// ComponentVersions represent the versions of the components whose objects
+	// should match, and may be patterns as described for Names.
+	ComponentVersions []string `json:"componentVersions,omitempty"`
+
 	// InvertMatch inverts the match. By default, the ObjectSelector will include
 	// objects matching all of the criteria above. This flag indicates that objects
 	// NOT matching the criteria should be included instead.
@@ -71,6 +92,11 @@ type PatchTemplate struct {
 	//
 	// If either APIVersion or Kind are present in the Template, they will be
 	// removed during patch-appllication and added to the ObjectSelector.
+	//
+	// The Template may render a multi-document YAML stream, for example by
+	// ranging over a list of options, in which case each document is a
+	// separate patch. The metadata.name of each document, if present, further
+	// restricts the patch to objects with that name.
 	Template string `json:"template,omitempty"`
 
 	// PatchType represents how patches are applied. If not specified, use
@@ -0,0 +1,97 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReplacementPhase indicates when replacements are applied, relative to
+// PatchTemplates.
+type ReplacementPhase string
+
+const (
+	// ReplacementPhaseBeforePatches applies replacements after ObjectTemplates
+	// are rendered but before PatchTemplates are applied.
+	ReplacementPhaseBeforePatches ReplacementPhase = "BeforePatches"
+
+	// ReplacementPhaseAfterPatches applies replacements after PatchTemplates
+	// are applied. This is the default.
+	ReplacementPhaseAfterPatches ReplacementPhase = "AfterPatches"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ReplacementTemplate copies values from fields of one object in a component
+// into fields of other objects in the same component. For example, it can
+// copy the name of a Service into an environment variable of a Deployment.
+type ReplacementTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Phase indicates when the replacements are applied. If not specified,
+	// they are applied after PatchTemplates.
+	Phase ReplacementPhase `json:"phase,omitempty"`
+
+	// Replacements to apply, in order.
+	Replacements []Replacement `json:"replacements,omitempty"`
+}
+
+// Replacement copies a single value from a source object into the fields of
+// the target objects.
+type Replacement struct {
+	// Source identifies the object and field whose value is copied.
+	Source ReplacementSource `json:"source,omitempty"`
+
+	// Targets identify the objects and fields that receive the value.
+	Targets []ReplacementTarget `json:"targets,omitempty"`
+}
+
+// ReplacementSource identifies the object and field whose value is copied.
+// Exactly one object in the component must match the Kind and Name.
+type ReplacementSource struct {
+	// Kind of the source object, in any of the forms described for
+	// ObjectSelector.Kinds.
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the source object, in any of the forms described for
+	// ObjectSelector.Names.
+	Name string `json:"name,omitempty"`
+
+	// FieldPath is the path of the field whose value is copied. Path elements
+	// are separated by dots. A path element of the form '[key=value]'
+	// selects the element of a list whose field 'key' is 'value', and an
+	// integer path element selects the element of a list by index. For
+	// example, 'spec.containers.[name=app].image'. If not specified, the
+	// FieldPath is 'metadata.name'.
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+// ReplacementTarget identifies the objects and fields that receive the value.
+type ReplacementTarget struct {
+	// Selector identifies the target objects. If not specified, every object
+	// except the source and the templates is a target. Templates are only
+	// targets if the selector's Kinds name their kind.
+	Selector *ObjectSelector `json:"selector,omitempty"`
+
+	// FieldPaths are the paths of the fields that are set to the value, in the
+	// form described for ReplacementSource.FieldPath.
+	FieldPaths []string `json:"fieldPaths,omitempty"`
+
+	// Create indicates whether to create fields that don't exist in a target
+	// object. List elements are never created. By default, targets without
+	// the field are left unchanged.
+	Create bool `json:"create,omitempty"`
+}
@@ -20,6 +20,7 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -40,6 +41,17 @@ func (in *Bundle) DeepCopyInto(out *Bundle) {
 			}
 		}
 	}
+	if in.PatchTemplates != nil {
+		in, out := &in.PatchTemplates, &out.PatchTemplates
+		*out = make([]*PatchTemplate, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(PatchTemplate)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
 	return
 }
 
@@ -71,6 +83,17 @@ func (in *BundleBuilder) DeepCopyInto(out *BundleBuilder) {
 		*out = make([]File, len(*in))
 		copy(*out, *in)
 	}
+	if in.PatchTemplates != nil {
+		in, out := &in.PatchTemplates, &out.PatchTemplates
+		*out = make([]*PatchTemplate, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(PatchTemplate)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
 	return
 }
 
@@ -430,11 +453,28 @@ func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
 			(*out)[key] = val
 		}
 	}
+	if in.MatchExpressions != nil {
+		in, out := &in.MatchExpressions, &out.MatchExpressions
+		*out = make([]v1.LabelSelectorRequirement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Namespaces != nil {
 		in, out := &in.Namespaces, &out.Namespaces
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ComponentNames != nil {
+		in, out := &in.ComponentNames, &out.ComponentNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ComponentVersions != nil {
+		in, out := &in.ComponentVersions, &out.ComponentVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.InvertMatch != nil {
 		in, out := &in.InvertMatch, &out.InvertMatch
 		*out = new(bool)
@@ -588,6 +628,105 @@ func (in *PatchTemplateBuilder) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Replacement) DeepCopyInto(out *Replacement) {
+	*out = *in
+	out.Source = in.Source
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]ReplacementTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Replacement.
+func (in *Replacement) DeepCopy() *Replacement {
+	if in == nil {
+		return nil
+	}
+	out := new(Replacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplacementSource) DeepCopyInto(out *ReplacementSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplacementSource.
+func (in *ReplacementSource) DeepCopy() *ReplacementSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplacementSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplacementTarget) DeepCopyInto(out *ReplacementTarget) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(ObjectSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FieldPaths != nil {
+		in, out := &in.FieldPaths, &out.FieldPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplacementTarget.
+func (in *ReplacementTarget) DeepCopy() *ReplacementTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplacementTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplacementTemplate) DeepCopyInto(out *ReplacementTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Replacements != nil {
+		in, out := &in.Replacements, &out.Replacements
+		*out = make([]Replacement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplacementTemplate.
+func (in *ReplacementTemplate) DeepCopy() *ReplacementTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplacementTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReplacementTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Requirements) DeepCopyInto(out *Requirements) {
 	*out = *in
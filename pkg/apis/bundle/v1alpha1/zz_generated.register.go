@@ -67,6 +67,7 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&ObjectTemplateBuilder{},
 		&PatchTemplate{},
 		&PatchTemplateBuilder{},
+		&ReplacementTemplate{},
 		&Requirements{},
 	)
 	// AddToGroupVersion allows the serialization of client types like ListOptions.
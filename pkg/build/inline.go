@@ -130,6 +130,9 @@ func (n *Inliner) BundleFiles(ctx context.Context, data *bundle.BundleBuilder, b
 		Version:    data.Version,
 		Components: comps,
 	}
+	for _, pt := range data.PatchTemplates {
+		newBundle.PatchTemplates = append(newBundle.PatchTemplates, pt.DeepCopy())
+	}
 	return newBundle, nil
 }
 
@@ -29,11 +29,35 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PatchBuildConfig is a config option that can be passed to PatchTemplate,
+// ComponentPatchTemplates and AllPatchTemplates.
+type PatchBuildConfig func(*patchBuildConfig)
+
+// patchBuildConfig configures the building of PatchTemplates.
+type patchBuildConfig struct {
+	// limits bound the execution of the PatchTemplateBuilder templates.
+	limits options.TemplateLimits
+}
+
+// WithTemplateLimits bounds the execution of each PatchTemplateBuilder
+// template with the specified limits. Exceeding a limit results in an
+// *options.TemplateLimitError.
+func WithTemplateLimits(limits options.TemplateLimits) PatchBuildConfig {
+	return func(c *patchBuildConfig) {
+		c.limits = limits
+	}
+}
+
 // PatchTemplate renders a PatchTemplate from a PatchTemplateBuilder and options.
 //
 // If the PatchTemplateBuilder has the annotation `bundle.gke.io/safe-yaml`,
 // then yaml-templater will use safe-yaml templater.
-func PatchTemplate(ptb *bundle.PatchTemplateBuilder, opts options.JSONOptions) (*bundle.PatchTemplate, error) {
+func PatchTemplate(ptb *bundle.PatchTemplateBuilder, opts options.JSONOptions, cfgs ...PatchBuildConfig) (*bundle.PatchTemplate, error) {
+	cfg := &patchBuildConfig{}
+	for _, c := range cfgs {
+		c(cfg)
+	}
+
 	name := ptb.GetName()
 	if ptb.Template == "" {
 		return nil, fmt.Errorf("cannot build PatchTemplate from PatchTemplateBuilder %q: it has an empty template", name)
@@ -63,11 +87,11 @@ func PatchTemplate(ptb *bundle.PatchTemplateBuilder, opts options.JSONOptions) (
 		addParamDefaults("", ptb.TargetSchema.Properties, opts)
 	}
 
-	tmpl = tmpl.Option("missingkey=error")
+	tmpl = tmpl.Option("missingkey=error").Limits(cfg.limits)
 	var buf bytes.Buffer
 	err = tmpl.Execute(&buf, opts)
 	if err != nil {
-		return nil, fmt.Errorf("cannot build PatchTemplate from PatchTemplateBuilder %q: error executing template: %v", name, err)
+		return nil, fmt.Errorf("cannot build PatchTemplate from PatchTemplateBuilder %q: error executing template: %w", name, err)
 	}
 
 	pt := &bundle.PatchTemplate{
@@ -86,7 +110,7 @@ func PatchTemplate(ptb *bundle.PatchTemplateBuilder, opts options.JSONOptions) (
 
 // ComponentPatchTemplates iterates through all PatchTemplateBuilders in a
 // Components Objects, and converts them into PatchTemplates.
-func ComponentPatchTemplates(c *bundle.Component, fopts *filter.Options, opts options.JSONOptions) (*bundle.Component, error) {
+func ComponentPatchTemplates(c *bundle.Component, fopts *filter.Options, opts options.JSONOptions, cfgs ...PatchBuildConfig) (*bundle.Component, error) {
 	ptbFilter := fopts
 	if ptbFilter == nil {
 		ptbFilter = &filter.Options{}
@@ -106,7 +130,7 @@ func ComponentPatchTemplates(c *bundle.Component, fopts *filter.Options, opts op
 			return nil, err
 		}
 
-		pt, err := PatchTemplate(&ptb, opts)
+		pt, err := PatchTemplate(&ptb, opts, cfgs...)
 		if err != nil {
 			return nil, err
 		}
@@ -126,10 +150,10 @@ func ComponentPatchTemplates(c *bundle.Component, fopts *filter.Options, opts op
 
 // AllPatchTemplates is a convenience method to build all PatchTemplateBuilders into
 // PatchTemplates for all Components in a Bundle.
-func AllPatchTemplates(bw *wrapper.BundleWrapper, fopts *filter.Options, opts options.JSONOptions) (*wrapper.BundleWrapper, error) {
+func AllPatchTemplates(bw *wrapper.BundleWrapper, fopts *filter.Options, opts options.JSONOptions, cfgs ...PatchBuildConfig) (*wrapper.BundleWrapper, error) {
 	switch bw.Kind() {
 	case "Component":
-		comp, err := ComponentPatchTemplates(bw.Component(), fopts, opts)
+		comp, err := ComponentPatchTemplates(bw.Component(), fopts, opts, cfgs...)
 		if err != nil {
 			return nil, err
 		}
@@ -138,7 +162,7 @@ func AllPatchTemplates(bw *wrapper.BundleWrapper, fopts *filter.Options, opts op
 		bun := bw.Bundle()
 		var comps []*bundle.Component
 		for _, comp := range bun.Components {
-			comp, err := ComponentPatchTemplates(comp, fopts, opts)
+			comp, err := ComponentPatchTemplates(comp, fopts, opts, cfgs...)
 			if err != nil {
 				return nil, err
 			}
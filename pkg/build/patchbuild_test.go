@@ -15,11 +15,14 @@
 package build
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
 	"github.com/google/go-cmp/cmp"
 )
@@ -515,3 +518,36 @@ spec:
 		})
 	}
 }
+
+func TestPatchTemplateLimits(t *testing.T) {
+	ptb := &bundle.PatchTemplateBuilder{}
+	err := converter.FromYAMLString(`
+kind: PatchTemplateBuilder
+apiVersion: bundle.gke.io/v1alpha1
+metadata:
+  name: nested
+template: |
+  kind: Pod
+  metadata:
+    annotations:
+      {{- range .Items}}{{range $.Items}}{{with .}}
+      a: b{{end}}{{end}}{{end}}
+`).ToObject(ptb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options.JSONOptions{"Items": []interface{}{"x"}}
+
+	if _, err := PatchTemplate(ptb, opts, WithTemplateLimits(options.TemplateLimits{MaxDepth: 3})); err != nil {
+		t.Fatalf("PatchTemplate() within limits: got error %v", err)
+	}
+
+	_, err = PatchTemplate(ptb, opts, WithTemplateLimits(options.TemplateLimits{MaxDepth: 2}))
+	var lerr *options.TemplateLimitError
+	if !errors.As(err, &lerr) || lerr.Limit != options.TemplateDepthLimit {
+		t.Fatalf("PatchTemplate() exceeding depth: got error %v, expected a TemplateLimitError for %s", err, options.TemplateDepthLimit)
+	}
+	if !strings.Contains(err.Error(), `PatchTemplateBuilder "nested"`) {
+		t.Errorf("got error %q, expected it to name the PatchTemplateBuilder", err)
+	}
+}
@@ -32,8 +32,11 @@ type options struct {
 	// apply to PatchTemplates
 	// TODO(jbelamaric): Make this a list of files
 	optionsFile string
-}
 
+	// setFlags contains individual option overrides, applied after the options
+	// file.
+	setFlags cmdlib.SetFlags
+}
 
 func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopt *cmdlib.GlobalOptions) {
 	brw := cmdlib.NewBundleReaderWriter(fio, sio)
@@ -55,14 +58,14 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, rw file
 		optFiles = []string{o.optionsFile}
 	}
 
-	buildOpts, err := cmdlib.MergeOptions(ctx, rw, optFiles)
+	buildOpts, redactor, err := cmdlib.ReadOptions(ctx, rw, optFiles, &o.setFlags)
 	if err != nil {
 		return err
 	}
 
 	bw, err = build.AllPatchTemplates(bw, &filter.Options{}, buildOpts)
 	if err != nil {
-		return err
+		return redactor.RedactError(err)
 	}
 
 	return brw.WriteBundleData(ctx, bw, gopt)
@@ -36,5 +36,6 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 	}
 	// While options-file is technically optional, it is usually provided to detemplatize the patch templates.
 	cmd.Flags().StringVarP(&opts.optionsFile, "options-file", "", "", "File containing options to apply to patch templates")
+	cmdlib.AddSetFlags(cmd, &opts.setFlags)
 	return cmd
 }
@@ -21,8 +21,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	log "k8s.io/klog"
 
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
@@ -72,6 +74,7 @@ type fileInliner interface {
 // reading / writing bundle data.
 type BundleReaderWriter interface {
 	ReadBundleData(context.Context, *GlobalOptions) (*wrapper.BundleWrapper, error)
+	ReadRawBundleData(context.Context, *GlobalOptions) (*wrapper.BundleWrapper, error)
 	WriteBundleData(context.Context, *wrapper.BundleWrapper, *GlobalOptions) error
 	WriteStructuredContents(context.Context, interface{}, *GlobalOptions) error
 }
@@ -94,7 +97,25 @@ func NewBundleReaderWriter(rw files.FileReaderWriter, stdio StdioReaderWriter) B
 }
 
 // ReadBundleData reads either data file contents from a file or stdin.
+// BundleBuilders and ComponentBuilders read from a file are inlined.
 func (brw *realBundleReaderWriter) ReadBundleData(ctx context.Context, g *GlobalOptions) (*wrapper.BundleWrapper, error) {
+	bw, err := brw.ReadRawBundleData(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	// For now, we can only inline component data files because we need the path
+	// context.
+	if g.InputFile != "" && (bw.BundleBuilder() != nil || bw.ComponentBuilder() != nil) {
+		return brw.inlineData(ctx, bw, g)
+	}
+	return bw, nil
+}
+
+// ReadRawBundleData reads either data file contents from a file or stdin, the
+// same as ReadBundleData, except that BundleBuilders and ComponentBuilders
+// aren't inlined, so that no other files are read.
+func (brw *realBundleReaderWriter) ReadRawBundleData(ctx context.Context, g *GlobalOptions) (*wrapper.BundleWrapper, error) {
 	var bytes []byte
 	var err error
 	inFmt := g.InputFormat
@@ -120,17 +141,7 @@ func (brw *realBundleReaderWriter) ReadBundleData(ctx context.Context, g *Global
 		inFmt = "yaml"
 	}
 
-	bw, err := wrapper.FromRaw(inFmt, bytes)
-	if err != nil {
-		return nil, err
-	}
-
-	// For now, we can only inline component data files because we need the path
-	// context.
-	if g.InputFile != "" && (bw.BundleBuilder() != nil || bw.ComponentBuilder() != nil) {
-		return brw.inlineData(ctx, bw, g)
-	}
-	return bw, nil
+	return wrapper.FromRaw(inFmt, bytes)
 }
 
 // inlineData inlines a cluster bundle before processing
@@ -222,10 +233,12 @@ func ParseStringMap(p string) map[string]string {
 	return m
 }
 
-// MergeOptions reads multiple options files and combines them into a single map.
-// Option values will be overwritten if a later file has the same key.
+// MergeOptions reads multiple options files and deep-merges them into a single
+// map. Nested maps are merged recursively, while other values, including
+// lists, are overwritten if a later file has the same key. See options.Merge
+// for details.
 func MergeOptions(ctx context.Context, rw files.FileReaderWriter, files []string) (options.JSONOptions, error) {
-	optData := make(map[string]interface{})
+	optData := options.JSONOptions{}
 	for _, f := range files {
 		bytes, err := rw.ReadFile(ctx, f)
 		if err != nil {
@@ -235,9 +248,41 @@ func MergeOptions(ctx context.Context, rw files.FileReaderWriter, files []string
 		if err != nil {
 			return nil, err
 		}
-		for k, v := range data {
-			optData[k] = v
-		}
+		optData = options.Merge(optData, data)
 	}
 	return optData, nil
 }
+
+var multiDoc = regexp.MustCompile("(^|\n)---")
+var onlyWhitespace = regexp.MustCompile(`^\s*$`)
+
+// ReadObjects reads Kubernetes objects from files. YAML files may contain
+// multiple objects separated by '---'.
+func ReadObjects(ctx context.Context, rw files.FileReaderWriter, files []string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, f := range files {
+		contents, err := rw.ReadFile(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(f) == ".json" || !multiDoc.Match(contents) {
+			obj, err := converter.FromFileName(f, contents).ToUnstructured()
+			if err != nil {
+				return nil, fmt.Errorf("reading object from file %q: %v", f, err)
+			}
+			objs = append(objs, obj)
+			continue
+		}
+		for i, doc := range multiDoc.Split(string(contents), -1) {
+			if onlyWhitespace.MatchString(doc) {
+				continue
+			}
+			obj, err := converter.FromYAMLString(doc).ToUnstructured()
+			if err != nil {
+				return nil, fmt.Errorf("reading object number %d from file %q: %v", i, f, err)
+			}
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
@@ -268,6 +268,37 @@ spec:
 	}
 }
 
+func TestReadRawBundleData(t *testing.T) {
+	brw := &realBundleReaderWriter{
+		rw: &testutil.FakeFileReaderWriter{
+			AlwaysRead: componentBuilderEx,
+		},
+		stdio: &cmdtest.FakeStdioReaderWriter{},
+		makeInlinerFn: func(rw files.FileReaderWriter, inputFile string) fileInliner {
+			return &fakeInliner{
+				componentOut: componentEx,
+			}
+		},
+	}
+	opts := &GlobalOptions{InputFile: "/foo/bar/biff.yaml"}
+
+	data, err := brw.ReadRawBundleData(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.ComponentBuilder() == nil {
+		t.Errorf("got kind %q, expected the ComponentBuilder not to be inlined", data.Kind())
+	}
+
+	data, err = brw.ReadBundleData(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Component() == nil {
+		t.Errorf("got kind %q, expected the ComponentBuilder to be inlined", data.Kind())
+	}
+}
+
 func TestWriteBundleData(t *testing.T) {
 	testcases := []struct {
 		desc           string
@@ -363,3 +394,34 @@ func TestWriteBundleData(t *testing.T) {
 		})
 	}
 }
+
+func TestReadObjects(t *testing.T) {
+	rw := testutil.NewEmptyReaderWriter()
+	rw.AddReadFile(&testutil.FilePair{Path: "multi.yaml", Contents: `
+kind: Foo
+metadata:
+  name: foo
+---
+kind: Bar
+metadata:
+  name: bar
+---
+`})
+	rw.AddReadFile(&testutil.FilePair{Path: "single.json", Contents: `{"kind": "Biff", "metadata": {"name": "biff"}}`})
+
+	objs, err := ReadObjects(context.Background(), rw, []string{"multi.yaml", "single.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range objs {
+		got = append(got, o.GetKind()+"/"+o.GetName())
+	}
+	if exp := "Foo/foo,Bar/bar,Biff/biff"; strings.Join(got, ",") != exp {
+		t.Errorf("got objects %v, expected %s", got, exp)
+	}
+
+	if _, err := ReadObjects(context.Background(), rw, []string{"missing.yaml"}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/patchtmpl"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/valuefrom"
+)
+
+// ReadOptions reads and merges the options files, applies the --set flags and
+// then resolves any valueFrom references to secrets. The returned Redactor
+// should be used to redact errors produced while applying the options, since
+// they may contain the resolved values.
+func ReadOptions(ctx context.Context, rw files.FileReaderWriter, optFiles []string, s *SetFlags) (options.JSONOptions, *valuefrom.Redactor, error) {
+	opts, err := MergeOptions(ctx, rw, optFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err = ApplySetFlags(ctx, rw, opts, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return valuefrom.NewResolver(rw).Resolve(ctx, opts)
+}
+
+// PreflightOptions validates each component's options against the
+// OptionsSchema of every template in the component that matches the filter
+// options, before any templates are rendered. All the violations are reported
+// in a single error. A nil filter selects all templates.
+func PreflightOptions(comps []*bundle.Component, optsFn options.ComponentOptionsFunc, fopts *filter.Options) error {
+	var msgs []string
+	for _, comp := range comps {
+		opts, err := optsFn(comp)
+		if err != nil {
+			return fmt.Errorf("getting options for component %v: %v", comp.ComponentReference(), err)
+		}
+		if opts == nil {
+			continue
+		}
+		objs := filter.NewFilter().SelectObjects(comp.Spec.Objects, fopts)
+		schemas, err := openapi.TemplateSchemas(objs)
+		if err != nil {
+			return fmt.Errorf("for component %v: %v", comp.ComponentReference(), err)
+		}
+		for _, e := range openapi.ValidateTemplateOptions(opts, schemas) {
+			msgs = append(msgs, fmt.Sprintf("component %q: %v", comp.Spec.ComponentName, e))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("options failed validation with %d error(s):\n%s", len(msgs), strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// PreflightBundleOptions validates options as PreflightOptions does for the
+// components of a bundle, but also validates each component's options against
+// the OptionsSchemas of the bundle-level PatchTemplates, since those are
+// applied to the objects of every component.
+func PreflightBundleOptions(bun *bundle.Bundle, optsFn options.ComponentOptionsFunc, fopts *filter.Options) error {
+	var pts []*unstructured.Unstructured
+	for _, pt := range bun.PatchTemplates {
+		obj, err := patchtmpl.ToUnstructured(pt)
+		if err != nil {
+			return err
+		}
+		pts = append(pts, obj)
+	}
+	var comps []*bundle.Component
+	for _, comp := range bun.Components {
+		c := *comp
+		c.Spec.Objects = append(append([]*unstructured.Unstructured{}, comp.Spec.Objects...), pts...)
+		comps = append(comps, &c)
+	}
+	return PreflightOptions(comps, optsFn, fopts)
+}
+
+// PreflightScopes checks that every key of component-scoped options is either
+// the global scope or the name of a component, so that the options of a
+// misspelled component aren't silently ignored.
+func PreflightScopes(comps []*bundle.Component, scoped options.JSONOptions) error {
+	if unknown := options.UnknownScopes(scoped, comps); len(unknown) > 0 {
+		return fmt.Errorf("component-scoped options have keys that are neither %q nor a component name: %s",
+			options.GlobalScope, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdlib
+
+import (
+	"strings"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var preflightComponentEx = `
+apiVersion: bundle.gke.io/v1alpha1
+kind: Component
+spec:
+  componentName: test-comp
+  version: 0.1.0
+  objects:
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: ObjectTemplate
+    metadata:
+      name: obj-tmpl
+    optionsSchema:
+      required:
+      - Name
+      properties:
+        Name:
+          type: string
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: PatchTemplate
+    metadata:
+      name: patch-tmpl
+    optionsSchema:
+      properties:
+        Replicas:
+          type: integer`
+
+func TestPreflightOptions(t *testing.T) {
+	comp, err := converter.FromYAMLString(preflightComponentEx).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		desc         string
+		opts         options.JSONOptions
+		fopts        *filter.Options
+		expErrSubstr []string
+	}{
+		{
+			desc: "success",
+			opts: options.JSONOptions{"Name": "foo", "Replicas": 2},
+		},
+		{
+			desc: "success: no options",
+		},
+		{
+			desc: "errors from every template",
+			opts: options.JSONOptions{"Replicas": "two"},
+			expErrSubstr: []string{
+				"2 error(s)",
+				`component "test-comp": ObjectTemplate[obj-tmpl].Name: Required value`,
+				`component "test-comp": PatchTemplate[patch-tmpl].Replicas: Invalid value`,
+			},
+		},
+		{
+			desc:         "filtered to patch templates",
+			opts:         options.JSONOptions{"Replicas": "two"},
+			fopts:        &filter.Options{Kinds: []string{"PatchTemplate"}},
+			expErrSubstr: []string{"1 error(s)", "PatchTemplate[patch-tmpl].Replicas"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := PreflightOptions([]*bundle.Component{comp}, options.SameForAll(tc.opts), tc.fopts)
+			if len(tc.expErrSubstr) == 0 {
+				if cerr := testutil.CheckErrorCases(err, ""); cerr != nil {
+					t.Fatal(cerr)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error, but expected errors containing %q", tc.expErrSubstr)
+			}
+			for _, sub := range tc.expErrSubstr {
+				if !strings.Contains(err.Error(), sub) {
+					t.Errorf("got error %q, expected it to contain %q", err.Error(), sub)
+				}
+			}
+		})
+	}
+}
+
+func TestPreflightBundleOptions(t *testing.T) {
+	comp, err := converter.FromYAMLString(preflightComponentEx).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bun := &bundle.Bundle{
+		Components: []*bundle.Component{comp},
+		PatchTemplates: []*bundle.PatchTemplate{{
+			ObjectMeta: metav1.ObjectMeta{Name: "bundle-patch-tmpl"},
+			OptionsSchema: &apiextv1beta1.JSONSchemaProps{
+				Properties: map[string]apiextv1beta1.JSONSchemaProps{
+					"Namespace": {Type: "string"},
+				},
+			},
+		}},
+	}
+	fopts := &filter.Options{Kinds: []string{"PatchTemplate"}}
+
+	err = PreflightBundleOptions(bun, options.SameForAll(options.JSONOptions{"Namespace": "kube-system"}), fopts)
+	if cerr := testutil.CheckErrorCases(err, ""); cerr != nil {
+		t.Fatal(cerr)
+	}
+
+	err = PreflightBundleOptions(bun, options.SameForAll(options.JSONOptions{"Namespace": 3}), fopts)
+	if cerr := testutil.CheckErrorCases(err, `component "test-comp": PatchTemplate[bundle-patch-tmpl].Namespace`); cerr != nil {
+		t.Fatal(cerr)
+	}
+	if len(comp.Spec.Objects) != 2 {
+		t.Errorf("got %d component objects, expected the component to be unchanged", len(comp.Spec.Objects))
+	}
+}
+
+func TestPreflightScopes(t *testing.T) {
+	comp, err := converter.FromYAMLString(preflightComponentEx).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps := []*bundle.Component{comp}
+
+	ok := options.JSONOptions{"global": map[string]interface{}{}, "test-comp": map[string]interface{}{}}
+	if err := PreflightScopes(comps, ok); err != nil {
+		t.Errorf("got error %v, expected none", err)
+	}
+
+	typo := options.JSONOptions{"global": map[string]interface{}{}, "tset-comp": map[string]interface{}{}}
+	if cerr := testutil.CheckErrorCases(PreflightScopes(comps, typo), `neither "global" nor a component name: tset-comp`); cerr != nil {
+		t.Error(cerr)
+	}
+}
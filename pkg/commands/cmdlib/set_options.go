@@ -0,0 +1,121 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+// SetFlags contains the values of the --set family of flags, which override
+// individual options after any options files have been merged.
+type SetFlags struct {
+	// Values have the form path=value, where the value is parsed as YAML, so
+	// that numbers, booleans, lists and maps are typed.
+	Values []string
+
+	// StringValues have the form path=value, where the value is always
+	// treated as a string.
+	StringValues []string
+
+	// FileValues have the form path=file, where the value is the contents of
+	// the file as a string.
+	FileValues []string
+}
+
+// AddSetFlags adds the --set, --set-string and --set-file flags to a command.
+func AddSetFlags(cmd *cobra.Command, s *SetFlags) {
+	cmd.Flags().StringArrayVar(&s.Values, "set", []string{},
+		"Set an option, as in 'a.b.c=value'. The value is parsed as YAML. May be repeated. Applied after options files.")
+	cmd.Flags().StringArrayVar(&s.StringValues, "set-string", []string{},
+		"Set an option to a string, as in 'a.b.c=value'. May be repeated. Applied after --set.")
+	cmd.Flags().StringArrayVar(&s.FileValues, "set-file", []string{},
+		"Set an option to the contents of a file, as in 'a.b.c=path/to/file'. May be repeated. Applied after --set-string.")
+}
+
+// IsEmpty returns whether no --set flags were specified.
+func (s *SetFlags) IsEmpty() bool {
+	return s == nil || len(s.Values)+len(s.StringValues)+len(s.FileValues) == 0
+}
+
+// ApplySetFlags applies the --set flags on top of some options, returning a
+// new set of options. Option paths are dot-separated; a literal dot in a key
+// can be escaped with a backslash. The --set values are applied first, then
+// --set-string and finally --set-file, each in the order given.
+func ApplySetFlags(ctx context.Context, rw files.FileReaderWriter, opts options.JSONOptions, s *SetFlags) (options.JSONOptions, error) {
+	if s.IsEmpty() {
+		return opts, nil
+	}
+	out := options.Merge(opts)
+
+	for _, kv := range s.Values {
+		path, raw, err := splitSetFlag("--set", kv)
+		if err != nil {
+			return nil, err
+		}
+		var val interface{}
+		if raw != "" {
+			if err := converter.FromYAMLString(raw).ToObject(&val); err != nil {
+				return nil, fmt.Errorf("parsing --set value %q: %v", kv, err)
+			}
+		} else {
+			val = ""
+		}
+		if err := options.SetPath(out, options.SplitPath(path), val); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range s.StringValues {
+		path, val, err := splitSetFlag("--set-string", kv)
+		if err != nil {
+			return nil, err
+		}
+		if err := options.SetPath(out, options.SplitPath(path), val); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range s.FileValues {
+		path, fname, err := splitSetFlag("--set-file", kv)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := rw.ReadFile(ctx, fname)
+		if err != nil {
+			return nil, fmt.Errorf("reading --set-file %q: %v", kv, err)
+		}
+		if err := options.SetPath(out, options.SplitPath(path), string(contents)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// splitSetFlag splits a flag value of the form path=value.
+func splitSetFlag(flag, kv string) (string, string, error) {
+	i := strings.Index(kv, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%s value %q must have the form path=value", flag, kv)
+	}
+	return kv[:i], kv[i+1:], nil
+}
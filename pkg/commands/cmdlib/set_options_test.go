@@ -0,0 +1,127 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdlib
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestMergeOptions(t *testing.T) {
+	ctx := context.Background()
+	rw := &testutil.FakeFileReaderWriter{
+		ReadFiles: map[string]string{
+			"/base.yaml": `
+image:
+  repo: gcr.io/foo
+  tag: v1
+args: [a, b]`,
+			"/override.yaml": `
+image:
+  tag: v2
+args: [c]`,
+		},
+	}
+	got, err := MergeOptions(ctx, rw, []string{"/base.yaml", "/override.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := options.JSONOptions{
+		"image": map[string]interface{}{"repo": "gcr.io/foo", "tag": "v2"},
+		"args":  []interface{}{"c"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %v, expected %v", got, exp)
+	}
+}
+
+func TestApplySetFlags(t *testing.T) {
+	testcases := []struct {
+		desc         string
+		opts         options.JSONOptions
+		flags        *SetFlags
+		exp          options.JSONOptions
+		expErrSubstr string
+	}{
+		{
+			desc: "no flags",
+			opts: options.JSONOptions{"a": "b"},
+			exp:  options.JSONOptions{"a": "b"},
+		},
+		{
+			desc: "typed values",
+			opts: options.JSONOptions{"image": map[string]interface{}{"repo": "gcr.io/foo"}},
+			flags: &SetFlags{Values: []string{
+				"image.tag=v2",
+				"replicas=3",
+				"enabled=true",
+				"args=[a, b]",
+				"empty=",
+			}},
+			exp: options.JSONOptions{
+				"image":    map[string]interface{}{"repo": "gcr.io/foo", "tag": "v2"},
+				"replicas": float64(3),
+				"enabled":  true,
+				"args":     []interface{}{"a", "b"},
+				"empty":    "",
+			},
+		},
+		{
+			desc: "string values after typed values",
+			flags: &SetFlags{
+				Values:       []string{"version=1.10"},
+				StringValues: []string{"version=1.10", "enabled=true"},
+			},
+			exp: options.JSONOptions{"version": "1.10", "enabled": "true"},
+		},
+		{
+			desc:  "file values",
+			flags: &SetFlags{FileValues: []string{"cert.data=/cert.pem"}},
+			exp:   options.JSONOptions{"cert": map[string]interface{}{"data": "CERTDATA"}},
+		},
+		{
+			desc:         "error: missing equals",
+			flags:        &SetFlags{Values: []string{"foo"}},
+			expErrSubstr: "must have the form path=value",
+		},
+		{
+			desc:         "error: missing file",
+			flags:        &SetFlags{FileValues: []string{"foo=/missing.txt"}},
+			expErrSubstr: "reading --set-file",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			rw := &testutil.FakeFileReaderWriter{
+				ReadFiles: map[string]string{"/cert.pem": "CERTDATA"},
+			}
+			got, err := ApplySetFlags(context.Background(), rw, tc.opts, tc.flags)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got %v, expected %v", got, tc.exp)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
 	bundleoptions "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/valuefrom"
 	"github.com/spf13/cobra"
 	log "k8s.io/klog"
 )
@@ -29,6 +30,14 @@ import (
 // options represents options flags for the export command.
 type options struct {
 	optionsFiles []string
+
+	// setFlags contains individual option overrides, applied after the options
+	// files.
+	setFlags cmdlib.SetFlags
+
+	// If componentScoped is true, the options are keyed by component name, with
+	// a 'global' section that applies to every component.
+	componentScoped bool
 }
 
 func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopt *cmdlib.GlobalOptions) {
@@ -45,16 +54,29 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, fio fil
 	}
 
 	var optData bundleoptions.JSONOptions
-	if len(o.optionsFiles) > 0 {
+	var redactor *valuefrom.Redactor
+	if len(o.optionsFiles) > 0 || !o.setFlags.IsEmpty() {
 		var err error
-		optData, err = cmdlib.MergeOptions(ctx, fio, o.optionsFiles)
+		optData, redactor, err = cmdlib.ReadOptions(ctx, fio, o.optionsFiles, &o.setFlags)
 		if err != nil {
 			return err
 		}
 	}
-	objs, err := bw.ExportAsObjects(optData)
+
+	optsFn := bundleoptions.SameForAll(optData)
+	if o.componentScoped {
+		optsFn = bundleoptions.ByComponentName(optData)
+		if err := cmdlib.PreflightScopes(bw.AllComponents(), optData); err != nil {
+			return err
+		}
+	}
+	if err := cmdlib.PreflightOptions(bw.AllComponents(), optsFn, nil); err != nil {
+		return redactor.RedactError(err)
+	}
+
+	objs, err := bw.ExportAsObjectsWithOptionsFunc(optsFn)
 	if err != nil {
-		return err
+		return redactor.RedactError(err)
 	}
 
 	exporter := converter.ObjectExporter{Objects: objs}
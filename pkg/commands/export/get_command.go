@@ -36,7 +36,10 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 	}
 
 	cmd.Flags().StringArrayVar(&opts.optionsFiles, "options-files", []string{},
-		"File containing options to apply to templates. May be repeated, later values override earlier ones. If not set, templates will not be rendered")
+		"File containing options to apply to templates. May be repeated, later values override earlier ones. If neither options files nor --set flags are given, templates will not be rendered")
+	cmdlib.AddSetFlags(cmd, &opts.setFlags)
+	cmd.Flags().BoolVar(&opts.componentScoped, "component-scoped-options", false,
+		"Whether the options are keyed by component name. Options in the 'global' section are applied to every component, underneath the component's own options. Other keys must be component names.")
 
 	return cmd
 }
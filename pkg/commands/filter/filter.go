@@ -20,11 +20,14 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	log "k8s.io/klog"
 
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
 )
 
 // options represents options flags for the filter command.
@@ -45,12 +48,23 @@ type options struct {
 	// Example: foo=bar,biff=bam
 	annotations string
 
-	// Comma + semicolon separated annotations to filter
+	// Comma + semicolon separated labels to filter
 	// Example: foo=bar,biff=bam
 	labels string
 
+	// A Kubernetes label selector to filter
+	// Example: 'tier in (frontend,backend),!canary'
+	selector string
+
 	// Whether to perform the opposite match.
 	invertMatch bool
+
+	// A SemVer range that component versions must satisfy, such as '1.4.x'.
+	// Components with other versions are removed.
+	versionConstraint string
+
+	// Whether to keep only the latest version of each component.
+	latest bool
 }
 
 func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopt *cmdlib.GlobalOptions) {
@@ -82,7 +96,39 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, gopt *c
 	if o.labels != "" {
 		fopts.Labels = cmdlib.ParseStringMap(o.labels)
 	}
+	if o.selector != "" {
+		sel, err := metav1.ParseToLabelSelector(o.selector)
+		if err != nil {
+			return fmt.Errorf("parsing selector %q: %v", o.selector, err)
+		}
+		if len(sel.MatchLabels) > 0 && fopts.Labels == nil {
+			fopts.Labels = make(map[string]string)
+		}
+		for k, v := range sel.MatchLabels {
+			fopts.Labels[k] = v
+		}
+		fopts.MatchExpressions = sel.MatchExpressions
+	}
 	fopts.InvertMatch = o.invertMatch
+	if err := fopts.Validate(); err != nil {
+		return err
+	}
+
+	if o.versionConstraint != "" || o.latest {
+		if o.filterType != "components" || bw.Bundle() == nil {
+			return fmt.Errorf("--version-constraint and --latest can only be used to filter the components of a bundle")
+		}
+		comps, err := selectVersions(bw.Bundle().Components, o.versionConstraint, o.latest)
+		if err != nil {
+			return err
+		}
+		bw.Bundle().Components = comps
+
+		// Without other options, all components would be filtered out.
+		if o.kinds == "" && o.names == "" && o.namespaces == "" && o.annotations == "" && o.labels == "" && o.selector == "" {
+			return brw.WriteBundleData(ctx, bw, gopt)
+		}
+	}
 
 	if o.filterType == "components" && bw.Bundle() != nil {
 		bw.Bundle().Components = filter.NewFilter().FilterComponents(bw.Bundle().Components, fopts)
@@ -99,3 +145,32 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, gopt *c
 
 	return brw.WriteBundleData(ctx, bw, gopt)
 }
+
+// selectVersions returns the components whose versions satisfy a SemVer
+// constraint, or, if latest is true, only the latest such version of each
+// component. The components are grouped by name, in the order of their first
+// appearance, and sorted by version.
+func selectVersions(comps []*bundle.Component, constraint string, latest bool) ([]*bundle.Component, error) {
+	finder := find.NewComponentFinder(comps)
+	var names []string
+	seen := make(map[string]bool)
+	for _, c := range comps {
+		if name := c.Spec.ComponentName; !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var out []*bundle.Component
+	for _, name := range names {
+		matched, err := finder.Matching(name, constraint)
+		if err != nil {
+			return nil, err
+		}
+		if latest && len(matched) > 0 {
+			matched = matched[len(matched)-1:]
+		}
+		out = append(out, matched...)
+	}
+	return out, nil
+}
@@ -38,12 +38,17 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 
 	// Optional flags
 	cmd.Flags().StringVarP(&opts.filterType, "filter-type", "", "objects", "Whether to filter components or objects")
-	cmd.Flags().StringVarP(&opts.kinds, "kinds", "", "", "Comma separated kinds to filter on")
-	cmd.Flags().StringVarP(&opts.names, "names", "", "", "Comma separated names to filter on")
+	cmd.Flags().StringVarP(&opts.kinds, "kinds", "", "", "Comma separated kinds to filter on. Kinds may be qualified by group ('Deployment.apps') or apiVersion ('apps/v1,Deployment'), and may be globs ('*Role') or regexes ('regex:(Cluster)?Role')")
+	cmd.Flags().StringVarP(&opts.names, "names", "", "", "Comma separated names to filter on. Names may be globs ('etcd-*') or regexes ('regex:etcd-[0-9]+')")
 	cmd.Flags().StringVarP(&opts.namespaces, "namespaces", "", "", "Comma separated namespaces to filter on")
-	cmd.Flags().StringVarP(&opts.annotations, "annotations", "", "", "Comma + semicolon separated annotations to filter on. Ex: 'foo=bar,biff=bam'")
-	cmd.Flags().StringVarP(&opts.labels, "labels", "", "", "Comma + semicolon separated labelsto filter on. Ex: 'foo=bar,biff=bam'")
+	cmd.Flags().StringVarP(&opts.annotations, "annotations", "", "", "Comma + semicolon separated annotations to filter on, all of which must match. Ex: 'foo=bar,biff=bam'")
+	cmd.Flags().StringVarP(&opts.labels, "labels", "", "", "Comma + semicolon separated labels to filter on, all of which must match. Ex: 'foo=bar,biff=bam'")
+	cmd.Flags().StringVarP(&opts.selector, "selector", "", "", "Kubernetes label selector to filter on. Ex: 'tier in (frontend,backend),!canary'")
 	cmd.Flags().BoolVarP(&opts.invertMatch, "invert-match", "", false, "Whether to keep objects instead of filtering them")
+	cmd.Flags().StringVarP(&opts.versionConstraint, "version-constraint", "", "", "SemVer range that component versions must satisfy, such as '>=1.4.0 <1.5.0' or '1.4.x'. "+
+		"Components with other versions are removed. Only valid with --filter-type=components")
+	cmd.Flags().BoolVarP(&opts.latest, "latest", "", false, "Whether to keep only the latest version of each component, among the versions satisfying --version-constraint. "+
+		"Only valid with --filter-type=components")
 
 	return cmd
 }
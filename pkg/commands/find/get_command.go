@@ -31,15 +31,90 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 		Long:  "Provides functionality for searching through cluster bundles. See subcommands usage.",
 	}
 
+	opts := &options{}
 	imagesCmd := &cobra.Command{
 		Use:   "images",
 		Short: "Find images in the bundle",
-		Long:  "Apply all the patches found in a bundle to customize it with the given options custom resources",
+		Long: "Find the container images, and the OS images of NodeConfigs, referenced by the objects of the components in a bundle. " +
+			"By default, the unique images are output as YAML or JSON. With '--group-by', the images are grouped by component or by object, " +
+			"along with the container name and field path of each image. With '--format=table' or '--format=csv', " +
+			"each image is output on a row with its component, object, container name and field path.",
 		Run: func(cmd *cobra.Command, args []string) {
-			findAction(ctx, fio, sio, cmd, gopts)
+			findAction(ctx, fio, sio, cmd, opts, gopts)
 		},
 	}
+	imagesCmd.Flags().StringVar(&opts.imagePaths, "image-paths", "",
+		"File containing additional image paths for custom resources, of the form:\n"+
+			"  imagePaths:\n"+
+			"  - apiVersion: monitoring.coreos.com/v1\n"+
+			"    kind: Prometheus\n"+
+			"    paths: [spec.image, '{.spec.containers[*].image}']\n"+
+			"Container images and NodeConfig OS images are always found.")
+	imagesCmd.Flags().StringVar(&opts.groupBy, "group-by", "",
+		"Group the images by 'component' or by 'object', with the container name and field path of each image")
+	imagesCmd.Flags().StringVar(&opts.diff, "diff", "",
+		"Path of another bundle or component, usually an older release, to compare against. "+
+			"The images that were added to or removed from the input are output, with their locations.")
 
+	refsOpts := &refsOptions{}
+	refsCmd := &cobra.Command{
+		Use:   "refs",
+		Short: "Find references between objects in the bundle",
+		Long: "Find the references between the objects of the components in a bundle, and whether they resolve to objects in the bundle. " +
+			"References are found in the pod specs of Pods and workloads (volumes, envFrom, env, imagePullSecrets, serviceAccountName and priorityClassName), " +
+			"in the roleRef and ServiceAccount subjects of RoleBindings and ClusterRoleBindings, and in the selectors of Services. " +
+			"The graph is output as YAML or JSON, or, with '--format=dot', in the Graphviz DOT language.",
+		Run: func(cmd *cobra.Command, args []string) {
+			refsAction(ctx, fio, sio, cmd, refsOpts, gopts)
+		},
+	}
+	refsCmd.Flags().BoolVar(&refsOpts.dangling, "dangling", false,
+		"Only output the dangling references, which resolve to no object in the bundle. Optional references, and references to objects created by Kubernetes "+
+			"such as the default ServiceAccount, system-* PriorityClasses and default ClusterRoles, are not dangling.")
+
+	queryLong := "Results are output as YAML or JSON, or, with '--format=table', as a table. " +
+		"With '--jsonpath', only the given fields of each result are output, as in '--jsonpath={.spec.template.spec.containers[*].image}'."
+
+	compOpts := &queryOptions{}
+	componentsCmd := &cobra.Command{
+		Use:   "components",
+		Short: "Find components in the bundle",
+		Long: "Find the components in a bundle that contain objects matching the given options, such as the components containing a DaemonSet " +
+			"with '--kinds=DaemonSet'. Without object options, all the components with the given names are found. " + queryLong,
+		Run: func(cmd *cobra.Command, args []string) {
+			componentsAction(ctx, fio, sio, cmd, compOpts, gopts)
+		},
+	}
+	addQueryFlags(componentsCmd, compOpts)
+
+	objOpts := &queryOptions{}
+	objectsCmd := &cobra.Command{
+		Use:   "objects",
+		Short: "Find objects in the bundle",
+		Long: "Find the objects of the components in a bundle that match the given options, such as an object in a component " +
+			"with '--components=etcd --kinds=Pod --names=etcd-server'. " + queryLong,
+		Run: func(cmd *cobra.Command, args []string) {
+			objectsAction(ctx, fio, sio, cmd, objOpts, gopts)
+		},
+	}
+	addQueryFlags(objectsCmd, objOpts)
+
+	tmplOpts := &queryOptions{}
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Find templates in the bundle",
+		Long: "Find the PatchTemplates, ObjectTemplates and ReplacementTemplates of the components in a bundle that match the given options. " +
+			"The table output includes the type of each template and the selector of each PatchTemplate. " + queryLong,
+		Run: func(cmd *cobra.Command, args []string) {
+			templatesAction(ctx, fio, sio, cmd, tmplOpts, gopts)
+		},
+	}
+	addQueryFlags(templatesCmd, tmplOpts)
+
+	cmd.AddCommand(componentsCmd)
+	cmd.AddCommand(objectsCmd)
+	cmd.AddCommand(templatesCmd)
 	cmd.AddCommand(imagesCmd)
+	cmd.AddCommand(refsCmd)
 	return cmd
 }
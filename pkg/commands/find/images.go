@@ -22,26 +22,135 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
 )
 
-type options struct {}
+// options represents options flags for the find images command.
+type options struct {
+	// imagePaths contains additional image paths for custom resources.
+	imagePaths string
 
-func findAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, gopt *cmdlib.GlobalOptions) {
+	// groupBy is either 'component' or 'object', to group the images found
+	// by component or by object. By default, the images aren't grouped.
+	groupBy string
+
+	// diff is the path of another bundle or component to compare the images
+	// of the input against.
+	diff string
+}
+
+// imageColumns are the table and CSV columns for image locations.
+var imageColumns = []string{"COMPONENT", "VERSION", "KIND", "NAMESPACE", "NAME", "CONTAINER", "FIELD", "IMAGE"}
+
+func findAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopt *cmdlib.GlobalOptions) {
 	brw := cmdlib.NewBundleReaderWriter(fio, sio)
-	if err := runFindImages(ctx, brw, gopt); err != nil {
+	if err := runFindImages(ctx, opts, brw, fio, sio, gopt); err != nil {
 		log.Exitf("error in runFindImages: %v", err)
 	}
 }
 
-func runFindImages(ctx context.Context, brw cmdlib.BundleReaderWriter, gopt *cmdlib.GlobalOptions) error {
+func runFindImages(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, rw files.FileReaderWriter, sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	var finderOpts []find.ImageFinderConfig
+	if o.imagePaths != "" {
+		contents, err := rw.ReadFile(ctx, o.imagePaths)
+		if err != nil {
+			return fmt.Errorf("reading image paths file: %v", err)
+		}
+		cfg := &find.ImagePathConfig{}
+		if err := converter.FromFileName(o.imagePaths, contents).ToObject(cfg); err != nil {
+			return fmt.Errorf("parsing image paths file %q: %v", o.imagePaths, err)
+		}
+		paths, err := find.NewImagePaths(cfg.ImagePaths...)
+		if err != nil {
+			return fmt.Errorf("invalid image paths file %q: %v", o.imagePaths, err)
+		}
+		finderOpts = append(finderOpts, find.WithImagePaths(paths))
+	}
+
+	if o.groupBy != "" && o.groupBy != "component" && o.groupBy != "object" {
+		return fmt.Errorf("unknown --group-by value %q: must be either 'component' or 'object'", o.groupBy)
+	}
+	if o.groupBy != "" && o.diff != "" {
+		return fmt.Errorf("--group-by and --diff can't be used together")
+	}
+
 	bw, err := brw.ReadBundleData(ctx, gopt)
 	if err != nil {
 		return fmt.Errorf("error reading contents: %v", err)
 	}
+	finder := find.NewImageFinder(bw.AllComponents(), finderOpts...)
+	tabular := gopt.OutputFormat == tableFormat || gopt.OutputFormat == csvFormat
+
+	if o.diff != "" {
+		ogopt := &cmdlib.GlobalOptions{InputFile: o.diff, OutputFormat: gopt.OutputFormat}
+		obw, err := brw.ReadBundleData(ctx, ogopt)
+		if err != nil {
+			return fmt.Errorf("error reading %q: %v", o.diff, err)
+		}
+		oldLocs := find.NewImageFinder(obw.AllComponents(), finderOpts...).AllImageLocations()
+		diff := find.DiffImages(oldLocs, finder.AllImageLocations())
+		if !tabular {
+			return brw.WriteStructuredContents(ctx, diff, gopt)
+		}
+		var rows [][]string
+		for _, l := range diff.Added {
+			rows = append(rows, append([]string{"added"}, imageLocationRow(l)...))
+		}
+		for _, l := range diff.Removed {
+			rows = append(rows, append([]string{"removed"}, imageLocationRow(l)...))
+		}
+		return writeImageRows(sio, gopt, append([]string{"CHANGE"}, imageColumns...), rows)
+	}
 
-	found := find.NewImageFinder(bw.AllComponents()).AllImages().Flattened()
+	if o.groupBy == "" && !tabular {
+		found := finder.AllImages().Flattened()
+		return brw.WriteStructuredContents(ctx, found, gopt)
+	}
 
-	return brw.WriteStructuredContents(ctx, found, gopt)
+	locs := finder.AllImageLocations()
+	var groups []*find.ImageGroup
+	switch o.groupBy {
+	case "component":
+		groups = find.GroupImagesByComponent(locs)
+	case "object":
+		groups = find.GroupImagesByObject(locs)
+	}
+	if !tabular {
+		return brw.WriteStructuredContents(ctx, groups, gopt)
+	}
+	if groups != nil {
+		locs = nil
+		for _, g := range groups {
+			locs = append(locs, g.Images...)
+		}
+	}
+	var rows [][]string
+	for _, l := range locs {
+		rows = append(rows, imageLocationRow(l))
+	}
+	return writeImageRows(sio, gopt, imageColumns, rows)
+}
+
+// imageLocationRow returns the table or CSV row for an image location.
+func imageLocationRow(l *find.ImageLocation) []string {
+	return []string{
+		l.Key.Component.ComponentName,
+		l.Key.Component.Version,
+		l.Key.Object.Kind,
+		l.Key.Object.Namespace,
+		l.Key.Object.Name,
+		l.Container,
+		l.Field,
+		l.Image,
+	}
+}
+
+// writeImageRows writes image rows as a table or as CSV.
+func writeImageRows(sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions, header []string, rows [][]string) error {
+	if gopt.OutputFormat == csvFormat {
+		return writeCSV(sio, header, rows)
+	}
+	return writeTable(sio, header, rows)
 }
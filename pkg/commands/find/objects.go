@@ -0,0 +1,60 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+)
+
+func objectsAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *queryOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runFindObjects(ctx, opts, brw, sio, gopt); err != nil {
+		log.Exitf("error in runFindObjects: %v", err)
+	}
+}
+
+func runFindObjects(ctx context.Context, o *queryOptions, brw cmdlib.BundleReaderWriter, sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	fopts, err := o.filterOptions()
+	if err != nil {
+		return err
+	}
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	var results []*queryResult
+	for _, m := range findObjects(bw.AllComponents(), fopts) {
+		for _, obj := range m.objects {
+			results = append(results, &queryResult{
+				obj:     obj,
+				keys:    []string{m.component.Spec.ComponentName, obj.GetKind(), obj.GetNamespace(), obj.GetName()},
+				details: []string{obj.GetAPIVersion()},
+			})
+		}
+	}
+	tbl := queryTable{
+		keys:    []string{"COMPONENT", "KIND", "NAMESPACE", "NAME"},
+		details: []string{"APIVERSION"},
+	}
+	return writeResults(ctx, o, tbl, results, brw, sio, gopt)
+}
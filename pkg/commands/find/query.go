@@ -0,0 +1,289 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
+)
+
+// queryOptions represents the options flags shared by the find components,
+// objects and templates commands.
+type queryOptions struct {
+	// Comma-separated component names to search in
+	components string
+
+	// Comma-separated kinds to search for
+	kinds string
+
+	// Comma-separated metadata.names to search for
+	names string
+
+	// Comma-separated namespaces to search in
+	namespaces string
+
+	// Comma + semicolon separated annotations to search for
+	// Example: foo=bar,biff=bam
+	annotations string
+
+	// Comma + semicolon separated labels to search for
+	// Example: foo=bar,biff=bam
+	labels string
+
+	// A Kubernetes label selector to search for
+	// Example: 'tier in (frontend,backend),!canary'
+	selector string
+
+	// A JSONPath expression to project from each result
+	// Example: '{.spec.template.spec.containers[*].name}'
+	jsonPath string
+}
+
+// addQueryFlags adds the query flags to a find command.
+func addQueryFlags(cmd *cobra.Command, o *queryOptions) {
+	cmd.Flags().StringVarP(&o.components, "components", "", "",
+		"Comma-separated component names to search in. Names may be globs, or regular expressions with the prefix 'regex:'")
+	cmd.Flags().StringVarP(&o.kinds, "kinds", "", "",
+		"Comma-separated kinds to search for, such as 'Deployment', 'Deployment.apps' or 'apps/v1,Deployment'")
+	cmd.Flags().StringVarP(&o.names, "names", "", "",
+		"Comma-separated names to search for. Names may be globs, or regular expressions with the prefix 'regex:'")
+	cmd.Flags().StringVarP(&o.namespaces, "namespaces", "", "",
+		"Comma-separated namespaces to search in")
+	cmd.Flags().StringVarP(&o.annotations, "annotations", "", "",
+		"Comma and semicolon separated annotations to search for. Ex: 'foo=bar,biff=bam'")
+	cmd.Flags().StringVarP(&o.labels, "labels", "", "",
+		"Comma and semicolon separated labels to search for. Ex: 'foo=bar,biff=bam'")
+	cmd.Flags().StringVarP(&o.selector, "selector", "l", "",
+		"A Kubernetes label selector to search for, supporting =, ==, !=, in, notin, and existence. Ex: 'tier in (frontend,backend),!canary'")
+	cmd.Flags().StringVarP(&o.jsonPath, "jsonpath", "", "",
+		"A JSONPath expression, as in kubectl, to project from each result instead of writing it whole. Ex: '{.metadata.labels}'")
+}
+
+// filterOptions returns the filter options for the query flags.
+func (o *queryOptions) filterOptions() (*filter.Options, error) {
+	fopts := &filter.Options{}
+	if o.components != "" {
+		fopts.ComponentNames = strings.Split(o.components, ",")
+	}
+	if o.kinds != "" {
+		fopts.Kinds = strings.Split(o.kinds, ",")
+	}
+	if o.names != "" {
+		fopts.Names = strings.Split(o.names, ",")
+	}
+	if o.namespaces != "" {
+		fopts.Namespaces = strings.Split(o.namespaces, ",")
+	}
+	if o.annotations != "" {
+		fopts.Annotations = cmdlib.ParseStringMap(o.annotations)
+	}
+	if o.labels != "" {
+		fopts.Labels = cmdlib.ParseStringMap(o.labels)
+	}
+	if o.selector != "" {
+		sel, err := metav1.ParseToLabelSelector(o.selector)
+		if err != nil {
+			return nil, fmt.Errorf("parsing selector %q: %v", o.selector, err)
+		}
+		if len(sel.MatchLabels) > 0 && fopts.Labels == nil {
+			fopts.Labels = make(map[string]string)
+		}
+		for k, v := range sel.MatchLabels {
+			if lv, ok := fopts.Labels[k]; ok && lv != v {
+				return nil, fmt.Errorf("label %q is %q in --labels but %q in --selector", k, lv, v)
+			}
+			fopts.Labels[k] = v
+		}
+		fopts.MatchExpressions = sel.MatchExpressions
+	}
+	if err := fopts.Validate(); err != nil {
+		return nil, err
+	}
+	return fopts, nil
+}
+
+// matchedObjects are the objects of a component that match a query.
+type matchedObjects struct {
+	component *bundle.Component
+	objects   []*unstructured.Unstructured
+}
+
+// findObjects returns the objects that match the filter options, grouped by
+// component, in the order of the components. Components whose names don't
+// match are skipped. If there are object options, components with no matching
+// objects are skipped too; otherwise, every component whose name matches is
+// returned, even if it has no objects.
+func findObjects(comps []*bundle.Component, fopts *filter.Options) []*matchedObjects {
+	objFilters := hasObjectFilters(fopts)
+	var out []*matchedObjects
+	for _, c := range comps {
+		if !filter.MatchesComponentReference(c.ComponentReference(), fopts) {
+			continue
+		}
+		m := &matchedObjects{component: c}
+		for _, obj := range find.NewObjectFinder(c).Objects(core.ObjectRef{}) {
+			if filter.MatchesObject(obj, fopts) {
+				m.objects = append(m.objects, obj)
+			}
+		}
+		if len(m.objects) > 0 || !objFilters {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// hasObjectFilters returns whether the filter options select objects, rather
+// than only components.
+func hasObjectFilters(fopts *filter.Options) bool {
+	return len(fopts.Kinds) > 0 || len(fopts.Names) > 0 || len(fopts.Namespaces) > 0 ||
+		len(fopts.Annotations) > 0 || len(fopts.Labels) > 0 || len(fopts.MatchExpressions) > 0
+}
+
+// queryResult is a component or object found by a query.
+type queryResult struct {
+	// obj is the component or object, which is written for YAML and JSON
+	// output and projected with JSONPath.
+	obj interface{}
+
+	// keys are the table columns that identify the result.
+	keys []string
+
+	// details are the remaining table columns, which are replaced by the
+	// projected value with JSONPath.
+	details []string
+}
+
+// queryTable contains the headers of the table columns for query results.
+type queryTable struct {
+	keys    []string
+	details []string
+}
+
+// writeResults writes the query results. With --format=table, the results are
+// written as a table. Otherwise, the results are written as a YAML or JSON
+// list. With a JSONPath expression, the projected values are written instead
+// of the whole results.
+func writeResults(ctx context.Context, o *queryOptions, tbl queryTable, results []*queryResult, brw cmdlib.BundleReaderWriter, sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	var jp *jsonpath.JSONPath
+	if o.jsonPath != "" {
+		jp = jsonpath.New("jsonpath").AllowMissingKeys(true)
+		if err := jp.Parse(relaxedJSONPath(o.jsonPath)); err != nil {
+			return fmt.Errorf("parsing JSONPath %q: %v", o.jsonPath, err)
+		}
+	}
+
+	if gopt.OutputFormat == tableFormat {
+		header := append([]string{}, tbl.keys...)
+		if jp != nil {
+			header = append(header, "VALUE")
+		} else {
+			header = append(header, tbl.details...)
+		}
+		var rows [][]string
+		for _, r := range results {
+			row := append([]string{}, r.keys...)
+			if jp != nil {
+				data, err := jsonObject(r.obj)
+				if err != nil {
+					return err
+				}
+				var val bytes.Buffer
+				if err := jp.Execute(&val, data); err != nil {
+					return fmt.Errorf("evaluating JSONPath %q: %v", o.jsonPath, err)
+				}
+				row = append(row, val.String())
+			} else {
+				row = append(row, r.details...)
+			}
+			rows = append(rows, row)
+		}
+		return writeTable(sio, header, rows)
+	}
+
+	out := []interface{}{}
+	for _, r := range results {
+		if jp == nil {
+			out = append(out, r.obj)
+			continue
+		}
+		data, err := jsonObject(r.obj)
+		if err != nil {
+			return err
+		}
+		found, err := jp.FindResults(data)
+		if err != nil {
+			return fmt.Errorf("evaluating JSONPath %q: %v", o.jsonPath, err)
+		}
+		var vals []interface{}
+		for _, f := range found {
+			for _, v := range f {
+				vals = append(vals, v.Interface())
+			}
+		}
+		if len(vals) == 1 {
+			out = append(out, vals[0])
+		} else {
+			out = append(out, vals)
+		}
+	}
+	return brw.WriteStructuredContents(ctx, out, gopt)
+}
+
+// relaxedJSONPath allows JSONPath expressions without the surrounding braces
+// or leading dot, as kubectl does, so that 'metadata.name' is equivalent to
+// '{.metadata.name}'.
+func relaxedJSONPath(path string) string {
+	if strings.Contains(path, "{") {
+		return path
+	}
+	path = strings.TrimPrefix(path, "$")
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		path = "." + path
+	}
+	return "{" + path + "}"
+}
+
+// jsonObject converts a component or object into its generic JSON form, so
+// that it can be evaluated with JSONPath.
+func jsonObject(obj interface{}) (interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	b, err := converter.FromObject(obj).ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestFilterOptions(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		opts         queryOptions
+		exp          *filter.Options
+		expErrSubstr string
+	}{
+		{
+			desc: "no flags",
+			exp:  &filter.Options{},
+		},
+		{
+			desc: "lists",
+			opts: queryOptions{
+				components: "etcd,kube-*",
+				kinds:      "Deployment,apps/v1,DaemonSet",
+				names:      "foo,regex:ba.",
+				namespaces: "kube-system",
+			},
+			exp: &filter.Options{
+				ComponentNames: []string{"etcd", "kube-*"},
+				Kinds:          []string{"Deployment", "apps/v1", "DaemonSet"},
+				Names:          []string{"foo", "regex:ba."},
+				Namespaces:     []string{"kube-system"},
+			},
+		},
+		{
+			desc: "selector labels are merged with labels",
+			opts: queryOptions{
+				annotations: "foo=bar",
+				labels:      "app=web",
+				selector:    "tier=frontend,env in (prod,staging),!canary",
+			},
+			exp: &filter.Options{
+				Annotations: map[string]string{"foo": "bar"},
+				Labels:      map[string]string{"app": "web", "tier": "frontend"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "canary", Operator: metav1.LabelSelectorOpDoesNotExist, Values: []string{}},
+					{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+				},
+			},
+		},
+		{
+			desc: "same label in labels and selector",
+			opts: queryOptions{
+				labels:   "app=web",
+				selector: "app=web",
+			},
+			exp: &filter.Options{
+				Labels:           map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{},
+			},
+		},
+		{
+			desc: "error: conflicting labels and selector",
+			opts: queryOptions{
+				labels:   "app=web",
+				selector: "app=db",
+			},
+			expErrSubstr: `label "app" is "web" in --labels but "db" in --selector`,
+		},
+		{
+			desc:         "error: bad selector",
+			opts:         queryOptions{selector: "env in (prod"},
+			expErrSubstr: "parsing selector",
+		},
+		{
+			desc:         "error: bad name pattern",
+			opts:         queryOptions{names: "regex:("},
+			expErrSubstr: "invalid name pattern",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.opts.filterOptions()
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got filter options %+v, expected %+v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestFindObjects(t *testing.T) {
+	b, err := converter.FromYAMLString(`
+kind: Bundle
+components:
+- spec:
+    componentName: zeta
+    version: 1.0.0
+    objects:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: zeta-cfg
+- spec:
+    componentName: alpha
+    version: 1.0.0
+    objects:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: alpha-cfg
+    - apiVersion: v1
+      kind: Secret
+      metadata:
+        name: alpha-secret
+- spec:
+    componentName: zeta
+    version: 1.0.0
+    objects:
+    - apiVersion: v1
+      kind: Secret
+      metadata:
+        name: zeta-secret
+- spec:
+    componentName: empty
+    version: 1.0.0
+`).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc  string
+		fopts *filter.Options
+		exp   []string
+	}{
+		{
+			desc:  "all objects in component order",
+			fopts: &filter.Options{},
+			exp:   []string{"zeta:zeta-cfg", "alpha:alpha-cfg,alpha-secret", "zeta:zeta-secret", "empty:"},
+		},
+		{
+			desc:  "components without matches are skipped",
+			fopts: &filter.Options{Kinds: []string{"Secret"}},
+			exp:   []string{"alpha:alpha-secret", "zeta:zeta-secret"},
+		},
+		{
+			desc:  "component names",
+			fopts: &filter.Options{ComponentNames: []string{"zeta"}},
+			exp:   []string{"zeta:zeta-cfg", "zeta:zeta-secret"},
+		},
+		{
+			desc:  "components without objects match without object options",
+			fopts: &filter.Options{ComponentNames: []string{"empty"}},
+			exp:   []string{"empty:"},
+		},
+		{
+			desc:  "components without objects don't match object options",
+			fopts: &filter.Options{ComponentNames: []string{"empty"}, Kinds: []string{"Secret"}},
+		},
+		{
+			desc:  "no matches",
+			fopts: &filter.Options{Names: []string{"missing"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got []string
+			for _, m := range findObjects(b.Components, tc.fopts) {
+				s := m.component.Spec.ComponentName + ":"
+				for i, obj := range m.objects {
+					if i > 0 {
+						s += ","
+					}
+					s += obj.GetName()
+				}
+				got = append(got, s)
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got matches %v, expected %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestRelaxedJSONPath(t *testing.T) {
+	testCases := []struct {
+		path string
+		exp  string
+	}{
+		{path: "{.metadata.name}", exp: "{.metadata.name}"},
+		{path: "{range .items[*]}{.name}{end}", exp: "{range .items[*]}{.name}{end}"},
+		{path: ".metadata.name", exp: "{.metadata.name}"},
+		{path: "metadata.name", exp: "{.metadata.name}"},
+		{path: "$.metadata.name", exp: "{.metadata.name}"},
+		{path: "[0].name", exp: "{[0].name}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := relaxedJSONPath(tc.path); got != tc.exp {
+				t.Errorf("relaxedJSONPath(%q) = %q, expected %q", tc.path, got, tc.exp)
+			}
+		})
+	}
+}
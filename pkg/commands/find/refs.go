@@ -0,0 +1,63 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
+)
+
+// dotFormat is the output format for writing reference graphs in the Graphviz
+// DOT language.
+const dotFormat = "dot"
+
+// refsOptions represents options flags for the find refs command.
+type refsOptions struct {
+	// If dangling is true, only references that resolve to no object are
+	// output.
+	dangling bool
+}
+
+func refsAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *refsOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runFindRefs(ctx, opts, brw, sio, gopt); err != nil {
+		log.Exitf("error in runFindRefs: %v", err)
+	}
+}
+
+func runFindRefs(ctx context.Context, o *refsOptions, brw cmdlib.BundleReaderWriter, sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	g := find.NewRefFinder(bw.AllComponents()).Graph()
+	if o.dangling {
+		g = g.DanglingGraph()
+	}
+
+	if gopt.OutputFormat == dotFormat {
+		_, err := sio.Write([]byte(g.DOT()))
+		return err
+	}
+	return brw.WriteStructuredContents(ctx, g, gopt)
+}
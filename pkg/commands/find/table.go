@@ -0,0 +1,73 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+)
+
+const (
+	// tableFormat is the output format for writing results as a table.
+	tableFormat = "table"
+
+	// csvFormat is the output format for writing results as comma-separated
+	// values, with a header row.
+	csvFormat = "csv"
+)
+
+// none is the table value for empty fields.
+const none = "<none>"
+
+// writeTable writes rows as a table with aligned columns.
+func writeTable(sio cmdlib.StdioReaderWriter, header []string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cols := make([]string, len(row))
+		for i, col := range row {
+			if col == "" {
+				col = none
+			}
+			cols[i] = col
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := sio.Write(buf.Bytes())
+	return err
+}
+
+// writeCSV writes rows as comma-separated values, preceded by a header row.
+func writeCSV(sio cmdlib.StdioReaderWriter, header []string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	_, err := sio.Write(buf.Bytes())
+	return err
+}
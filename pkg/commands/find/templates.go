@@ -0,0 +1,152 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	log "k8s.io/klog"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+)
+
+// templateKinds are the kinds of the templates found by find templates.
+var templateKinds = map[string]bool{
+	"PatchTemplate":       true,
+	"ObjectTemplate":      true,
+	"ReplacementTemplate": true,
+}
+
+func templatesAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *queryOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runFindTemplates(ctx, opts, brw, sio, gopt); err != nil {
+		log.Exitf("error in runFindTemplates: %v", err)
+	}
+}
+
+func runFindTemplates(ctx context.Context, o *queryOptions, brw cmdlib.BundleReaderWriter, sio cmdlib.StdioReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	fopts, err := o.filterOptions()
+	if err != nil {
+		return err
+	}
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	var results []*queryResult
+	for _, m := range findObjects(bw.AllComponents(), fopts) {
+		for _, obj := range m.objects {
+			if !templateKinds[obj.GetKind()] {
+				continue
+			}
+			typ, sel, err := templateDetails(obj)
+			if err != nil {
+				return fmt.Errorf("in component %q: %v", m.component.Spec.ComponentName, err)
+			}
+			results = append(results, &queryResult{
+				obj:     obj,
+				keys:    []string{m.component.Spec.ComponentName, obj.GetKind(), obj.GetName()},
+				details: []string{typ, sel},
+			})
+		}
+	}
+	tbl := queryTable{
+		keys:    []string{"COMPONENT", "KIND", "NAME"},
+		details: []string{"TYPE", "SELECTOR"},
+	}
+	return writeResults(ctx, o, tbl, results, brw, sio, gopt)
+}
+
+// templateDetails returns the type and a summary of the selector of a
+// template, for table output. The type is the patchType of PatchTemplates, the
+// type of ObjectTemplates and the phase of ReplacementTemplates. Only
+// PatchTemplates have selectors.
+func templateDetails(obj *unstructured.Unstructured) (string, string, error) {
+	var typ, sel string
+	switch obj.GetKind() {
+	case "PatchTemplate":
+		pt := &bundle.PatchTemplate{}
+		if err := converter.FromUnstructured(obj).ToObject(pt); err != nil {
+			return "", "", fmt.Errorf("invalid PatchTemplate %q: %v", obj.GetName(), err)
+		}
+		typ, sel = pt.PatchType, selectorSummary(pt.Selector)
+	case "ObjectTemplate":
+		ot := &bundle.ObjectTemplate{}
+		if err := converter.FromUnstructured(obj).ToObject(ot); err != nil {
+			return "", "", fmt.Errorf("invalid ObjectTemplate %q: %v", obj.GetName(), err)
+		}
+		typ = string(ot.Type)
+	case "ReplacementTemplate":
+		rt := &bundle.ReplacementTemplate{}
+		if err := converter.FromUnstructured(obj).ToObject(rt); err != nil {
+			return "", "", fmt.Errorf("invalid ReplacementTemplate %q: %v", obj.GetName(), err)
+		}
+		typ = string(rt.Phase)
+	}
+	return typ, sel, nil
+}
+
+// selectorSummary summarizes an object selector on one line, such as
+// 'kinds=Deployment,DaemonSet labels=app=web'.
+func selectorSummary(sel *bundle.ObjectSelector) string {
+	if sel == nil {
+		return ""
+	}
+	var parts []string
+	add := func(field string, vals []string) {
+		if len(vals) > 0 {
+			parts = append(parts, field+"="+strings.Join(vals, ","))
+		}
+	}
+	add("kinds", sel.Kinds)
+	add("names", sel.Names)
+	add("namespaces", sel.Namespaces)
+	add("annotations", keyValues(sel.Annotations))
+	add("labels", keyValues(sel.Labels))
+	var exprs []string
+	for _, e := range sel.MatchExpressions {
+		expr := e.Key + " " + strings.ToLower(string(e.Operator))
+		if len(e.Values) > 0 {
+			expr += " (" + strings.Join(e.Values, ",") + ")"
+		}
+		exprs = append(exprs, expr)
+	}
+	add("matchExpressions", exprs)
+	add("componentNames", sel.ComponentNames)
+	add("componentVersions", sel.ComponentVersions)
+	if sel.InvertMatch != nil && *sel.InvertMatch {
+		parts = append(parts, "invertMatch")
+	}
+	return strings.Join(parts, " ")
+}
+
+// keyValues returns the sorted key=value pairs of a map.
+func keyValues(m map[string]string) []string {
+	var kvs []string
+	for k, v := range m {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return kvs
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package images contains commands for transforming the images referenced by
+// components.
+package images
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+// GetCommand returns the command for transforming images.
+func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, gopts *cmdlib.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Transform and copy the images referenced by the bundle",
+		Long:  "Provides functionality for transforming and copying the container and OS images referenced by cluster bundles. See subcommands usage.",
+	}
+
+	rewriteOpts := &rewriteOptions{}
+	rewriteCmd := &cobra.Command{
+		Use:   "rewrite",
+		Short: "Rewrite image references using a mapping file",
+		Long: "Rewrite every container image and NodeConfig OS image URL in a component or bundle using the rules in a mapping file, " +
+			"for example to use images mirrored in a private registry. The mapping file is YAML or JSON of the form:\n\n" +
+			"  rules:\n" +
+			"  - prefix: gcr.io/foo\n" +
+			"    replacement: registry.local/foo\n" +
+			"  - exact: k8s.gcr.io/pause:3.1\n" +
+			"    replacement: registry.local/pause:3.1\n\n" +
+			"A prefix rule matches images that start with the prefix followed by '/', ':', '@' or nothing. " +
+			"An exact rule takes precedence over prefix rules, and the longest matching prefix wins. " +
+			"Images that match no rule are left unchanged and reported as warnings.",
+		Run: func(cmd *cobra.Command, args []string) {
+			rewriteAction(ctx, fio, sio, cmd, rewriteOpts, gopts)
+		},
+	}
+	rewriteCmd.Flags().StringVar(&rewriteOpts.rulesFile, "rules-file", "", "File containing the image rewrite rules.")
+	rewriteCmd.Flags().BoolVar(&rewriteOpts.strict, "strict", false, "Fail if any image matches no rule.")
+
+	pinOpts := &pinOptions{}
+	pinCmd := &cobra.Command{
+		Use:   "pin",
+		Short: "Pin image tags to digests",
+		Long: "Replace the tag of every container image in a component or bundle with the digest of the image manifest, " +
+			"so that 'repo:tag' becomes 'repo@sha256:...'. Digests are resolved through the OCI distribution API of the image registries, " +
+			"or, in offline mode, read from a lock file of the form:\n\n" +
+			"  digests:\n" +
+			"    gcr.io/foo/bar:1.0: sha256:abcd...\n\n" +
+			"Images that already have a digest are left unchanged.",
+		Run: func(cmd *cobra.Command, args []string) {
+			pinAction(ctx, fio, sio, cmd, pinOpts, gopts)
+		},
+	}
+	pinCmd.Flags().StringVar(&pinOpts.registryConfig, "registry-config", "",
+		"File containing registry credentials, in the form of a Docker config file. If not specified, registries are accessed anonymously.")
+	pinCmd.Flags().StringSliceVar(&pinOpts.insecureRegistries, "insecure-registry", nil,
+		"Registry, as host or host:port, to access over plain HTTP rather than HTTPS, such as a local test registry. May be repeated.")
+	pinCmd.Flags().StringVar(&pinOpts.lockFile, "lock-file", "", "File containing precomputed image digests. If specified, registries are not accessed.")
+	pinCmd.Flags().StringVar(&pinOpts.writeLockFile, "write-lock-file", "", "File to write the digests of the pinned images to, for later use with --lock-file.")
+
+	saveOpts := &saveOptions{}
+	saveCmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save images to an OCI image layout",
+		Long: "Copy every container image referenced by a component or bundle, including every platform of multi-platform images, " +
+			"from its registry into an OCI image layout directory, for example to install the bundle without internet access. " +
+			"The digests of the saved images are written to a lock file, which can be used with 'images pin --lock-file'.",
+		Run: func(cmd *cobra.Command, args []string) {
+			saveAction(ctx, fio, sio, cmd, saveOpts, gopts)
+		},
+	}
+	saveCmd.Flags().StringVar(&saveOpts.ociLayout, "oci-layout", "", "Directory of the OCI image layout to save the images to.")
+	saveCmd.Flags().StringVar(&saveOpts.registryConfig, "registry-config", "",
+		"File containing registry credentials, in the form of a Docker config file. If not specified, registries are accessed anonymously.")
+	saveCmd.Flags().StringSliceVar(&saveOpts.insecureRegistries, "insecure-registry", nil,
+		"Registry, as host or host:port, to access over plain HTTP rather than HTTPS, such as a local test registry. May be repeated.")
+	saveCmd.Flags().StringVar(&saveOpts.lockFile, "lock-file", "",
+		"File to write the digests of the saved images to. If not specified, it's written to "+defaultLockFile+" in the OCI layout directory.")
+
+	loadOpts := &loadOptions{}
+	loadCmd := &cobra.Command{
+		Use:   "load",
+		Short: "Push images from an OCI image layout to a registry",
+		Long: "Push the images in an OCI image layout directory, as written by 'images save', to a registry. " +
+			"The images keep their repository path and tag, but their registry is replaced, " +
+			"so with '--registry=registry.local/mirror', 'gcr.io/foo/bar:1.0' is pushed to 'registry.local/mirror/foo/bar:1.0'. " +
+			"The output contains rewrite rules from the original to the pushed images, which can be used with 'images rewrite --rules-file'.",
+		Run: func(cmd *cobra.Command, args []string) {
+			loadAction(ctx, fio, sio, cmd, loadOpts, gopts)
+		},
+	}
+	loadCmd.Flags().StringVar(&loadOpts.ociLayout, "oci-layout", "", "Directory of the OCI image layout to push the images from.")
+	loadCmd.Flags().StringVar(&loadOpts.registry, "registry", "", "Registry to push the images to, optionally with a path prefix, such as 'registry.local/mirror'.")
+	loadCmd.Flags().StringVar(&loadOpts.registryConfig, "registry-config", "",
+		"File containing registry credentials, in the form of a Docker config file. If not specified, the registry is accessed anonymously.")
+	loadCmd.Flags().StringSliceVar(&loadOpts.insecureRegistries, "insecure-registry", nil,
+		"Registry, as host or host:port, to access over plain HTTP rather than HTTPS, such as a local test registry. May be repeated.")
+
+	cmd.AddCommand(rewriteCmd)
+	cmd.AddCommand(pinCmd)
+	cmd.AddCommand(saveCmd)
+	cmd.AddCommand(loadCmd)
+	return cmd
+}
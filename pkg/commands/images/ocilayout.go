@@ -0,0 +1,138 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/images"
+)
+
+// defaultLockFile is the name of the lock file written to the OCI layout
+// directory if no other path is specified.
+const defaultLockFile = "images.lock.yaml"
+
+// saveOptions represents options flags for the images save command.
+type saveOptions struct {
+	// ociLayout is the directory of the OCI image layout to save images to.
+	ociLayout string
+
+	// registryConfig contains registry credentials, in the form of a Docker
+	// config file.
+	registryConfig string
+
+	// insecureRegistries are the registries that are accessed over plain HTTP.
+	insecureRegistries []string
+
+	// lockFile is the path to write the digests of the saved images to.
+	lockFile string
+}
+
+func saveAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *saveOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runSave(ctx, opts, brw, fio, gopt); err != nil {
+		log.Exit(err)
+	}
+}
+
+func runSave(ctx context.Context, o *saveOptions, brw cmdlib.BundleReaderWriter, rw files.FileReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	if o.ociLayout == "" {
+		return fmt.Errorf("--oci-layout must be specified")
+	}
+	reg, err := newRegistryResolver(ctx, rw, o.registryConfig, o.insecureRegistries)
+	if err != nil {
+		return err
+	}
+
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+	switch bw.Kind() {
+	case "Component", "Bundle":
+	default:
+		return fmt.Errorf("bundle kind %q not supported for saving images", bw.Kind())
+	}
+
+	imgs := images.ContainerImages(bw.AllComponents())
+	lock, err := (&images.OCILayout{Dir: o.ociLayout}).Save(ctx, imgs, reg)
+	if err != nil {
+		return err
+	}
+
+	lockFile := o.lockFile
+	if lockFile == "" {
+		lockFile = filepath.Join(o.ociLayout, defaultLockFile)
+	}
+	by, err := converter.FromObject(lock).ToContentType(formatFromFile(lockFile))
+	if err != nil {
+		return fmt.Errorf("writing lock file: %v", err)
+	}
+	if err := rw.WriteFile(ctx, lockFile, by, 0644); err != nil {
+		return fmt.Errorf("writing lock file: %v", err)
+	}
+	return nil
+}
+
+// loadOptions represents options flags for the images load command.
+type loadOptions struct {
+	// ociLayout is the directory of the OCI image layout to load images from.
+	ociLayout string
+
+	// registry is the registry to push the images to, optionally with a path
+	// prefix.
+	registry string
+
+	// registryConfig contains registry credentials, in the form of a Docker
+	// config file.
+	registryConfig string
+
+	// insecureRegistries are the registries that are accessed over plain HTTP.
+	insecureRegistries []string
+}
+
+func loadAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *loadOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runLoad(ctx, opts, brw, fio, gopt); err != nil {
+		log.Exit(err)
+	}
+}
+
+func runLoad(ctx context.Context, o *loadOptions, brw cmdlib.BundleReaderWriter, rw files.FileReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	if o.ociLayout == "" {
+		return fmt.Errorf("--oci-layout must be specified")
+	}
+	if o.registry == "" {
+		return fmt.Errorf("--registry must be specified")
+	}
+	reg, err := newRegistryResolver(ctx, rw, o.registryConfig, o.insecureRegistries)
+	if err != nil {
+		return err
+	}
+
+	rules, err := (&images.OCILayout{Dir: o.ociLayout}).Load(ctx, o.registry, reg)
+	if err != nil {
+		return err
+	}
+	return brw.WriteStructuredContents(ctx, rules, gopt)
+}
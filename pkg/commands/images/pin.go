@@ -0,0 +1,138 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/images"
+)
+
+// pinOptions represents options flags for the images pin command.
+type pinOptions struct {
+	// registryConfig contains registry credentials, in the form of a Docker
+	// config file.
+	registryConfig string
+
+	// insecureRegistries are the registries that are accessed over plain HTTP.
+	insecureRegistries []string
+
+	// lockFile contains precomputed image digests. If specified, registries
+	// are not accessed.
+	lockFile string
+
+	// writeLockFile is the path to write the digests of the pinned images to.
+	writeLockFile string
+}
+
+func pinAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *pinOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runPin(ctx, opts, brw, fio, gopt); err != nil {
+		log.Exit(err)
+	}
+}
+
+func runPin(ctx context.Context, o *pinOptions, brw cmdlib.BundleReaderWriter, rw files.FileReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	if o.lockFile != "" && o.registryConfig != "" {
+		return fmt.Errorf("only one of --lock-file or --registry-config may be specified")
+	}
+
+	var resolver images.DigestResolver
+	if o.lockFile != "" {
+		lock := &images.LockFile{}
+		if err := readStructuredFile(ctx, rw, o.lockFile, lock); err != nil {
+			return err
+		}
+		resolver = lock
+	} else {
+		reg, err := newRegistryResolver(ctx, rw, o.registryConfig, o.insecureRegistries)
+		if err != nil {
+			return err
+		}
+		resolver = reg
+	}
+
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+	switch bw.Kind() {
+	case "Component", "Bundle":
+	default:
+		return fmt.Errorf("bundle kind %q not supported for pinning images", bw.Kind())
+	}
+
+	// The components are pinned in-place.
+	lock, err := images.PinAll(ctx, bw.AllComponents(), resolver)
+	if err != nil {
+		return err
+	}
+
+	if o.writeLockFile != "" {
+		by, err := converter.FromObject(lock).ToContentType(formatFromFile(o.writeLockFile))
+		if err != nil {
+			return fmt.Errorf("writing lock file: %v", err)
+		}
+		if err := rw.WriteFile(ctx, o.writeLockFile, by, 0644); err != nil {
+			return fmt.Errorf("writing lock file: %v", err)
+		}
+	}
+	return brw.WriteBundleData(ctx, bw, gopt)
+}
+
+// newRegistryResolver creates a RegistryResolver with the credentials in a
+// registry config file. If the path is empty, registries are accessed
+// anonymously. The insecure registries are accessed over plain HTTP.
+func newRegistryResolver(ctx context.Context, rw files.FileReaderWriter, path string, insecure []string) (*images.RegistryResolver, error) {
+	var config *images.RegistryConfig
+	if path != "" {
+		config = &images.RegistryConfig{}
+		if err := readStructuredFile(ctx, rw, path, config); err != nil {
+			return nil, err
+		}
+	}
+	reg := images.NewRegistryResolver(config)
+	reg.InsecureRegistries = insecure
+	return reg, nil
+}
+
+// readStructuredFile reads a YAML or JSON file into obj.
+func readStructuredFile(ctx context.Context, rw files.FileReaderWriter, path string, obj interface{}) error {
+	contents, err := rw.ReadFile(ctx, path)
+	if err != nil {
+		return fmt.Errorf("reading file %q: %v", path, err)
+	}
+	if err := converter.FromFileName(path, contents).ToObject(obj); err != nil {
+		return fmt.Errorf("parsing file %q: %v", path, err)
+	}
+	return nil
+}
+
+// formatFromFile returns the content format for a file, based on its
+// extension.
+func formatFromFile(path string) string {
+	if filepath.Ext(path) == ".json" {
+		return "json"
+	}
+	return "yaml"
+}
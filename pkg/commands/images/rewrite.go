@@ -0,0 +1,78 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/images"
+)
+
+// rewriteOptions represents options flags for the images rewrite command.
+type rewriteOptions struct {
+	// rulesFile contains the image rewrite rules.
+	rulesFile string
+
+	// If strict is true, images that match no rule are an error rather than a
+	// warning.
+	strict bool
+}
+
+func rewriteAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *rewriteOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runRewrite(ctx, opts, brw, fio, gopt); err != nil {
+		log.Exit(err)
+	}
+}
+
+func runRewrite(ctx context.Context, o *rewriteOptions, brw cmdlib.BundleReaderWriter, rw files.FileReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	if o.rulesFile == "" {
+		return fmt.Errorf("--rules-file must be specified")
+	}
+	rules := &images.RewriteRules{}
+	if err := readStructuredFile(ctx, rw, o.rulesFile, rules); err != nil {
+		return err
+	}
+	if err := rules.Validate(); err != nil {
+		return fmt.Errorf("invalid rules file %q: %v", o.rulesFile, err)
+	}
+
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	switch bw.Kind() {
+	case "Component", "Bundle":
+	default:
+		return fmt.Errorf("bundle kind %q not supported for rewriting images", bw.Kind())
+	}
+
+	// The components are rewritten in-place.
+	unmatched := images.RewriteAll(bw.AllComponents(), rules)
+	for _, u := range unmatched {
+		log.Warningf("Image %q in %s %q of component %q matched no rewrite rule", u.Image, u.Key.Object.Kind, u.Key.Object.Name, u.Key.Component.ComponentName)
+	}
+	if o.strict && len(unmatched) > 0 {
+		return fmt.Errorf("%d image(s) matched no rewrite rule", len(unmatched))
+	}
+	return brw.WriteBundleData(ctx, bw, gopt)
+}
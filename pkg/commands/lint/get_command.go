@@ -0,0 +1,44 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint contains the command for statically checking templates against
+// their options schemas.
+package lint
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+// GetCommand returns the command for linting templates.
+func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, gopts *cmdlib.GlobalOptions) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check templates against their options schemas",
+		Long: `Check the go-template ObjectTemplates, PatchTemplates and PatchTemplateBuilders in a component or bundle ` +
+			`for references to options that aren't declared in their schemas, and for schema properties that no template uses.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			action(ctx, fio, sio, cmd, opts, gopts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.ignoreUnused, "ignore-unused", "", false,
+		"Whether to log unused schema properties as warnings instead of failing")
+
+	return cmd
+}
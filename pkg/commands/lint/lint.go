@@ -0,0 +1,70 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/lint"
+)
+
+// options represents options flags for the lint command.
+type options struct {
+	// If ignoreUnused is true, unused schema properties are logged as warnings
+	// rather than returned as errors.
+	ignoreUnused bool
+}
+
+func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopts *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := run(ctx, opts, brw, gopts); err != nil {
+		log.Exit(err)
+	}
+}
+
+func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	var msgs []string
+	for _, comp := range bw.AllComponents() {
+		issues, err := lint.Component(comp)
+		if err != nil {
+			return err
+		}
+		for _, i := range issues {
+			if o.ignoreUnused && i.Type == lint.UnusedProperty {
+				log.Warning(i)
+				continue
+			}
+			msgs = append(msgs, i.String())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("found %d template issue(s):\n%s", len(msgs), strings.Join(msgs, "\n"))
+	}
+
+	log.Info("No issues found")
+	return nil
+}
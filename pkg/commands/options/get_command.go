@@ -0,0 +1,50 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package options contains commands for inspecting component options.
+package options
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+// GetCommand returns the command for inspecting options.
+func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, gopts *cmdlib.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "options",
+		Short: "Inspect the options accepted by components",
+		Long:  "Provides functionality for inspecting the options accepted by components. See subcommands usage.",
+	}
+
+	sopts := &schemaOptions{}
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the aggregate options schema",
+		Long: "Merge the OptionsSchemas of all ObjectTemplates and PatchTemplates into a single schema. " +
+			"For a Component, a single schema is printed; for a Bundle, a schema is printed for each component, keyed by component name. " +
+			"Conflicting types or defaults for the same option are reported as errors.",
+		Run: func(cmd *cobra.Command, args []string) {
+			schemaAction(ctx, fio, sio, cmd, sopts, gopts)
+		},
+	}
+	schemaCmd.Flags().BoolVar(&sopts.ignoreConflicts, "ignore-conflicts", false,
+		"Print the schema even if there are conflicting definitions. Conflicts are logged and the first definition is used.")
+
+	cmd.AddCommand(schemaCmd)
+	return cmd
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	log "k8s.io/klog"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+)
+
+// schemaOptions represents options flags for the options schema command.
+type schemaOptions struct {
+	// ignoreConflicts indicates whether to print the schema even if there are
+	// conflicting option definitions.
+	ignoreConflicts bool
+}
+
+func schemaAction(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *schemaOptions, gopt *cmdlib.GlobalOptions) {
+	brw := cmdlib.NewBundleReaderWriter(fio, sio)
+	if err := runSchema(ctx, opts, brw, gopt); err != nil {
+		log.Exit(err)
+	}
+}
+
+func runSchema(ctx context.Context, o *schemaOptions, brw cmdlib.BundleReaderWriter, gopt *cmdlib.GlobalOptions) error {
+	bw, err := brw.ReadBundleData(ctx, gopt)
+	if err != nil {
+		return fmt.Errorf("error reading contents: %v", err)
+	}
+
+	var out interface{}
+	var conflicts []*openapi.SchemaConflict
+	switch bw.Kind() {
+	case "Component":
+		out, conflicts, err = openapi.ComponentSchema(bw.Component())
+	case "Bundle":
+		out, conflicts, err = openapi.BundleSchemas(bw.Bundle())
+	default:
+		return fmt.Errorf("bundle kind %q not supported for options schema", bw.Kind())
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		var msgs []string
+		for _, c := range conflicts {
+			msgs = append(msgs, c.String())
+		}
+		if !o.ignoreConflicts {
+			return fmt.Errorf("found %d conflicting option definitions:\n%s", len(conflicts), strings.Join(msgs, "\n"))
+		}
+		for _, m := range msgs {
+			log.Warning(m)
+		}
+	}
+
+	return brw.WriteStructuredContents(ctx, out, gopt)
+}
@@ -37,7 +37,13 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 	}
 	// While options-file is technically optional, it is usually provided to detemplatize the patch templates.
 	cmd.Flags().StringArrayVar(&opts.optionsFiles, "options-file", []string{}, "File containing options to apply to patch templates. May be repeated, later values override earlier ones.")
+	cmdlib.AddSetFlags(cmd, &opts.setFlags)
 	cmd.Flags().StringVar(&opts.patchAnnotations, "patch-annotations", "", "Select a subset of patches to apply based on a list of annotations of the form \"key1=val1,key2=val2\"")
+	cmd.Flags().BoolVar(&opts.componentScoped, "component-scoped-options", false,
+		"Whether the options are keyed by component name. Options in the 'global' section are applied to every component, underneath the component's own options. Other keys must be component names.")
 	cmd.Flags().BoolVar(&opts.keepTemplates, "keep-templates", false, "Do not remove templates that have been applied from the component.")
+	cmd.Flags().StringArrayVar(&opts.crdFiles, "crd-file", []string{},
+		"File containing CustomResourceDefinitions whose schemas are used to strategic-merge-patch custom resources. "+
+			"CustomResourceDefinitions in a component are always used. May be repeated.")
 	return cmd
 }
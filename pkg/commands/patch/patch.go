@@ -25,7 +25,9 @@ import (
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	bundleoptions "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/patchtmpl"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/valuefrom"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/wrapper"
 )
 
@@ -39,9 +41,21 @@ type options struct {
 	// apply to PatchTemplates
 	optionsFiles []string
 
+	// setFlags contains individual option overrides, applied after the options
+	// files.
+	setFlags cmdlib.SetFlags
+
+	// If componentScoped is true, the options are keyed by component name, with
+	// a 'global' section that applies to every component.
+	componentScoped bool
+
 	// If keepTemplates is true, PatchTemplates will not be stripped from
 	// the component objects.
 	keepTemplates bool
+
+	// crdFiles contain CustomResourceDefinitions whose schemas are used to
+	// strategic-merge-patch custom resources.
+	crdFiles []string
 }
 
 func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioReaderWriter, cmd *cobra.Command, opts *options, gopt *cmdlib.GlobalOptions) {
@@ -57,32 +71,80 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, rw file
 		return fmt.Errorf("error reading contents: %v", err)
 	}
 
-	optData, err := cmdlib.MergeOptions(ctx, rw, o.optionsFiles)
+	optData, redactor, err := cmdlib.ReadOptions(ctx, rw, o.optionsFiles, &o.setFlags)
 	if err != nil {
 		return err
 	}
 
+	optsFn := bundleoptions.SameForAll(optData)
+	if o.componentScoped {
+		optsFn = bundleoptions.ByComponentName(optData)
+		if err := cmdlib.PreflightScopes(bw.AllComponents(), optData); err != nil {
+			return err
+		}
+	}
+
 	fopts := &filter.Options{Annotations: cmdlib.ParseStringMap(o.patchAnnotations)}
-	applier := patchtmpl.NewApplier(patchtmpl.DefaultPatcherScheme(), fopts, o.keepTemplates)
+
+	// Only the PatchTemplates that will be applied need to accept the options.
+	preflightOpts := &filter.Options{
+		Kinds:       []string{"PatchTemplate"},
+		Annotations: fopts.Annotations,
+	}
+	// Bundle-level PatchTemplates are applied to every component, so each
+	// component's options must also be accepted by them.
+	if bw.Kind() == "Bundle" {
+		err = cmdlib.PreflightBundleOptions(bw.Bundle(), optsFn, preflightOpts)
+	} else {
+		err = cmdlib.PreflightOptions(bw.AllComponents(), optsFn, preflightOpts)
+	}
+	if err != nil {
+		return redactor.RedactError(err)
+	}
+
+	crds, err := cmdlib.ReadObjects(ctx, rw, o.crdFiles)
+	if err != nil {
+		return fmt.Errorf("reading CRD files: %v", err)
+	}
+
+	applier := valuefrom.NewRedactingApplier(
+		patchtmpl.NewApplierWithConfig(
+			patchtmpl.WithFilterOpts(fopts),
+			patchtmpl.WithIncludeTemplates(o.keepTemplates),
+			patchtmpl.WithCRDs(crds...),
+		), redactor)
 
 	switch bw.Kind() {
 	case "Component":
-		comp, err := applier.ApplyOptions(bw.Component(), optData)
+		comp, err := applyOptions(applier, bw.Component(), optsFn)
 		if err != nil {
 			return err
 		}
 		bw = wrapper.FromComponent(comp)
 	case "Bundle":
 		bun := bw.Bundle()
+		// Bundle-level PatchTemplates are applied after each component's own
+		// PatchTemplates.
+		bunApplier := valuefrom.NewRedactingApplier(
+			patchtmpl.NewBundleApplier(bun.PatchTemplates, patchtmpl.WithFilterOpts(fopts), patchtmpl.WithCRDs(crds...)), redactor)
 		var comps []*bundle.Component
 		for _, comp := range bun.Components {
-			comp, err := applier.ApplyOptions(comp, optData)
+			comp, err := applyOptions(applier, comp, optsFn)
 			if err != nil {
 				return err
 			}
+			if len(bun.PatchTemplates) > 0 {
+				comp, err = applyOptions(bunApplier, comp, optsFn)
+				if err != nil {
+					return err
+				}
+			}
 			comps = append(comps, comp)
 		}
 		bun.Components = comps
+		if !o.keepTemplates {
+			bun.PatchTemplates = nil
+		}
 		bw = wrapper.FromBundle(bun)
 	default:
 		return fmt.Errorf("bundle kind %q not supported for patching", bw.Kind())
@@ -90,3 +152,12 @@ func run(ctx context.Context, o *options, brw cmdlib.BundleReaderWriter, rw file
 
 	return brw.WriteBundleData(ctx, bw, gopt)
 }
+
+// applyOptions applies the options for a single component.
+func applyOptions(applier bundleoptions.Applier, comp *bundle.Component, optsFn bundleoptions.ComponentOptionsFunc) (*bundle.Component, error) {
+	opts, err := optsFn(comp)
+	if err != nil {
+		return nil, err
+	}
+	return applier.ApplyOptions(comp, opts)
+}
@@ -23,6 +23,9 @@ import (
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/export"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/filter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/find"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/images"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/lint"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/patch"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/validate"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/version"
@@ -68,12 +71,17 @@ func AddCommandsInternal(ctx context.Context, cio *cmdlib.CmdIO, flagset *flag.F
 
 	rootCmd.PersistentFlags().StringVarP(
 		&(gopts.OutputFormat), "format", "", "", "The output file format. One of either 'json' or 'yaml'. "+
+			"Some find subcommands also accept 'table' (find components, objects, templates and images), "+
+			"'csv' (find images) and 'dot' (find refs); other commands reject them. "+
 			"If not specified, it defaults to yaml.")
 
 	rootCmd.AddCommand(build.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(export.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(filter.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(find.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
+	rootCmd.AddCommand(images.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
+	rootCmd.AddCommand(lint.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
+	rootCmd.AddCommand(options.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(patch.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(validate.GetCommand(ctx, cio.FileIO, cio.StdIO, gopts))
 	rootCmd.AddCommand(version.GetCommand(cio))
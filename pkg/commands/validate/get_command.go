@@ -28,7 +28,9 @@ func GetCommand(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.Stdi
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate a bundle file",
-		Long:  `Validate a bundle file to ensure the bundle file follows the bundle schema and doesn't contain errors.`,
+		Long: `Validate a bundle file to ensure the bundle file follows the bundle schema and doesn't contain errors. ` +
+			`Bundles, Components, BundleBuilders and ComponentBuilders can be validated. ` +
+			`Builders are validated without reading the files they reference.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			action(ctx, fio, sio, cmd, gopts)
 		},
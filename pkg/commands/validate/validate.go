@@ -20,6 +20,7 @@ import (
 
 	log "k8s.io/klog"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/commands/cmdlib"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
@@ -35,19 +36,28 @@ func action(ctx context.Context, fio files.FileReaderWriter, sio cmdlib.StdioRea
 }
 
 func runValidate(ctx context.Context, brw cmdlib.BundleReaderWriter, gopt *cmdlib.GlobalOptions) error {
-	bw, err := brw.ReadBundleData(ctx, gopt)
+	// Builders are validated as written, without reading the files they
+	// reference.
+	bw, err := brw.ReadRawBundleData(ctx, gopt)
 	if err != nil {
 		return fmt.Errorf("error reading contents: %v", err)
 	}
 
-	bundleType := bw.Kind()
-	if bundleType == "Component" {
-		errs := validate.Component(bw.Component())
-		if len(errs) > 0 {
-			return fmt.Errorf("there were one or more errors found while validating the bundle:\n%v", errs.ToAggregate())
-		}
-	} else { // TODO(BradfordMedeiros): add validation for BundleBuilder, Bundle, ComponentBuilder here.
-		return fmt.Errorf("Kind %q not yet supported", bundleType)
+	var errs field.ErrorList
+	switch bundleType := bw.Kind(); bundleType {
+	case "Component":
+		errs = validate.Component(bw.Component())
+	case "Bundle":
+		errs = validate.Bundle(bw.Bundle())
+	case "ComponentBuilder":
+		errs = validate.ComponentBuilder(bw.ComponentBuilder())
+	case "BundleBuilder":
+		errs = validate.BundleBuilder(bw.BundleBuilder())
+	default:
+		return fmt.Errorf("Kind %q not supported", bundleType)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("there were one or more errors found while validating the bundle:\n%v", errs.ToAggregate())
 	}
 
 	log.Info("No errors found")
@@ -16,6 +16,7 @@ package core
 
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 )
@@ -41,6 +42,10 @@ type ObjectRef struct {
 	// The Kind for an Object.
 	Kind string
 
+	// The Namespace of an Object. It's empty for cluster-scoped objects, and
+	// for namespaced objects whose namespace isn't specified.
+	Namespace string
+
 	// The Name of an Object.
 	Name string
 }
@@ -50,6 +55,14 @@ func ObjectRefFromUnstructured(o *unstructured.Unstructured) ObjectRef {
 	return ObjectRef{
 		APIVersion: o.GetAPIVersion(),
 		Kind:       o.GetKind(),
+		Namespace:  o.GetNamespace(),
 		Name:       o.GetName(),
 	}
 }
+
+// GroupVersionKind parses the APIVersion and Kind of an ObjectRef. The group
+// is empty for the core API group, whose APIVersion is just a version such as
+// 'v1'.
+func (r ObjectRef) GroupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind)
+}
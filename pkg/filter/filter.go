@@ -17,10 +17,9 @@
 package filter
 
 import (
-	"strings"
-
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -33,30 +32,50 @@ func NewFilter() *Filter {
 	return &Filter{}
 }
 
-// Options for filtering bundles. By default, if any of the options match, then
+// Options for filtering bundles. By default, if all of the options match, then
 // the relevant component or object is removed. If InvertMatch is set, then the
 // objects are kept instead of removed.
 type Options struct {
-	// Kinds represent the Kinds to filter on. Can either be unqualified ("Deployment")
-	// or qualified ("apps/v1beta1,Pod"). Qualified kinds are often called
-	// GroupVersionKind in the Kubernetes Schema.
+	// Kinds represent the Kinds to filter on. Can either be unqualified
+	// ("Deployment"), qualified by group ("Deployment.apps"), or qualified by
+	// apiVersion ("apps/v1beta1,Deployment"). Qualified kinds are often called
+	// GroupVersionKind in the Kubernetes Schema. Each part may be a glob or a
+	// regular expression, as described for Names.
 	Kinds []string
 
 	// Names represent the names to filter on. For objects, this is the
-	// metadata.name field. For components, this is the ComponentName.
+	// metadata.name field. For components, this is the ComponentName. Names
+	// containing '*', '?' or '[' are globs, and names with the prefix "regex:"
+	// are regular expressions that must match the whole name.
 	Names []string
 
-	// Annotations contain key/value pairs to filter on. An empty string value matches
-	// all annotation-values for a particular key.
+	// Annotations contain key/value pairs to filter on. All of the pairs must
+	// match. An empty string value matches all annotation-values for a
+	// particular key.
 	Annotations map[string]string
 
-	// Labels contain key/value pairs to filter on. An empty string value matches
-	// all label-values for a particular key.
+	// Labels contain key/value pairs to filter on. All of the pairs must match.
+	// An empty string value matches all label-values for a particular key.
 	Labels map[string]string
 
+	// MatchExpressions are Kubernetes label selector requirements, using the
+	// In, NotIn, Exists and DoesNotExist operators. All of the requirements
+	// must match.
+	MatchExpressions []metav1.LabelSelectorRequirement
+
 	// Namespaces to filter on.
 	Namespaces []string
 
+	// ComponentNames represent the names of the components whose objects to
+	// filter on, and may be patterns as described for Names. They are only
+	// considered by MatchesComponentReference.
+	ComponentNames []string
+
+	// ComponentVersions represent the versions of the components whose objects
+	// to filter on, and may be patterns as described for Names. They are only
+	// considered by MatchesComponentReference.
+	ComponentVersions []string
+
 	// InvertMatch indicates wether to return the opposite match.
 	InvertMatch bool
 }
@@ -69,11 +88,14 @@ func OptionsFromObjectSelector(sel *bundle.ObjectSelector) *Options {
 	}
 
 	opts := &Options{
-		Kinds:       sel.Kinds,
-		Names:       sel.Names,
-		Annotations: sel.Annotations,
-		Labels:      sel.Labels,
-		Namespaces:  sel.Namespaces,
+		Kinds:             sel.Kinds,
+		Names:             sel.Names,
+		Annotations:       sel.Annotations,
+		Labels:            sel.Labels,
+		MatchExpressions:  sel.MatchExpressions,
+		Namespaces:        sel.Namespaces,
+		ComponentNames:    sel.ComponentNames,
+		ComponentVersions: sel.ComponentVersions,
 	}
 	if sel.InvertMatch != nil {
 		opts.InvertMatch = *sel.InvertMatch
@@ -173,11 +195,8 @@ func (f *Filter) PartitionObjects(data []*unstructured.Unstructured, o *Options)
 
 // objectData contains data about the object being filtered.
 type objectData struct {
-	// APIVersion of the object.
-	apiVersion string
-
-	// Kind of the object.
-	kind string
+	// ref identifies the object by APIVersion, Kind, Namespace and Name.
+	ref core.ObjectRef
 
 	// name of the object. For unstructured objects, this is is metadata.name.
 	// For components, this is ComponentName
@@ -190,20 +209,23 @@ type objectData struct {
 // objectDataFromComponent returns ObjectData created from a component.
 func objectDataFromComponent(c *bundle.Component) *objectData {
 	return &objectData{
-		apiVersion: c.APIVersion,
-		kind:       c.Kind,
-		name:       c.Spec.ComponentName,
-		meta:       c.ObjectMeta.DeepCopy(),
+		ref: core.ObjectRef{
+			APIVersion: c.APIVersion,
+			Kind:       c.Kind,
+			Namespace:  c.Namespace,
+			Name:       c.Name,
+		},
+		name: c.Spec.ComponentName,
+		meta: c.ObjectMeta.DeepCopy(),
 	}
 }
 
 // newObjectData returns ObjectData created from an unstructured Object.
 func newObjectData(uns *unstructured.Unstructured) *objectData {
 	return &objectData{
-		apiVersion: uns.GetAPIVersion(),
-		kind:       uns.GetKind(),
-		name:       uns.GetName(),
-		meta:       converter.FromUnstructured(uns).ExtractObjectMeta(),
+		ref:  core.ObjectRefFromUnstructured(uns),
+		name: uns.GetName(),
+		meta: converter.FromUnstructured(uns).ExtractObjectMeta(),
 	}
 }
 
@@ -217,11 +239,24 @@ func MatchesObject(obj *unstructured.Unstructured, o *Options) bool {
 	return matches(newObjectData(obj), o)
 }
 
+// MatchesComponentReference returns whether a component reference matches the
+// ComponentNames and ComponentVersions of the options. If both are empty, all
+// components match. InvertMatch does not apply to components: it only inverts
+// the match on the objects within the matching components.
+func MatchesComponentReference(ref bundle.ComponentReference, o *Options) bool {
+	if o == nil {
+		return true
+	}
+	return matchAnyPattern(o.ComponentNames, ref.ComponentName) &&
+		matchAnyPattern(o.ComponentVersions, ref.Version)
+}
+
 // Matches returns whether an object matches the given. The match functions
 // does an AND of ORS. In otherwords:
 //
 // (name1 OR name2 OR name3) AND
-// (kind2 OR kind2 OR kind3) AND etc.
+// (kind2 OR kind2 OR kind3) AND
+// (label1 AND label2) AND etc.
 func matches(d *objectData, o *Options) bool {
 	if o == nil {
 		return true
@@ -231,13 +266,7 @@ func matches(d *objectData, o *Options) bool {
 	if len(o.Kinds) > 0 {
 		matchesKinds = false
 		for _, optk := range o.Kinds {
-			objKind := d.kind
-			if strings.ContainsRune(optk, ',') {
-				// Assume this is a Qualified Kind match of the form
-				// "apps/v1beta1,Deployment". Commas shouldn't be normally in a kind.
-				objKind = d.apiVersion + "," + d.kind
-			}
-			if optk == objKind {
+			if matchKind(optk, d.ref) {
 				matchesKinds = true
 				break
 			}
@@ -247,7 +276,7 @@ func matches(d *objectData, o *Options) bool {
 	if len(o.Namespaces) > 0 {
 		matchesNS = false
 		for _, optn := range o.Namespaces {
-			if optn == d.meta.Namespace {
+			if optn == d.ref.Namespace {
 				matchesNS = true
 				break
 			}
@@ -257,31 +286,16 @@ func matches(d *objectData, o *Options) bool {
 	if len(o.Names) > 0 {
 		matchesNames = false
 		for _, optn := range o.Names {
-			if optn == d.meta.GetName() {
+			if matchPattern(optn, d.meta.GetName()) {
 				matchesNames = true
 				break
 			}
 		}
 	}
-	matchesAnnot := true
-	if len(o.Annotations) > 0 {
-		matchesAnnot = false
-		for key, v := range o.Annotations {
-			if val, ok := d.meta.Annotations[key]; ok && val == v {
-				matchesAnnot = true
-				break
-			}
-		}
-	}
-	matchesLabels := true
-	if len(o.Labels) > 0 {
-		matchesLabels = false
-		for key, v := range o.Labels {
-			if val, ok := d.meta.Labels[key]; ok && val == v {
-				matchesLabels = true
-				break
-			}
-		}
+	matchesAnnot := matchKeyValues(o.Annotations, d.meta.Annotations)
+	matchesLabels := matchKeyValues(o.Labels, d.meta.Labels)
+	if len(o.MatchExpressions) > 0 {
+		matchesLabels = matchesLabels && matchExpressions(o.MatchExpressions, d.meta.Labels)
 	}
 
 	matches := matchesKinds && matchesNS && matchesNames && matchesAnnot && matchesLabels
@@ -20,6 +20,8 @@ import (
 
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
@@ -238,13 +240,116 @@ func TestFilterObjects(t *testing.T) {
 			desc: "filter-success select: kind filter",
 			opt: &Options{
 				Kinds: []string{"Pod", "Deployment"}, // Pod or Deployment
+				Annotations: map[string]string{
+					"foof": "yar",
+				},
+			},
+			fil:         sel,
+			expObjNames: []string{"bog-pod"},
+		},
+		{
+			desc: "filter-success select: annotations are ANDed",
+			opt: &Options{
 				Annotations: map[string]string{
 					"foof": "yar",
 					"foo":  "bar",
 				},
 			},
+			fil: sel,
+		},
+		{
+			desc: "filter-success select: labels and annotations are ANDed",
+			opt: &Options{
+				Labels: map[string]string{
+					"component": "zork",
+				},
+				Annotations: map[string]string{
+					"zoof": "",
+				},
+			},
 			fil:         sel,
-			expObjNames: []string{"zap-pod", "bog-pod"},
+			expObjNames: []string{"zog-dep"},
+		},
+
+		// Set-based selectors
+		{
+			desc: "filter-success select: match expressions In",
+			opt: &Options{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "component", Operator: metav1.LabelSelectorOpIn, Values: []string{"bork", "nork"}},
+				},
+			},
+			fil:         sel,
+			expObjNames: []string{"bog-pod", "nog-pod"},
+		},
+		{
+			desc: "filter-success select: match expressions NotIn and Exists",
+			opt: &Options{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "component", Operator: metav1.LabelSelectorOpExists},
+					{Key: "component", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"zork"}},
+				},
+			},
+			fil:         sel,
+			expObjNames: []string{"bog-pod", "nog-pod"},
+		},
+		{
+			desc: "filter-success select: match expressions DoesNotExist",
+			opt: &Options{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "component", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			fil: sel,
+		},
+
+		// Patterns
+		{
+			desc: "filter-success select: name glob",
+			opt: &Options{
+				Names: []string{"*-pod"},
+			},
+			fil:         sel,
+			expObjNames: []string{"zap-pod", "bog-pod", "nog-pod"},
+		},
+		{
+			desc: "filter-success select: name character class glob",
+			opt: &Options{
+				Names: []string{"[bn]og-*"},
+			},
+			fil:         sel,
+			expObjNames: []string{"bog-pod", "nog-pod"},
+		},
+		{
+			desc: "filter-success select: name regex",
+			opt: &Options{
+				Names: []string{"regex:z.g-.*"},
+			},
+			fil:         sel,
+			expObjNames: []string{"zog-dep"},
+		},
+		{
+			desc: "filter-success select: regex must match the whole name",
+			opt: &Options{
+				Names: []string{"regex:zog"},
+			},
+			fil: sel,
+		},
+		{
+			desc: "filter-success select: kind glob",
+			opt: &Options{
+				Kinds: []string{"Dep*"},
+			},
+			fil:         sel,
+			expObjNames: []string{"zog-dep"},
+		},
+		{
+			desc: "filter-success select: qualified kind glob",
+			opt: &Options{
+				Kinds: []string{"v1*,Pod"},
+			},
+			fil:         sel,
+			expObjNames: []string{"zap-pod", "bog-pod", "nog-pod"},
 		},
 	}
 
@@ -669,3 +774,93 @@ spec:
 		})
 	}
 }
+
+func TestMatchKind(t *testing.T) {
+	testCases := []struct {
+		pattern    string
+		apiVersion string
+		kind       string
+		exp        bool
+	}{
+		{"Deployment", "apps/v1", "Deployment", true},
+		{"Deployment.apps", "apps/v1", "Deployment", true},
+		{"Deployment.apps", "extensions/v1beta1", "Deployment", false},
+		{"Pod.", "v1", "Pod", true},
+		{"Pod.", "example.com/v1", "Pod", false},
+		{"*.apps", "apps/v1", "StatefulSet", true},
+		{"*.*.k8s.io", "rbac.authorization.k8s.io/v1", "Role", true},
+		{"*.*.k8s.io", "apps/v1", "Deployment", false},
+		{"Role.*.k8s.io", "rbac.authorization.k8s.io/v1", "Role", true},
+		{"apps/v1,Deployment", "apps/v1", "Deployment", true},
+		{"apps/v1,Deployment", "apps/v1beta1", "Deployment", false},
+		{"regex:(Cluster)?Role", "rbac.authorization.k8s.io/v1", "ClusterRole", true},
+	}
+	for _, tc := range testCases {
+		if got := matchKind(tc.pattern, core.ObjectRef{APIVersion: tc.apiVersion, Kind: tc.kind}); got != tc.exp {
+			t.Errorf("matchKind(%q, %q, %q) = %t, expected %t", tc.pattern, tc.apiVersion, tc.kind, got, tc.exp)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		opt    *Options
+		expErr bool
+	}{
+		{desc: "valid", opt: &Options{Names: []string{"foo-*", "regex:^a+$"}, Kinds: []string{"*.apps"}}},
+		{desc: "bad regex", opt: &Options{Names: []string{"regex:("}}, expErr: true},
+		{desc: "bad glob", opt: &Options{Kinds: []string{"Dep[loy"}}, expErr: true},
+		{
+			desc: "bad requirement",
+			opt: &Options{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "foo", Operator: metav1.LabelSelectorOpIn},
+			}},
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.opt.Validate()
+			if (err != nil) != tc.expErr {
+				t.Errorf("got error %v, expected error: %t", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestMatchesComponentReference(t *testing.T) {
+	ref := bundle.ComponentReference{ComponentName: "etcd-server", Version: "3.4.1"}
+	testCases := []struct {
+		desc string
+		opt  *Options
+		exp  bool
+	}{
+		{desc: "nil options", exp: true},
+		{desc: "no component patterns", opt: &Options{Names: []string{"foo"}}, exp: true},
+		{desc: "name glob", opt: &Options{ComponentNames: []string{"kube-*", "etcd-*"}}, exp: true},
+		{desc: "name mismatch", opt: &Options{ComponentNames: []string{"etcd"}}, exp: false},
+		{
+			desc: "name and version",
+			opt:  &Options{ComponentNames: []string{"etcd-server"}, ComponentVersions: []string{"3.4.*"}},
+			exp:  true,
+		},
+		{
+			desc: "version mismatch",
+			opt:  &Options{ComponentVersions: []string{"regex:3\\.[0-3]\\..*"}},
+			exp:  false,
+		},
+		{
+			desc: "invert match doesn't apply",
+			opt:  &Options{ComponentNames: []string{"etcd-server"}, InvertMatch: true},
+			exp:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := MatchesComponentReference(ref, tc.opt); got != tc.exp {
+				t.Errorf("MatchesComponentReference(%v) = %t, expected %t", tc.opt, got, tc.exp)
+			}
+		})
+	}
+}
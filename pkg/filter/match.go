@@ -0,0 +1,214 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+)
+
+// RegexPrefix marks a name or kind pattern as a regular expression, as in
+// "regex:^etcd-(server|client)$". Regular expressions must match the entire
+// value unless they are explicitly unanchored, as in "regex:.*etcd.*".
+const RegexPrefix = "regex:"
+
+// globChars are the characters that make a name or kind pattern a glob.
+const globChars = "*?["
+
+// patternCache caches compiled patterns, keyed by pattern.
+var patternCache sync.Map
+
+// compilePattern compiles a name or kind pattern into a regular expression.
+// Patterns with the RegexPrefix are regular expressions, patterns containing
+// any of '*', '?' or '[' are globs, and all other patterns are matched
+// exactly, in which case nil is returned.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	isRegex := strings.HasPrefix(pattern, RegexPrefix)
+	if !isRegex && !strings.ContainsAny(pattern, globChars) {
+		return nil, nil
+	}
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	var expr string
+	if isRegex {
+		expr = "^(?:" + strings.TrimPrefix(pattern, RegexPrefix) + ")$"
+	} else {
+		var err error
+		expr, err = globToRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// globToRegex converts a glob into an anchored regular expression. In a glob,
+// '*' matches any sequence of characters, '?' matches any single character and
+// '[...]' matches a character class, as in path.Match. Unlike path.Match, '*'
+// also matches '/'.
+func globToRegex(glob string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("invalid glob %q: unterminated character class", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// matchPattern returns whether a value matches a name or kind pattern.
+// Invalid patterns never match; use Options.Validate to detect them.
+func matchPattern(pattern, val string) bool {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	if re == nil {
+		return pattern == val
+	}
+	return re.MatchString(val)
+}
+
+// matchAnyPattern returns whether a value matches any of the patterns. An
+// empty list of patterns matches every value.
+func matchAnyPattern(patterns []string, val string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchPattern(p, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKind returns whether an object's kind matches a kind pattern. Kind
+// patterns have one of the following forms, where each part may itself be a
+// glob or regular expression:
+//
+//   - "Deployment" matches the kind in any API group.
+//   - "Deployment.apps" matches the kind in the given API group, at any
+//     version. The core group is written as an empty group, as in "Pod.".
+//   - "apps/v1,Deployment" matches the kind at an exact apiVersion.
+func matchKind(pattern string, ref core.ObjectRef) bool {
+	if strings.HasPrefix(pattern, RegexPrefix) {
+		return matchPattern(pattern, ref.Kind)
+	}
+	if i := strings.IndexRune(pattern, ','); i >= 0 {
+		// Assume this is a Qualified Kind match of the form
+		// "apps/v1beta1,Deployment". Commas shouldn't be normally in a kind.
+		return matchPattern(pattern[:i], ref.APIVersion) && matchPattern(pattern[i+1:], ref.Kind)
+	}
+	if i := strings.IndexRune(pattern, '.'); i >= 0 {
+		// Kinds never contain dots, so this is a group-qualified kind of the
+		// form "Deployment.apps".
+		return matchPattern(pattern[:i], ref.Kind) && matchPattern(pattern[i+1:], ref.GroupVersionKind().Group)
+	}
+	return matchPattern(pattern, ref.Kind)
+}
+
+// matchKeyValues returns whether all of the key/value pairs are present in
+// the map. An empty value matches any value for the key.
+func matchKeyValues(want, have map[string]string) bool {
+	for k, v := range want {
+		val, ok := have[k]
+		if !ok || (v != "" && val != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchExpressions returns whether the labels satisfy all of the label
+// selector requirements. Invalid requirements never match; use
+// Options.Validate to detect them.
+func matchExpressions(reqs []metav1.LabelSelectorRequirement, have map[string]string) bool {
+	sel, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchExpressions: reqs})
+	if err != nil {
+		return false
+	}
+	return sel.Matches(labels.Set(have))
+}
+
+// Validate returns an error if any of the name, kind or component patterns
+// are invalid regular expressions or globs, or if any of the label selector
+// requirements are invalid.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, n := range o.Names {
+		if _, err := compilePattern(n); err != nil {
+			return fmt.Errorf("invalid name pattern: %v", err)
+		}
+	}
+	for _, n := range append(append([]string{}, o.ComponentNames...), o.ComponentVersions...) {
+		if _, err := compilePattern(n); err != nil {
+			return fmt.Errorf("invalid component pattern: %v", err)
+		}
+	}
+	for _, k := range o.Kinds {
+		if strings.HasPrefix(k, RegexPrefix) {
+			if _, err := compilePattern(k); err != nil {
+				return fmt.Errorf("invalid kind pattern: %v", err)
+			}
+			continue
+		}
+		for _, part := range strings.FieldsFunc(k, func(r rune) bool { return r == ',' || r == '.' }) {
+			if _, err := compilePattern(part); err != nil {
+				return fmt.Errorf("invalid kind pattern %q: %v", k, err)
+			}
+		}
+	}
+	if len(o.MatchExpressions) > 0 {
+		if _, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchExpressions: o.MatchExpressions}); err != nil {
+			return fmt.Errorf("invalid label selector: %v", err)
+		}
+	}
+	return nil
+}
@@ -16,7 +16,10 @@ package find
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/blang/semver"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
@@ -91,6 +94,70 @@ func (f *ComponentFinder) UniqueComponentFromName(name string) (*bundle.Componen
 	return f.Component(comps[0]), nil
 }
 
+// Matching returns the components with a name whose versions satisfy a
+// constraint, sorted by ascending version. The constraint is a SemVer range,
+// such as '>=1.4.0 <1.5.0', '1.4.x' or '>=1.0.0 <2.0.0 || >=3.0.0'. If the
+// constraint is empty, all versions match. Otherwise, pre-release versions
+// such as '1.5.0-rc.1' only match if the constraint contains a pre-release
+// version, so that '1.4.x' doesn't match '1.5.0-rc.1'.
+//
+// Versions that are equal according to SemVer, because they only differ in
+// build metadata, are sorted by their string form, so the order is
+// deterministic. If a component with the name has a version that isn't valid
+// SemVer, an error is returned.
+func (f *ComponentFinder) Matching(name, constraint string) ([]*bundle.Component, error) {
+	matches := func(semver.Version) bool { return true }
+	if constraint != "" {
+		rng, err := semver.ParseRange(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %v", constraint, err)
+		}
+		// Ranges don't contain hyphens other than in pre-release versions.
+		allowPre := strings.Contains(constraint, "-")
+		matches = func(v semver.Version) bool {
+			return (allowPre || len(v.Pre) == 0) && rng(v)
+		}
+	}
+
+	type versioned struct {
+		ver  semver.Version
+		comp *bundle.Component
+	}
+	var found []versioned
+	for _, c := range f.nameCompLookup[name] {
+		ver, err := semver.Parse(c.Spec.Version)
+		if err != nil {
+			return nil, fmt.Errorf("component %q has invalid version %q: %v", name, c.Spec.Version, err)
+		}
+		if matches(ver) {
+			found = append(found, versioned{ver, c})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if cmp := found[i].ver.Compare(found[j].ver); cmp != 0 {
+			return cmp < 0
+		}
+		return found[i].comp.Spec.Version < found[j].comp.Spec.Version
+	})
+
+	var out []*bundle.Component
+	for _, v := range found {
+		out = append(out, v.comp)
+	}
+	return out, nil
+}
+
+// Latest returns the component with a name whose version is the highest
+// version satisfying a constraint, as for Matching. If no component is found,
+// nil is returned.
+func (f *ComponentFinder) Latest(name, constraint string) (*bundle.Component, error) {
+	comps, err := f.Matching(name, constraint)
+	if err != nil || len(comps) == 0 {
+		return nil, err
+	}
+	return comps[len(comps)-1], nil
+}
+
 // Objects returns Component's Cluster objects (given some object
 // ref) or nil.
 func (f *ComponentFinder) Objects(cref bundle.ComponentReference, ref core.ObjectRef) []*unstructured.Unstructured {
@@ -144,6 +211,10 @@ func (c *ObjectFinder) Objects(ref core.ObjectRef) []*unstructured.Unstructured
 			// Doing a search based on kind
 			key.Kind = o.GetKind()
 		}
+		if ref.Namespace != "" {
+			// Doing a search based on namespace
+			key.Namespace = o.GetNamespace()
+		}
 		if key == ref {
 			out = append(out, o)
 		}
@@ -23,6 +23,7 @@ import (
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
 )
 
 var validComponentExample = `
@@ -140,6 +141,18 @@ spec:
     kind: DaemonSet
     metadata:
       name: kube-proxy
+
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: kube-system
+
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: default
 `
 
 func TestComponentFinder_PartialLookup(t *testing.T) {
@@ -157,12 +170,12 @@ func TestComponentFinder_PartialLookup(t *testing.T) {
 		{
 			desc: "get everything",
 			ref:  core.ObjectRef{},
-			exp:  []string{"pody", "dodo", "kube-proxy", "kube-proxy"},
+			exp:  []string{"pody", "dodo", "kube-proxy", "kube-proxy", "config", "config"},
 		},
 		{
 			desc: "get apiversion",
 			ref:  core.ObjectRef{APIVersion: "v1"},
-			exp:  []string{"pody", "dodo", "kube-proxy"},
+			exp:  []string{"pody", "dodo", "kube-proxy", "config", "config"},
 		},
 		{
 			desc: "get kind",
@@ -188,6 +201,20 @@ func TestComponentFinder_PartialLookup(t *testing.T) {
 			desc: "get none",
 			ref:  core.ObjectRef{Name: "kube-proxy", APIVersion: "zed"},
 		},
+		{
+			desc: "get namespace",
+			ref:  core.ObjectRef{Namespace: "kube-system"},
+			exp:  []string{"config"},
+		},
+		{
+			desc: "get name in namespace",
+			ref:  core.ObjectRef{Kind: "ConfigMap", Namespace: "default", Name: "config"},
+			exp:  []string{"config"},
+		},
+		{
+			desc: "get name in wrong namespace",
+			ref:  core.ObjectRef{Kind: "ConfigMap", Namespace: "kube-public", Name: "config"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -208,3 +235,102 @@ func getObjNames(objs []*unstructured.Unstructured) []string {
 	}
 	return names
 }
+
+var versionedComponentExample = `
+components:
+- spec: {componentName: etcd, version: 1.4.2}
+- spec: {componentName: etcd, version: 1.4.10}
+- spec: {componentName: etcd, version: 1.5.0}
+- spec: {componentName: etcd, version: 1.4.10+build.2}
+- spec: {componentName: etcd, version: 1.4.10+build.1}
+- spec: {componentName: etcd, version: 1.5.0-rc.1}
+- spec: {componentName: dns, version: 2.0.0}
+- spec: {componentName: broken, version: latest}
+`
+
+func TestComponentFinder_Versions(t *testing.T) {
+	b, err := converter.FromYAMLString(versionedComponentExample).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder := NewComponentFinder(b.Components)
+
+	testCases := []struct {
+		desc         string
+		name         string
+		constraint   string
+		exp          []string
+		expLatest    string
+		expErrSubstr string
+	}{
+		{
+			desc:      "all versions",
+			name:      "etcd",
+			exp:       []string{"1.4.2", "1.4.10", "1.4.10+build.1", "1.4.10+build.2", "1.5.0-rc.1", "1.5.0"},
+			expLatest: "1.5.0",
+		},
+		{
+			// Pre-release versions don't match constraints without pre-release
+			// versions.
+			desc:       "wildcard",
+			name:       "etcd",
+			constraint: "1.4.x",
+			exp:        []string{"1.4.2", "1.4.10", "1.4.10+build.1", "1.4.10+build.2"},
+			expLatest:  "1.4.10+build.2",
+		},
+		{
+			desc:       "range",
+			name:       "etcd",
+			constraint: ">=1.4.3 <1.5.0 || >=1.5.0-rc.1 <1.5.0",
+			exp:        []string{"1.4.10", "1.4.10+build.1", "1.4.10+build.2", "1.5.0-rc.1"},
+			expLatest:  "1.5.0-rc.1",
+		},
+		{
+			desc:       "no match",
+			name:       "dns",
+			constraint: "<2.0.0",
+		},
+		{
+			desc: "unknown component",
+			name: "nope",
+		},
+		{
+			desc:         "invalid constraint",
+			name:         "etcd",
+			constraint:   "~>1",
+			expErrSubstr: "invalid version constraint",
+		},
+		{
+			desc:         "invalid version",
+			name:         "broken",
+			expErrSubstr: `component "broken" has invalid version "latest"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comps, err := finder.Matching(tc.name, tc.constraint)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			var got []string
+			for _, c := range comps {
+				got = append(got, c.Spec.Version)
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("Matching(%q, %q) = %v, expected %v", tc.name, tc.constraint, got, tc.exp)
+			}
+
+			latest, err := finder.Latest(tc.name, tc.constraint)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			var gotLatest string
+			if latest != nil {
+				gotLatest = latest.Spec.Version
+			}
+			if gotLatest != tc.expLatest {
+				t.Errorf("Latest(%q, %q) = %q, expected %q", tc.name, tc.constraint, gotLatest, tc.expLatest)
+			}
+		})
+	}
+}
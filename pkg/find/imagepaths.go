@@ -0,0 +1,215 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ImagePathConfig contains the image path rules for custom resources. It is
+// usually read from a YAML or JSON file of the form:
+//
+//	imagePaths:
+//	- apiVersion: monitoring.coreos.com/v1
+//	  kind: Prometheus
+//	  paths:
+//	  - spec.image
+//	  - '{.spec.containers[*].image}'
+type ImagePathConfig struct {
+	// ImagePaths are the image path rules.
+	ImagePaths []ImagePathRule `json:"imagePaths,omitempty"`
+}
+
+// ImagePathRule specifies where to find the images in objects of a kind.
+type ImagePathRule struct {
+	// APIVersion of the objects. If empty, objects of any APIVersion match.
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of the objects.
+	Kind string `json:"kind"`
+
+	// Paths are the paths of the image fields, either as dot-separated field
+	// paths such as 'spec.version.image', or as JSONPath expressions such as
+	// '{.spec.containers[*].image}' or '$.spec.containers[*].image'. A path
+	// element may be a field name, '[*]' or '*' to select every list element or
+	// map field, '[]' to select every list element, or '[<n>]' to select a
+	// list element by index. Fields containing dots can be written as
+	// "['a.b']".
+	Paths []string `json:"paths"`
+}
+
+// ImagePaths are compiled ImagePathRules.
+type ImagePaths struct {
+	rules []compiledImagePathRule
+}
+
+// compiledImagePathRule is an ImagePathRule with parsed paths.
+type compiledImagePathRule struct {
+	apiVersion string
+	kind       string
+	paths      [][]imagePathElem
+}
+
+// imagePathElem is a single element of an image path.
+type imagePathElem struct {
+	// field is the name of a map field, if not a wildcard or index.
+	field string
+
+	// wildcard selects every list element or map field.
+	wildcard bool
+
+	// index selects a list element by index. It is -1 otherwise.
+	index int
+}
+
+// NewImagePaths compiles image path rules.
+func NewImagePaths(rules ...ImagePathRule) (*ImagePaths, error) {
+	p := &ImagePaths{}
+	for i, r := range rules {
+		if r.Kind == "" {
+			return nil, fmt.Errorf("image path rule %d: kind must be specified", i)
+		}
+		if len(r.Paths) == 0 {
+			return nil, fmt.Errorf("image path rule %d for kind %q: at least one path must be specified", i, r.Kind)
+		}
+		c := compiledImagePathRule{apiVersion: r.APIVersion, kind: r.Kind}
+		for _, path := range r.Paths {
+			elems, err := parseImagePath(path)
+			if err != nil {
+				return nil, fmt.Errorf("image path rule %d for kind %q: path %q: %v", i, r.Kind, path, err)
+			}
+			c.paths = append(c.paths, elems)
+		}
+		p.rules = append(p.rules, c)
+	}
+	return p, nil
+}
+
+// pathsFor returns the image paths for an object.
+func (p *ImagePaths) pathsFor(obj *unstructured.Unstructured) [][]imagePathElem {
+	if p == nil {
+		return nil
+	}
+	var paths [][]imagePathElem
+	for _, r := range p.rules {
+		if r.kind == obj.GetKind() && (r.apiVersion == "" || r.apiVersion == obj.GetAPIVersion()) {
+			paths = append(paths, r.paths...)
+		}
+	}
+	return paths
+}
+
+// parseImagePath parses a field path or JSONPath expression.
+func parseImagePath(path string) ([]imagePathElem, error) {
+	p := strings.TrimSpace(path)
+	if strings.HasPrefix(p, "{") {
+		if !strings.HasSuffix(p, "}") {
+			return nil, fmt.Errorf("unterminated '{'")
+		}
+		p = p[1 : len(p)-1]
+	}
+	p = strings.TrimPrefix(p, "$")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var elems []imagePathElem
+	for len(p) > 0 {
+		switch {
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '['")
+			}
+			elem, err := parseBracket(p[1:end])
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+			p = p[end+1:]
+		case p[0] == '.':
+			p = p[1:]
+			if p == "" || p[0] == '.' || p[0] == '[' {
+				return nil, fmt.Errorf("empty field name")
+			}
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			field := p[:end]
+			if field == "*" {
+				elems = append(elems, imagePathElem{wildcard: true, index: -1})
+			} else {
+				elems = append(elems, imagePathElem{field: field, index: -1})
+			}
+			p = p[end:]
+		}
+	}
+	return elems, nil
+}
+
+// parseBracket parses the contents of a bracketed path element.
+func parseBracket(s string) (imagePathElem, error) {
+	switch {
+	case s == "" || s == "*":
+		return imagePathElem{wildcard: true, index: -1}, nil
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return imagePathElem{field: s[1 : len(s)-1], index: -1}, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return imagePathElem{}, fmt.Errorf("unsupported path element [%s]", s)
+	}
+	return imagePathElem{index: i}, nil
+}
+
+// walkImagePath calls visit for every string value at a path, and replaces it
+// with the returned value. The field names passed to visit follow the same
+// conventions as containerImageRecurser. It returns the new value of val.
+func walkImagePath(val interface{}, path []imagePathElem, fieldName, parentFieldName string, field imageField, visit func(fieldName, parentFieldName string, field imageField, img string) string) interface{} {
+	if len(path) == 0 {
+		if s, ok := val.(string); ok {
+			return visit(fieldName, parentFieldName, field, s)
+		}
+		return val
+	}
+	e := path[0]
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if e.wildcard {
+			for _, k := range sortedKeys(v) {
+				v[k] = walkImagePath(v[k], path[1:], k, fieldName, field.child(v, fieldName, k), visit)
+			}
+		} else if c, ok := v[e.field]; ok && e.index < 0 {
+			v[e.field] = walkImagePath(c, path[1:], e.field, fieldName, field.child(v, fieldName, e.field), visit)
+		}
+	case []interface{}:
+		switch {
+		case e.wildcard:
+			for i, c := range v {
+				v[i] = walkImagePath(c, path[1:], fieldName, parentFieldName, field.index(i), visit)
+			}
+		case e.index >= 0 && e.index < len(v):
+			v[e.index] = walkImagePath(v[e.index], path[1:], fieldName, parentFieldName, field.index(e.index), visit)
+		}
+	}
+	return val
+}
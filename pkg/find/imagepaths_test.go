@@ -0,0 +1,201 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+const customResources = `
+kind: Component
+spec:
+  componentName: operators
+  objects:
+  - apiVersion: monitoring.coreos.com/v1
+    kind: Prometheus
+    metadata:
+      name: prom
+    spec:
+      image: quay.io/prometheus/prometheus:v2.0
+      version:
+        image: quay.io/prometheus/prometheus:v2.1
+      containers:
+      - image: gcr.io/sidecar:1.0
+      sidecars:
+        reloader: {image: gcr.io/reloader:1.0}
+        proxy: {image: gcr.io/proxy:1.0}
+  - apiVersion: example.com/v2
+    kind: Prometheus
+    metadata:
+      name: other
+    spec:
+      image: quay.io/other:1.0
+  - apiVersion: example.com/v1
+    kind: Database
+    metadata:
+      name: db
+    spec:
+      images: [gcr.io/db:1.0, gcr.io/db-backup:1.0]
+      'registry.io/image': gcr.io/dotted:1.0
+`
+
+func TestParseImagePath(t *testing.T) {
+	testCases := []struct {
+		path         string
+		exp          []imagePathElem
+		expErrSubstr string
+	}{
+		{
+			path: "spec.version.image",
+			exp:  []imagePathElem{{field: "spec", index: -1}, {field: "version", index: -1}, {field: "image", index: -1}},
+		},
+		{
+			path: "{.spec.containers[*].image}",
+			exp:  []imagePathElem{{field: "spec", index: -1}, {field: "containers", index: -1}, {wildcard: true, index: -1}, {field: "image", index: -1}},
+		},
+		{
+			path: "$.spec.images[1]",
+			exp:  []imagePathElem{{field: "spec", index: -1}, {field: "images", index: -1}, {index: 1}},
+		},
+		{
+			path: "spec.*.image",
+			exp:  []imagePathElem{{field: "spec", index: -1}, {wildcard: true, index: -1}, {field: "image", index: -1}},
+		},
+		{
+			path: "spec['registry.io/image']",
+			exp:  []imagePathElem{{field: "spec", index: -1}, {field: "registry.io/image", index: -1}},
+		},
+		{path: "", expErrSubstr: "empty path"},
+		{path: "spec..image", expErrSubstr: "empty field name"},
+		{path: "{.spec.image", expErrSubstr: "unterminated '{'"},
+		{path: "spec.images[0", expErrSubstr: "unterminated '['"},
+		{path: "spec.images[?(@.name=='a')]", expErrSubstr: "unsupported path element"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			got, err := parseImagePath(tc.path)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("parseImagePath(%q) = %+v, expected %+v", tc.path, got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestNewImagePaths_Errors(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		rule         ImagePathRule
+		expErrSubstr string
+	}{
+		{desc: "no kind", rule: ImagePathRule{Paths: []string{"spec.image"}}, expErrSubstr: "kind must be specified"},
+		{desc: "no paths", rule: ImagePathRule{Kind: "Foo"}, expErrSubstr: "at least one path"},
+		{desc: "bad path", rule: ImagePathRule{Kind: "Foo", Paths: []string{"a..b"}}, expErrSubstr: `kind "Foo": path "a..b"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewImagePaths(tc.rule)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Error(cerr)
+			}
+		})
+	}
+}
+
+func TestImageFinder_ImagePaths(t *testing.T) {
+	paths, err := NewImagePaths(
+		ImagePathRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "Prometheus",
+			Paths: []string{
+				"spec.image",
+				"spec.version.image",
+				"{.spec.sidecars.*.image}",
+				// Overlaps the built-in rules, so it mustn't find the image twice.
+				"$.spec.containers[*].image",
+			},
+		},
+		ImagePathRule{
+			Kind:  "Database",
+			Paths: []string{"spec.images[]", "spec['registry.io/image']"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comp, err := converter.FromYAMLString(customResources).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, img := range NewImageFinder([]*bundle.Component{comp}, WithImagePaths(paths)).AllContainerImages() {
+		got = append(got, img.Key.Object.Name+" "+img.Image)
+	}
+	sort.Strings(got)
+	exp := []string{
+		"db gcr.io/db-backup:1.0",
+		"db gcr.io/db:1.0",
+		"db gcr.io/dotted:1.0",
+		"prom gcr.io/proxy:1.0",
+		"prom gcr.io/reloader:1.0",
+		"prom gcr.io/sidecar:1.0",
+		"prom quay.io/prometheus/prometheus:v2.0",
+		"prom quay.io/prometheus/prometheus:v2.1",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got images\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// Without image paths, only the built-in rules are used.
+	got = nil
+	for _, img := range NewImageFinder([]*bundle.Component{comp}).AllContainerImages() {
+		got = append(got, img.Image)
+	}
+	if !reflect.DeepEqual(got, []string{"gcr.io/sidecar:1.0"}) {
+		t.Errorf("got images %v without image paths, expected only the container image", got)
+	}
+
+	// Images at the image paths can be modified.
+	NewImageFinder([]*bundle.Component{comp}, WithImagePaths(paths)).WalkAllImages(func(_ core.ClusterObjectKey, img string) string {
+		return "mirror/" + img
+	})
+	compStr, err := converter.FromObject(comp).ToYAMLString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"image: mirror/quay.io/prometheus/prometheus:v2.1",
+		"- mirror/gcr.io/db:1.0",
+		"registry.io/image: mirror/gcr.io/dotted:1.0",
+		"image: mirror/gcr.io/sidecar:1.0",
+		"image: quay.io/other:1.0",
+	} {
+		if !strings.Contains(compStr, s) {
+			t.Errorf("expected component to contain %q, but got\n%s", s, compStr)
+		}
+	}
+}
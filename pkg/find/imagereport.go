@@ -0,0 +1,190 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"sort"
+
+	"github.com/blang/semver"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+)
+
+// ImageLocation is an image found in an object, along with where it was
+// found, for reports that map images to components.
+type ImageLocation struct {
+	// Key identifies the component and object containing the image.
+	Key core.ClusterObjectKey
+
+	// Container is the name of the container using the image. It's empty for
+	// images that aren't in containers, such as NodeConfig OS images.
+	Container string `json:",omitempty"`
+
+	// Field is the path of the image field within the object, such as
+	// 'spec.template.spec.containers[0].image'.
+	Field string
+
+	// Image is the image. For example: `gcr.io/google_containers/etcd:3.1.11`
+	Image string
+}
+
+// AllImageLocations returns all the images found in the components, along
+// with their locations, in the order of the components and objects.
+func (b *ImageFinder) AllImageLocations() []*ImageLocation {
+	var locs []*ImageLocation
+	for _, c := range b.components {
+		for _, obj := range c.Spec.Objects {
+			key := core.ClusterObjectKey{
+				Component: c.ComponentReference(),
+				Object:    core.ObjectRefFromUnstructured(obj),
+			}
+			b.walkImageFields(obj, nil, func(field imageField, img string) string {
+				locs = append(locs, &ImageLocation{
+					Key:       key,
+					Container: field.container,
+					Field:     field.path,
+					Image:     img,
+				})
+				return img
+			})
+		}
+	}
+	return locs
+}
+
+// ImageGroup contains the images found in a component or in an object.
+type ImageGroup struct {
+	// Component is the component containing the images.
+	Component bundle.ComponentReference
+
+	// Object is the object containing the images, if the images are grouped
+	// by object.
+	Object *core.ObjectRef `json:",omitempty"`
+
+	// Images are the images found, with their locations.
+	Images []*ImageLocation
+}
+
+// GroupImagesByComponent groups image locations by component. The groups are
+// sorted by component name and version.
+func GroupImagesByComponent(locs []*ImageLocation) []*ImageGroup {
+	return groupImages(locs, func(l *ImageLocation) ImageGroup {
+		return ImageGroup{Component: l.Key.Component}
+	})
+}
+
+// GroupImagesByObject groups image locations by component and object. The
+// groups are sorted by component name and version, and then by object kind,
+// namespace and name.
+func GroupImagesByObject(locs []*ImageLocation) []*ImageGroup {
+	return groupImages(locs, func(l *ImageLocation) ImageGroup {
+		obj := l.Key.Object
+		return ImageGroup{Component: l.Key.Component, Object: &obj}
+	})
+}
+
+// groupImages groups image locations by the group key returned for each
+// location. Within a group, locations keep their order.
+func groupImages(locs []*ImageLocation, keyFn func(*ImageLocation) ImageGroup) []*ImageGroup {
+	type groupKey struct {
+		comp bundle.ComponentReference
+		obj  core.ObjectRef
+	}
+	var groups []*ImageGroup
+	byKey := make(map[groupKey]*ImageGroup)
+	for _, l := range locs {
+		g := keyFn(l)
+		k := groupKey{comp: g.Component}
+		if g.Object != nil {
+			k.obj = *g.Object
+		}
+		if byKey[k] == nil {
+			byKey[k] = &g
+			groups = append(groups, &g)
+		}
+		byKey[k].Images = append(byKey[k].Images, l)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Component.ComponentName != b.Component.ComponentName {
+			return a.Component.ComponentName < b.Component.ComponentName
+		}
+		if a.Component.Version != b.Component.Version {
+			return versionLess(a.Component.Version, b.Component.Version)
+		}
+		if a.Object == nil || b.Object == nil {
+			return false
+		}
+		if a.Object.Kind != b.Object.Kind {
+			return a.Object.Kind < b.Object.Kind
+		}
+		if a.Object.Namespace != b.Object.Namespace {
+			return a.Object.Namespace < b.Object.Namespace
+		}
+		return a.Object.Name < b.Object.Name
+	})
+	return groups
+}
+
+// versionLess returns whether version a sorts before version b. Versions are
+// compared as semantic versions, so that 1.9.0 sorts before 1.10.0. If either
+// version isn't a valid semantic version, they're compared as strings.
+func versionLess(a, b string) bool {
+	av, aerr := semver.Parse(a)
+	bv, berr := semver.Parse(b)
+	if aerr != nil || berr != nil {
+		return a < b
+	}
+	if c := av.Compare(bv); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+// ImageDiff contains the differences between the image sets of two bundles.
+type ImageDiff struct {
+	// Added are the locations of the images that are only in the new bundle.
+	Added []*ImageLocation
+
+	// Removed are the locations of the images that are only in the old bundle.
+	Removed []*ImageLocation
+}
+
+// DiffImages compares the image sets of two bundles. An image is added if it
+// is found anywhere in the new bundle, but nowhere in the old bundle, and
+// removed in the opposite case. All the locations of added and removed images
+// are returned, so that the images can be mapped to components.
+func DiffImages(oldLocs, newLocs []*ImageLocation) *ImageDiff {
+	return &ImageDiff{
+		Added:   imagesNotIn(newLocs, oldLocs),
+		Removed: imagesNotIn(oldLocs, newLocs),
+	}
+}
+
+// imagesNotIn returns the locations of the images that aren't in others.
+func imagesNotIn(locs, others []*ImageLocation) []*ImageLocation {
+	seen := make(map[string]bool)
+	for _, l := range others {
+		seen[l.Image] = true
+	}
+	var out []*ImageLocation
+	for _, l := range locs {
+		if !seen[l.Image] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
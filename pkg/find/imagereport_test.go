@@ -0,0 +1,177 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+)
+
+const imageReportBundle = `
+components:
+- spec:
+    componentName: etcd
+    version: 1.0.0
+    objects:
+    - apiVersion: v1
+      kind: Pod
+      metadata:
+        name: etcd-server
+        namespace: kube-system
+      spec:
+        initContainers:
+        - name: init
+          image: gcr.io/foo/init:1.0
+        containers:
+        - name: etcd
+          image: gcr.io/foo/etcd:3.1
+        - name: backup
+          image: gcr.io/foo/backup:1.0
+- spec:
+    componentName: app
+    version: 2.0.0
+    objects:
+    - apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: web
+      spec:
+        template:
+          spec:
+            containers:
+            - name: web
+              image: gcr.io/foo/web:2.0
+    - apiVersion: bundleext.gke.io/v1alpha1
+      kind: NodeConfig
+      metadata:
+        name: node
+      osImage:
+        url: gs://foo/os-image
+    - apiVersion: monitoring.coreos.com/v1
+      kind: Prometheus
+      metadata:
+        name: prom
+      spec:
+        image: gcr.io/foo/prometheus:2.0
+        sidecars:
+          'config.reloader': gcr.io/foo/reloader:1.0
+`
+
+func imageLocationStrings(locs []*ImageLocation) []string {
+	var out []string
+	for _, l := range locs {
+		out = append(out, strings.Join([]string{l.Key.Component.ComponentName, l.Key.Object.Kind, l.Container, l.Field, l.Image}, " "))
+	}
+	return out
+}
+
+func TestImageFinder_AllImageLocations(t *testing.T) {
+	b, err := converter.FromYAMLString(imageReportBundle).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := NewImagePaths(ImagePathRule{Kind: "Prometheus", Paths: []string{"spec.image", "spec.sidecars.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	locs := NewImageFinder(b.Components, WithImagePaths(paths)).AllImageLocations()
+
+	exp := []string{
+		"etcd Pod etcd spec.containers[0].image gcr.io/foo/etcd:3.1",
+		"etcd Pod backup spec.containers[1].image gcr.io/foo/backup:1.0",
+		"etcd Pod init spec.initContainers[0].image gcr.io/foo/init:1.0",
+		"app Deployment web spec.template.spec.containers[0].image gcr.io/foo/web:2.0",
+		"app NodeConfig  osImage.url gs://foo/os-image",
+		`app Prometheus  spec.image gcr.io/foo/prometheus:2.0`,
+		`app Prometheus  spec.sidecars["config.reloader"] gcr.io/foo/reloader:1.0`,
+	}
+	if got := imageLocationStrings(locs); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got image locations\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+
+	byComp := GroupImagesByComponent(locs)
+	if len(byComp) != 2 || byComp[0].Component.ComponentName != "app" || len(byComp[0].Images) != 4 ||
+		byComp[1].Component.ComponentName != "etcd" || len(byComp[1].Images) != 3 || byComp[0].Object != nil {
+		t.Errorf("got unexpected groups by component %+v", byComp)
+	}
+
+	var objs []string
+	for _, g := range GroupImagesByObject(locs) {
+		objs = append(objs, g.Component.ComponentName+" "+g.Object.Kind+" "+g.Object.Name+" "+strings.Join(imageLocationStrings(g.Images)[:1], ""))
+	}
+	expObjs := []string{
+		"app Deployment web app Deployment web spec.template.spec.containers[0].image gcr.io/foo/web:2.0",
+		"app NodeConfig node app NodeConfig  osImage.url gs://foo/os-image",
+		"app Prometheus prom app Prometheus  spec.image gcr.io/foo/prometheus:2.0",
+		"etcd Pod etcd-server etcd Pod etcd spec.containers[0].image gcr.io/foo/etcd:3.1",
+	}
+	if !reflect.DeepEqual(objs, expObjs) {
+		t.Errorf("got groups by object\n%s\nexpected\n%s", strings.Join(objs, "\n"), strings.Join(expObjs, "\n"))
+	}
+}
+
+func TestGroupImagesByComponent_VersionOrder(t *testing.T) {
+	var locs []*ImageLocation
+	for _, v := range []string{"1.10.0", "1.9.0", "dev", "1.9.0-rc.1", "1.0.0"} {
+		l := &ImageLocation{Image: "gcr.io/foo/app:" + v}
+		l.Key.Component = bundle.ComponentReference{ComponentName: "app", Version: v}
+		locs = append(locs, l)
+	}
+
+	var got []string
+	for _, g := range GroupImagesByComponent(locs) {
+		got = append(got, g.Component.Version)
+	}
+	// "dev" isn't a semantic version, so it's compared with the others as a
+	// string, and sorts last.
+	exp := []string{"1.0.0", "1.9.0-rc.1", "1.9.0", "1.10.0", "dev"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got versions %v, expected %v", got, exp)
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	b, err := converter.FromYAMLString(imageReportBundle).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLocs := NewImageFinder(b.Components).AllImageLocations()
+
+	newBundle := strings.Replace(imageReportBundle, "etcd:3.1", "etcd:3.2", 1)
+	newBundle = strings.Replace(newBundle, "gcr.io/foo/backup:1.0", "gcr.io/foo/init:1.0", 1)
+	b, err = converter.FromYAMLString(newBundle).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocs := NewImageFinder(b.Components).AllImageLocations()
+
+	d := DiffImages(oldLocs, newLocs)
+	// The init image is used twice, but isn't added.
+	expAdded := []string{"etcd Pod etcd spec.containers[0].image gcr.io/foo/etcd:3.2"}
+	if got := imageLocationStrings(d.Added); !reflect.DeepEqual(got, expAdded) {
+		t.Errorf("got added images %v, expected %v", got, expAdded)
+	}
+	expRemoved := []string{
+		"etcd Pod etcd spec.containers[0].image gcr.io/foo/etcd:3.1",
+		"etcd Pod backup spec.containers[1].image gcr.io/foo/backup:1.0",
+	}
+	if got := imageLocationStrings(d.Removed); !reflect.DeepEqual(got, expRemoved) {
+		t.Errorf("got removed images %v, expected %v", got, expRemoved)
+	}
+}
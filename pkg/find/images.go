@@ -17,6 +17,7 @@ package find
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -28,11 +29,31 @@ import (
 // ImageFinder finds container and OS Images in components.
 type ImageFinder struct {
 	components []*bundle.Component
+
+	// paths are additional image paths for custom resources.
+	paths *ImagePaths
+}
+
+// ImageFinderConfig is a config option that can be passed to NewImageFinder.
+type ImageFinderConfig func(*ImageFinder)
+
+// WithImagePaths modifies NewImageFinder so that the returned ImageFinder
+// also finds the images at the specified paths. The built-in rules, which find
+// the 'image' fields of containers and the OS image URLs of NodeConfigs, are
+// always used.
+func WithImagePaths(paths *ImagePaths) ImageFinderConfig {
+	return func(b *ImageFinder) {
+		b.paths = paths
+	}
 }
 
 // NewImageFinder creates a new ImageFinder.
-func NewImageFinder(c []*bundle.Component) *ImageFinder {
-	return &ImageFinder{c}
+func NewImageFinder(c []*bundle.Component, opts ...ImageFinderConfig) *ImageFinder {
+	b := &ImageFinder{components: c}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
 // ContainerImage is a helper struct for returning found container images for cluster objects.
@@ -98,16 +119,38 @@ func (b *ImageFinder) FilteredContainerImages(filter Filter, key bundle.Componen
 // container value.
 //
 // If an image value is returned from the function that is not equal to the input
-// value, the value is replaced with the new value.
+// value, the value is replaced with the new value. Images found through the
+// ImageFinder's image paths are walked after those found by the built-in rules.
 //
 // This changes the components object in-place, so if changes are intended, it is
 // recommend that the components be cloned.
 func (b *ImageFinder) WalkContainerImages(st *unstructured.Unstructured, filter Filter, emit func(img string) string) {
+	b.walkImageFields(st, filter, func(_ imageField, img string) string {
+		return emit(img)
+	})
+}
+
+// walkImageFields works the same as WalkContainerImages, except the location
+// of each image field is also provided.
+func (b *ImageFinder) walkImageFields(st *unstructured.Unstructured, filter Filter, emit func(field imageField, img string) string) {
 	// It would be more robust to just be aware of Pods, Deployments, and the
 	// various K8S types that have container images rather then recursing through
 	// everything.  It's possible, for example, that we that we might encouncer
 	// an 'image' field in some options custom resource that's unintended.
-	containerImageRecurser("", "", st.Object, filter, emit)
+	containerImageRecurser("", "", imageField{}, st.Object, filter, emit)
+
+	for _, path := range b.paths.pathsFor(st) {
+		walkImagePath(st.Object, path, "", "", imageField{}, func(fieldName, parentFieldName string, field imageField, img string) string {
+			// Images matching the built-in rules have already been walked.
+			if isBuiltinImageField(fieldName, parentFieldName) {
+				return img
+			}
+			if filter != nil && !filter(fieldName, parentFieldName, img) {
+				return img
+			}
+			return emit(field, img)
+		})
+	}
 }
 
 // WalkAllContainerImages works the same as WalkContainerImages, except all
@@ -143,16 +186,18 @@ type imageMod struct {
 //
 // If an image value is returned from the function and the value is not equal
 // to the input value.
-// 
-func containerImageRecurser(fieldName string, parentFieldName string, elem interface{}, filter Filter, emit func(img string) string) *imageMod {
+//
+// Map fields are traversed in sorted order, so that images are always found
+// in the same order.
+func containerImageRecurser(fieldName string, parentFieldName string, field imageField, elem interface{}, filter Filter, emit func(field imageField, img string) string) *imageMod {
 	switch elem := elem.(type) {
 	case map[string]interface{}:
 		if elem == nil {
 			return nil
 		}
 		var changes []*imageMod
-		for key, val := range elem {
-			if o := containerImageRecurser(key, fieldName, val, filter, emit); o != nil {
+		for _, key := range sortedKeys(elem) {
+			if o := containerImageRecurser(key, fieldName, field.child(elem, fieldName, key), elem[key], filter, emit); o != nil {
 				changes = append(changes, o)
 			}
 		}
@@ -162,13 +207,9 @@ func containerImageRecurser(fieldName string, parentFieldName string, elem inter
 		}
 		return nil
 	case string:
-		// It looks like it's frequently true that the parent name for the
-		// container object is 'container', 'containers' or
-		// 'somethingContainer[s]'.
-		if fieldName == "image" && (strings.Contains(parentFieldName, "container") || strings.Contains(parentFieldName, "Container")) ||
-				fieldName == "url" && parentFieldName == "osImage" { // hack to make finding images work with NodeConfigs
+		if isBuiltinImageField(fieldName, parentFieldName) {
 			if filter == nil || filter(fieldName, parentFieldName, elem) {
-				ret := emit(elem)
+				ret := emit(field, elem)
 				if ret != elem {
 					return &imageMod{fieldName, ret}
 				}
@@ -179,9 +220,9 @@ func containerImageRecurser(fieldName string, parentFieldName string, elem inter
 		if elem == nil {
 			return nil
 		}
-		for _, val := range elem {
+		for i, val := range elem {
 			// Ignore any result here. image-fields should be singletons in maps.
-			containerImageRecurser(fieldName, parentFieldName, val, filter, emit)
+			containerImageRecurser(fieldName, parentFieldName, field.index(i), val, filter, emit)
 		}
 		return nil
 	case int64, bool, float64, nil, json.Number:
@@ -191,6 +232,64 @@ func containerImageRecurser(fieldName string, parentFieldName string, elem inter
 	}
 }
 
+// isBuiltinImageField returns whether a field contains an image according to
+// the built-in rules.
+func isBuiltinImageField(fieldName, parentFieldName string) bool {
+	return fieldName == "image" && isContainerField(parentFieldName) ||
+		fieldName == "url" && parentFieldName == "osImage" // hack to make finding images work with NodeConfigs
+}
+
+// isContainerField returns whether a field contains containers.
+func isContainerField(fieldName string) bool {
+	// It looks like it's frequently true that the parent name for the
+	// container object is 'container', 'containers' or
+	// 'somethingContainer[s]'.
+	return strings.Contains(fieldName, "container") || strings.Contains(fieldName, "Container")
+}
+
+// imageField describes the location of an image field in an object.
+type imageField struct {
+	// path is the path of the field, such as 'spec.containers[0].image'.
+	path string
+
+	// container is the name of the container with the field, if any.
+	container string
+}
+
+// child returns the location of a field of a map. The map is a container if
+// it's in a field containing containers, in which case its name is the
+// container name.
+func (f imageField) child(m map[string]interface{}, mapFieldName, key string) imageField {
+	c := imageField{path: key}
+	if strings.ContainsAny(key, ".[]'") {
+		c.path = fmt.Sprintf("[%q]", key)
+		if f.path != "" {
+			c.path = f.path + c.path
+		}
+	} else if f.path != "" {
+		c.path = f.path + "." + key
+	}
+	if isContainerField(mapFieldName) {
+		c.container, _ = m["name"].(string)
+	}
+	return c
+}
+
+// index returns the location of an element of a list.
+func (f imageField) index(i int) imageField {
+	return imageField{path: fmt.Sprintf("%s[%d]", f.path, i), container: f.container}
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // WalkAllImages walks all node and container images. Only one of
 // nodeConfigName or key will be filled out, based on whether the image is from
 // a node config or from a cluster object.
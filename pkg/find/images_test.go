@@ -68,6 +68,7 @@ func TestImageFinder_Found(t *testing.T) {
 		Object: core.ObjectRef{
 			APIVersion: "v1",
 			Kind:       "Pod",
+			Namespace:  "kube-system",
 			Name:       "kube-scheduler",
 		},
 	}
@@ -0,0 +1,541 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+)
+
+// clusterScopedKinds are the referenced kinds that aren't namespaced.
+var clusterScopedKinds = map[string]bool{
+	"ClusterRole":   true,
+	"PriorityClass": true,
+}
+
+// builtinClusterRoles are the default ClusterRoles that aren't prefixed with
+// `system:`.
+var builtinClusterRoles = map[string]bool{
+	"cluster-admin": true,
+	"admin":         true,
+	"edit":          true,
+	"view":          true,
+}
+
+// isBuiltin returns whether an object is created by Kubernetes itself, and so
+// isn't expected to be in the components.
+func isBuiltin(kind, name string) bool {
+	switch kind {
+	case "ServiceAccount":
+		return name == "default"
+	case "PriorityClass":
+		return strings.HasPrefix(name, "system-")
+	case "ClusterRole":
+		return strings.HasPrefix(name, "system:") || builtinClusterRoles[name]
+	}
+	return false
+}
+
+// podSpecPaths are the paths to the pod specs of the workload kinds.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// RefNode identifies an object in a component.
+type RefNode struct {
+	// Component is the name of the component containing the object.
+	Component string `json:"component"`
+
+	// Kind of the object.
+	Kind string `json:"kind"`
+
+	// Namespace of the object, if any.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
+}
+
+// String returns a human-readable form of the node, such as
+// `component/Kind/namespace/name`.
+func (n RefNode) String() string {
+	if n.Namespace == "" {
+		return fmt.Sprintf("%s/%s/%s", n.Component, n.Kind, n.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", n.Component, n.Kind, n.Namespace, n.Name)
+}
+
+// newRefNode creates a RefNode for an object in a component.
+func newRefNode(comp *bundle.Component, obj *unstructured.Unstructured) RefNode {
+	return RefNode{
+		Component: comp.Spec.ComponentName,
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+// ObjectReference is a reference from an object to other objects, either by
+// name or, for Service selectors, by labels.
+type ObjectReference struct {
+	// From is the object containing the reference.
+	From RefNode `json:"from"`
+
+	// Field is the path of the field containing the reference, such as
+	// `spec.template.spec.serviceAccountName`.
+	Field string `json:"field"`
+
+	// Kind of the referenced objects. Selectors reference Pods, which
+	// resolve to the objects with matching pod templates.
+	Kind string `json:"kind"`
+
+	// Namespace of the referenced objects. It's empty for cluster-scoped
+	// kinds.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the referenced object, if it's referenced by name.
+	Name string `json:"name,omitempty"`
+
+	// Selector selects the referenced objects by label, if they're referenced
+	// by selector.
+	Selector map[string]string `json:"selector,omitempty"`
+
+	// Optional is true if the referenced object is allowed to be missing.
+	Optional bool `json:"optional,omitempty"`
+
+	// Builtin is true if the referenced object is created by Kubernetes
+	// itself, such as the default ServiceAccount, the system-* PriorityClasses
+	// and the default ClusterRoles, and so isn't expected to be in the
+	// components.
+	Builtin bool `json:"builtin,omitempty"`
+
+	// Targets are the objects that the reference resolves to. If there are
+	// none, the reference is dangling.
+	Targets []RefNode `json:"targets,omitempty"`
+}
+
+// target returns a human-readable form of the referenced objects.
+func (r *ObjectReference) target() string {
+	var s string
+	if r.Namespace != "" {
+		s = r.Namespace + "/"
+	}
+	if r.Selector != nil {
+		var sel []string
+		for k, v := range r.Selector {
+			sel = append(sel, k+"="+v)
+		}
+		sort.Strings(sel)
+		return fmt.Sprintf("%s %s{%s}", r.Kind, s, strings.Join(sel, ","))
+	}
+	return fmt.Sprintf("%s %s%s", r.Kind, s, r.Name)
+}
+
+// RefFinder finds the references between the objects of components.
+// References are found in:
+//
+//   - The pod specs of Pods and workloads: volumes, projected volumes, envFrom,
+//     env valueFrom, imagePullSecrets, serviceAccountName and
+//     priorityClassName.
+//   - The roleRef and ServiceAccount subjects of RoleBindings and
+//     ClusterRoleBindings.
+//   - The selectors of Services, which reference pods.
+//
+// References to objects in a namespace are resolved to objects in the same
+// namespace, as written in the objects. References to objects created by
+// Kubernetes itself are marked as Builtin.
+type RefFinder struct {
+	components []*bundle.Component
+}
+
+// NewRefFinder creates a new RefFinder.
+func NewRefFinder(c []*bundle.Component) *RefFinder {
+	return &RefFinder{c}
+}
+
+// RefGraph is the graph of references between the objects of components.
+type RefGraph struct {
+	// Nodes are the objects of the components, in order.
+	Nodes []RefNode `json:"nodes"`
+
+	// References are the references between the objects, in the order of the
+	// objects containing them.
+	References []*ObjectReference `json:"references"`
+}
+
+// ObjectReferences returns the references in an object, without resolving
+// them.
+func (f *RefFinder) ObjectReferences(comp *bundle.Component, obj *unstructured.Unstructured) []*ObjectReference {
+	e := &refExtractor{from: newRefNode(comp, obj), ns: obj.GetNamespace()}
+	kind := obj.GetKind()
+	if path, ok := podSpecPaths[kind]; ok {
+		if spec, ok := nestedMap(obj.Object, path...); ok {
+			e.podSpec(spec, strings.Join(path, "."))
+		}
+	}
+	switch kind {
+	case "RoleBinding", "ClusterRoleBinding":
+		e.binding(obj.Object, kind == "ClusterRoleBinding")
+	case "Service":
+		e.service(obj.Object)
+	}
+	return e.refs
+}
+
+// Graph finds and resolves all the references between the objects of the
+// components.
+func (f *RefFinder) Graph() *RefGraph {
+	g := &RefGraph{}
+	byName := make(map[string][]RefNode)
+	type podTemplate struct {
+		node   RefNode
+		labels map[string]string
+	}
+	var pods []podTemplate
+	for _, comp := range f.components {
+		for _, obj := range comp.Spec.Objects {
+			node := newRefNode(comp, obj)
+			g.Nodes = append(g.Nodes, node)
+			ns := node.Namespace
+			if clusterScopedKinds[node.Kind] {
+				ns = ""
+			}
+			key := refKey(node.Kind, ns, node.Name)
+			byName[key] = append(byName[key], node)
+			if labels, ok := podTemplateLabels(obj); ok {
+				pods = append(pods, podTemplate{node, labels})
+			}
+		}
+	}
+
+	for _, comp := range f.components {
+		for _, obj := range comp.Spec.Objects {
+			for _, ref := range f.ObjectReferences(comp, obj) {
+				if ref.Selector == nil {
+					ref.Targets = byName[refKey(ref.Kind, ref.Namespace, ref.Name)]
+				} else {
+					for _, p := range pods {
+						if p.node.Namespace == ref.Namespace && matchesSelector(p.labels, ref.Selector) {
+							ref.Targets = append(ref.Targets, p.node)
+						}
+					}
+				}
+				g.References = append(g.References, ref)
+			}
+		}
+	}
+	return g
+}
+
+// refKey returns the key of an object for resolving references by name.
+func refKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// Dangling returns the references that don't resolve to any object. Optional
+// and Builtin references are not included.
+func (g *RefGraph) Dangling() []*ObjectReference {
+	var out []*ObjectReference
+	for _, r := range g.References {
+		if len(r.Targets) == 0 && !r.Optional && !r.Builtin {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// DanglingGraph returns the subgraph of the dangling references, with only the
+// objects containing them.
+func (g *RefGraph) DanglingGraph() *RefGraph {
+	out := &RefGraph{References: g.Dangling()}
+	seen := make(map[RefNode]bool)
+	for _, r := range out.References {
+		seen[r.From] = true
+	}
+	for _, n := range g.Nodes {
+		if seen[n] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	return out
+}
+
+// DOT returns the graph in the Graphviz DOT language. Objects are grouped by
+// component, and dangling references point to dashed red nodes. Missing
+// Builtin objects are gray.
+func (g *RefGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph refs {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	var comps []string
+	nodesByComp := make(map[string][]RefNode)
+	for _, n := range g.Nodes {
+		if _, ok := nodesByComp[n.Component]; !ok {
+			comps = append(comps, n.Component)
+		}
+		nodesByComp[n.Component] = append(nodesByComp[n.Component], n)
+	}
+	for i, c := range comps {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%q;\n", c)
+		for _, n := range nodesByComp[c] {
+			label := n.Kind + "\n" + n.Name
+			if n.Namespace != "" {
+				label = n.Kind + "\n" + n.Namespace + "/" + n.Name
+			}
+			fmt.Fprintf(&b, "    %q [label=%q];\n", n.String(), label)
+		}
+		b.WriteString("  }\n")
+	}
+
+	dangling := make(map[string]bool)
+	for _, r := range g.References {
+		if len(r.Targets) == 0 {
+			id := "missing: " + r.target()
+			color := "red"
+			if r.Builtin {
+				color = "gray"
+			}
+			if !dangling[id] {
+				style := "dashed"
+				if r.Optional || r.Builtin {
+					style = "dotted"
+				}
+				fmt.Fprintf(&b, "  %q [label=%q, style=%s, color=%s];\n", id, r.target(), style, color)
+				dangling[id] = true
+			}
+			fmt.Fprintf(&b, "  %q -> %q [label=%q, color=%s];\n", r.From.String(), id, r.Field, color)
+			continue
+		}
+		for _, t := range r.Targets {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", r.From.String(), t.String(), r.Field)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// refExtractor collects the references in an object.
+type refExtractor struct {
+	from RefNode
+	ns   string
+	refs []*ObjectReference
+}
+
+// add adds a reference by name, if the name is set.
+func (e *refExtractor) add(field, kind string, name interface{}, optional bool) {
+	n, ok := name.(string)
+	if !ok || n == "" {
+		return
+	}
+	ns := e.ns
+	if clusterScopedKinds[kind] {
+		ns = ""
+	}
+	e.refs = append(e.refs, &ObjectReference{
+		From:      e.from,
+		Field:     field,
+		Kind:      kind,
+		Namespace: ns,
+		Name:      n,
+		Optional:  optional,
+		Builtin:   isBuiltin(kind, n),
+	})
+}
+
+// podSpec collects the references in a pod spec.
+func (e *refExtractor) podSpec(spec map[string]interface{}, path string) {
+	e.add(path+".serviceAccountName", "ServiceAccount", spec["serviceAccountName"], false)
+	if spec["serviceAccountName"] == nil {
+		// serviceAccount is the deprecated form of serviceAccountName.
+		e.add(path+".serviceAccount", "ServiceAccount", spec["serviceAccount"], false)
+	}
+	e.add(path+".priorityClassName", "PriorityClass", spec["priorityClassName"], false)
+
+	for i, s := range listOfMaps(spec["imagePullSecrets"]) {
+		e.add(fmt.Sprintf("%s.imagePullSecrets[%d].name", path, i), "Secret", s["name"], false)
+	}
+
+	for i, v := range listOfMaps(spec["volumes"]) {
+		vpath := fmt.Sprintf("%s.volumes[%d]", path, i)
+		if cm, ok := v["configMap"].(map[string]interface{}); ok {
+			e.add(vpath+".configMap.name", "ConfigMap", cm["name"], isOptional(cm))
+		}
+		if s, ok := v["secret"].(map[string]interface{}); ok {
+			e.add(vpath+".secret.secretName", "Secret", s["secretName"], isOptional(s))
+		}
+		if pvc, ok := v["persistentVolumeClaim"].(map[string]interface{}); ok {
+			e.add(vpath+".persistentVolumeClaim.claimName", "PersistentVolumeClaim", pvc["claimName"], false)
+		}
+		if p, ok := v["projected"].(map[string]interface{}); ok {
+			for j, src := range listOfMaps(p["sources"]) {
+				spath := fmt.Sprintf("%s.projected.sources[%d]", vpath, j)
+				if cm, ok := src["configMap"].(map[string]interface{}); ok {
+					e.add(spath+".configMap.name", "ConfigMap", cm["name"], isOptional(cm))
+				}
+				if s, ok := src["secret"].(map[string]interface{}); ok {
+					e.add(spath+".secret.name", "Secret", s["name"], isOptional(s))
+				}
+			}
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		for i, c := range listOfMaps(spec[field]) {
+			cpath := fmt.Sprintf("%s.%s[%d]", path, field, i)
+			for j, ef := range listOfMaps(c["envFrom"]) {
+				epath := fmt.Sprintf("%s.envFrom[%d]", cpath, j)
+				if cm, ok := ef["configMapRef"].(map[string]interface{}); ok {
+					e.add(epath+".configMapRef.name", "ConfigMap", cm["name"], isOptional(cm))
+				}
+				if s, ok := ef["secretRef"].(map[string]interface{}); ok {
+					e.add(epath+".secretRef.name", "Secret", s["name"], isOptional(s))
+				}
+			}
+			for j, env := range listOfMaps(c["env"]) {
+				vf, ok := env["valueFrom"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				epath := fmt.Sprintf("%s.env[%d].valueFrom", cpath, j)
+				if cm, ok := vf["configMapKeyRef"].(map[string]interface{}); ok {
+					e.add(epath+".configMapKeyRef.name", "ConfigMap", cm["name"], isOptional(cm))
+				}
+				if s, ok := vf["secretKeyRef"].(map[string]interface{}); ok {
+					e.add(epath+".secretKeyRef.name", "Secret", s["name"], isOptional(s))
+				}
+			}
+		}
+	}
+}
+
+// binding collects the references in a RoleBinding or ClusterRoleBinding.
+func (e *refExtractor) binding(obj map[string]interface{}, cluster bool) {
+	if rr, ok := obj["roleRef"].(map[string]interface{}); ok {
+		kind, _ := rr["kind"].(string)
+		if kind == "Role" && cluster {
+			// ClusterRoleBindings can only refer to ClusterRoles.
+			kind = ""
+		}
+		if kind == "Role" || kind == "ClusterRole" {
+			e.add("roleRef.name", kind, rr["name"], false)
+		}
+	}
+	for i, s := range listOfMaps(obj["subjects"]) {
+		if s["kind"] != "ServiceAccount" {
+			// Users and groups aren't objects.
+			continue
+		}
+		ns, _ := s["namespace"].(string)
+		if ns == "" {
+			ns = e.ns
+		}
+		n, _ := s["name"].(string)
+		if n == "" {
+			continue
+		}
+		e.refs = append(e.refs, &ObjectReference{
+			From:      e.from,
+			Field:     fmt.Sprintf("subjects[%d].name", i),
+			Kind:      "ServiceAccount",
+			Namespace: ns,
+			Name:      n,
+			Builtin:   isBuiltin("ServiceAccount", n),
+		})
+	}
+}
+
+// service collects the references in a Service.
+func (e *refExtractor) service(obj map[string]interface{}) {
+	selector, _, err := unstructured.NestedStringMap(obj, "spec", "selector")
+	if err != nil || len(selector) == 0 {
+		return
+	}
+	e.refs = append(e.refs, &ObjectReference{
+		From:      e.from,
+		Field:     "spec.selector",
+		Kind:      "Pod",
+		Namespace: e.ns,
+		Selector:  selector,
+	})
+}
+
+// podTemplateLabels returns the labels of the pods of a Pod or workload.
+func podTemplateLabels(obj *unstructured.Unstructured) (map[string]string, bool) {
+	path, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil, false
+	}
+	if obj.GetKind() == "Pod" {
+		return obj.GetLabels(), true
+	}
+	// The pod template's metadata is next to its spec.
+	labelsPath := append([]string{}, path[:len(path)-1]...)
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, append(labelsPath, "metadata", "labels")...)
+	return labels, true
+}
+
+// matchesSelector returns whether labels match all the selector's labels.
+func matchesSelector(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// nestedMap returns the map at a path of fields.
+func nestedMap(obj map[string]interface{}, path ...string) (map[string]interface{}, bool) {
+	m := obj
+	for _, p := range path {
+		var ok bool
+		if m, ok = m[p].(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// listOfMaps returns the maps in a list. Values that aren't maps are returned
+// as nil maps, so that the indexes are preserved.
+func listOfMaps(val interface{}) []map[string]interface{} {
+	l, _ := val.([]interface{})
+	out := make([]map[string]interface{}, 0, len(l))
+	for _, v := range l {
+		m, _ := v.(map[string]interface{})
+		out = append(out, m)
+	}
+	return out
+}
+
+// isOptional returns whether a reference has `optional: true`.
+func isOptional(ref map[string]interface{}) bool {
+	o, _ := ref["optional"].(bool)
+	return o
+}
@@ -0,0 +1,269 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package find
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+)
+
+const refsBundle = `
+kind: Bundle
+components:
+- spec:
+    componentName: app
+    objects:
+    - apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: web
+        namespace: app
+      spec:
+        template:
+          metadata:
+            labels: {app: web, tier: frontend}
+          spec:
+            serviceAccountName: app-sa
+            priorityClassName: high
+            imagePullSecrets:
+            - name: pull-secret
+            volumes:
+            - name: config
+              configMap: {name: cfg}
+            - name: certs
+              secret: {secretName: certs}
+            - name: data
+              persistentVolumeClaim: {claimName: data}
+            containers:
+            - name: web
+              envFrom:
+              - configMapRef: {name: extra, optional: true}
+              env:
+              - name: PASSWORD
+                valueFrom:
+                  secretKeyRef: {name: pull-secret, key: password}
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cfg
+        namespace: app
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: certs
+        namespace: other
+    - apiVersion: v1
+      kind: Service
+      metadata:
+        name: web
+        namespace: app
+      spec:
+        selector: {app: web}
+    - apiVersion: v1
+      kind: Service
+      metadata:
+        name: orphan
+        namespace: app
+      spec:
+        selector: {app: gone}
+- spec:
+    componentName: rbac
+    objects:
+    - apiVersion: v1
+      kind: ServiceAccount
+      metadata:
+        name: app-sa
+        namespace: app
+    - apiVersion: v1
+      kind: Secret
+      metadata:
+        name: pull-secret
+        namespace: app
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: RoleBinding
+      metadata:
+        name: reader
+        namespace: app
+      roleRef: {apiGroup: rbac.authorization.k8s.io, kind: Role, name: reader}
+      subjects:
+      - {kind: ServiceAccount, name: app-sa}
+      - {kind: User, name: alice}
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: ClusterRoleBinding
+      metadata:
+        name: view
+      roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: view}
+      subjects:
+      - {kind: ServiceAccount, name: app-sa, namespace: app}
+      - {kind: ServiceAccount, name: robot, namespace: ci}
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: ClusterRole
+      metadata:
+        name: view
+`
+
+func TestRefFinder_Graph(t *testing.T) {
+	b, err := converter.FromYAMLString(refsBundle).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewRefFinder(b.Components).Graph()
+
+	if len(g.Nodes) != 10 {
+		t.Errorf("got %d nodes, expected 10", len(g.Nodes))
+	}
+
+	var got []string
+	for _, r := range g.References {
+		var targets []string
+		for _, t := range r.Targets {
+			targets = append(targets, t.String())
+		}
+		got = append(got, r.From.String()+" "+r.Field+" -> "+r.target()+" "+strings.Join(targets, ","))
+	}
+	exp := []string{
+		"app/Deployment/app/web spec.template.spec.serviceAccountName -> ServiceAccount app/app-sa rbac/ServiceAccount/app/app-sa",
+		"app/Deployment/app/web spec.template.spec.priorityClassName -> PriorityClass high ",
+		"app/Deployment/app/web spec.template.spec.imagePullSecrets[0].name -> Secret app/pull-secret rbac/Secret/app/pull-secret",
+		"app/Deployment/app/web spec.template.spec.volumes[0].configMap.name -> ConfigMap app/cfg app/ConfigMap/app/cfg",
+		"app/Deployment/app/web spec.template.spec.volumes[1].secret.secretName -> Secret app/certs ",
+		"app/Deployment/app/web spec.template.spec.volumes[2].persistentVolumeClaim.claimName -> PersistentVolumeClaim app/data ",
+		"app/Deployment/app/web spec.template.spec.containers[0].envFrom[0].configMapRef.name -> ConfigMap app/extra ",
+		"app/Deployment/app/web spec.template.spec.containers[0].env[0].valueFrom.secretKeyRef.name -> Secret app/pull-secret rbac/Secret/app/pull-secret",
+		"app/Service/app/web spec.selector -> Pod app/{app=web} app/Deployment/app/web",
+		"app/Service/app/orphan spec.selector -> Pod app/{app=gone} ",
+		"rbac/RoleBinding/app/reader roleRef.name -> Role app/reader ",
+		"rbac/RoleBinding/app/reader subjects[0].name -> ServiceAccount app/app-sa rbac/ServiceAccount/app/app-sa",
+		"rbac/ClusterRoleBinding/view roleRef.name -> ClusterRole view rbac/ClusterRole/view",
+		"rbac/ClusterRoleBinding/view subjects[0].name -> ServiceAccount app/app-sa rbac/ServiceAccount/app/app-sa",
+		"rbac/ClusterRoleBinding/view subjects[1].name -> ServiceAccount ci/robot ",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got references\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+
+	var dangling []string
+	for _, r := range g.Dangling() {
+		dangling = append(dangling, r.target())
+	}
+	// The optional ConfigMap reference isn't dangling.
+	expDangling := []string{
+		"PriorityClass high",
+		"Secret app/certs",
+		"PersistentVolumeClaim app/data",
+		"Pod app/{app=gone}",
+		"Role app/reader",
+		"ServiceAccount ci/robot",
+	}
+	if !reflect.DeepEqual(dangling, expDangling) {
+		t.Errorf("got dangling references %v, expected %v", dangling, expDangling)
+	}
+
+	dg := g.DanglingGraph()
+	if len(dg.References) != len(expDangling) || len(dg.Nodes) != 4 {
+		t.Errorf("got dangling graph with %d references and %d nodes, expected %d references and 4 nodes", len(dg.References), len(dg.Nodes), len(expDangling))
+	}
+
+	dot := g.DOT()
+	for _, s := range []string{
+		"digraph refs {",
+		`label="rbac";`,
+		`"app/Deployment/app/web" -> "app/ConfigMap/app/cfg" [label="spec.template.spec.volumes[0].configMap.name"];`,
+		`"missing: Role app/reader" [label="Role app/reader", style=dashed, color=red];`,
+		`"missing: ConfigMap app/extra" [label="ConfigMap app/extra", style=dotted, color=red];`,
+		`"rbac/RoleBinding/app/reader" -> "missing: Role app/reader" [label="roleRef.name", color=red];`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("expected DOT output to contain %q, but got\n%s", s, dot)
+		}
+	}
+}
+
+func TestRefFinder_Builtin(t *testing.T) {
+	b, err := converter.FromYAMLString(`
+kind: Bundle
+components:
+- spec:
+    componentName: app
+    objects:
+    - apiVersion: apps/v1
+      kind: DaemonSet
+      metadata:
+        name: agent
+        namespace: kube-system
+      spec:
+        template:
+          spec:
+            serviceAccountName: default
+            priorityClassName: system-node-critical
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: ClusterRoleBinding
+      metadata:
+        name: agent
+      roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: system:auth-delegator}
+      subjects:
+      - {kind: ServiceAccount, name: default, namespace: kube-system}
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: RoleBinding
+      metadata:
+        name: agent
+        namespace: kube-system
+      roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: view}
+    - apiVersion: rbac.authorization.k8s.io/v1
+      kind: RoleBinding
+      metadata:
+        name: custom
+        namespace: kube-system
+      roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: system-custom}
+`).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewRefFinder(b.Components).Graph()
+
+	var builtin []string
+	for _, r := range g.References {
+		if r.Builtin {
+			builtin = append(builtin, r.target())
+		}
+	}
+	expBuiltin := []string{
+		"ServiceAccount kube-system/default",
+		"PriorityClass system-node-critical",
+		"ClusterRole system:auth-delegator",
+		"ServiceAccount kube-system/default",
+		"ClusterRole view",
+	}
+	if !reflect.DeepEqual(builtin, expBuiltin) {
+		t.Errorf("got builtin references %v, expected %v", builtin, expBuiltin)
+	}
+
+	var dangling []string
+	for _, r := range g.Dangling() {
+		dangling = append(dangling, r.target())
+	}
+	// The system- prefix only makes PriorityClasses builtin, not ClusterRoles.
+	expDangling := []string{"ClusterRole system-custom"}
+	if !reflect.DeepEqual(dangling, expDangling) {
+		t.Errorf("got dangling references %v, expected %v", dangling, expDangling)
+	}
+
+	if s := `"missing: ClusterRole view" [label="ClusterRole view", style=dotted, color=gray];`; !strings.Contains(g.DOT(), s) {
+		t.Errorf("expected DOT output to contain %q, but got\n%s", s, g.DOT())
+	}
+}
@@ -0,0 +1,520 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ociLayoutFile marks a directory as an OCI image layout.
+	ociLayoutFile = "oci-layout"
+
+	// ociIndexFile is the image index of an OCI image layout.
+	ociIndexFile = "index.json"
+
+	// imageNameAnnotation records the original reference of an image in the
+	// index of an OCI image layout. It's the annotation used by containerd.
+	imageNameAnnotation = "io.containerd.image.name"
+
+	// refNameAnnotation records the tag of an image in the index of an OCI
+	// image layout.
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+
+	mediaTypeOCIIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList        = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest    = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOctetStream       = "application/octet-stream"
+	ociImageLayoutVersion      = "1.0.0"
+	ociImageIndexSchemaVersion = 2
+)
+
+// descriptor describes content in an OCI image layout or registry.
+type descriptor struct {
+	MediaType   string            `json:"mediaType,omitempty"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	URLs        []string          `json:"urls,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// manifest contains the fields of image manifests and indexes that refer to
+// other content.
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        *descriptor  `json:"config,omitempty"`
+	Layers        []descriptor `json:"layers,omitempty"`
+	Manifests     []descriptor `json:"manifests,omitempty"`
+}
+
+// isIndex returns whether a media type is that of an image index or manifest
+// list.
+func isIndex(mediaType string) bool {
+	return mediaType == mediaTypeOCIIndex || mediaType == mediaTypeDockerList
+}
+
+// isManifest returns whether a media type is that of a single image manifest.
+func isManifest(mediaType string) bool {
+	return mediaType == mediaTypeOCIManifest || mediaType == mediaTypeDockerManifest
+}
+
+// OCILayout is a directory containing images in the OCI image layout format.
+// See https://github.com/opencontainers/image-spec/blob/main/image-layout.md.
+//
+// Unlike the other files read and written by the commands, the layout is
+// accessed directly on the local filesystem rather than through a
+// files.FileReaderWriter. Image layers can be gigabytes in size and must be
+// streamed to and from the registry, and blobs are written to a temporary file
+// and renamed once their digest is verified, so that an interrupted save never
+// leaves a corrupt blob in the layout. FileReaderWriter only reads and writes
+// whole files in memory, so it supports neither.
+type OCILayout struct {
+	// Dir is the directory of the layout.
+	Dir string
+}
+
+// blobPath returns the path of a blob in the layout.
+func (l *OCILayout) blobPath(dig string) (string, error) {
+	parts := strings.SplitN(dig, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" || len(parts[1]) != sha256.Size*2 {
+		return "", fmt.Errorf("unsupported digest %q: only sha256 digests are supported", dig)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", fmt.Errorf("invalid digest %q: %v", dig, err)
+	}
+	return filepath.Join(l.Dir, "blobs", parts[0], parts[1]), nil
+}
+
+// hasBlob returns whether a blob is in the layout.
+func (l *OCILayout) hasBlob(dig string) (bool, error) {
+	path, err := l.blobPath(dig)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// writeBlob writes a blob to the layout, and checks that its contents match
+// the digest.
+func (l *OCILayout) writeBlob(dig string, r io.Reader) error {
+	path, err := l.blobPath(dig)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing blob %q: %v", dig, err)
+	}
+	if got := fmt.Sprintf("sha256:%x", h.Sum(nil)); got != dig {
+		return fmt.Errorf("blob %q has unexpected digest %q", dig, got)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// openBlob opens a blob in the layout.
+func (l *OCILayout) openBlob(dig string) (io.ReadCloser, int64, error) {
+	path, err := l.blobPath(dig)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, fi.Size(), nil
+}
+
+// readBlob reads a blob in the layout.
+func (l *OCILayout) readBlob(dig string) ([]byte, error) {
+	path, err := l.blobPath(dig)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+// readIndex reads the image index of the layout. If the layout doesn't exist
+// yet, an empty index is returned.
+func (l *OCILayout) readIndex() (*manifest, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(l.Dir, ociIndexFile))
+	if os.IsNotExist(err) {
+		return &manifest{SchemaVersion: ociImageIndexSchemaVersion, MediaType: mediaTypeOCIIndex}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	index := &manifest{}
+	if err := json.Unmarshal(contents, index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", ociIndexFile, err)
+	}
+	return index, nil
+}
+
+// writeIndex writes the image index and the layout marker file.
+func (l *OCILayout) writeIndex(index *manifest) error {
+	layout, err := json.Marshal(map[string]string{"imageLayoutVersion": ociImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(l.Dir, ociLayoutFile), layout, 0644); err != nil {
+		return err
+	}
+	contents, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(l.Dir, ociIndexFile), contents, 0644)
+}
+
+// Save copies images from their registries into the layout, including every
+// platform of multi-platform images. Images already in the layout are
+// replaced. The returned LockFile records the manifest digest of every image,
+// keyed by the image reference.
+func (l *OCILayout) Save(ctx context.Context, imgs []string, reg *RegistryResolver) (*LockFile, error) {
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return nil, err
+	}
+	index, err := l.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &LockFile{Digests: make(map[string]string)}
+	saved := make(map[string]descriptor)
+	for _, img := range imgs {
+		if _, ok := saved[img]; ok {
+			continue
+		}
+		ref, err := ParseReference(img)
+		if err != nil {
+			return nil, err
+		}
+		reference := ref.Digest
+		if reference == "" {
+			reference = ref.TagOrDefault()
+		}
+		desc, err := l.pullManifest(ctx, reg, ref, reference)
+		if err != nil {
+			return nil, fmt.Errorf("saving image %q: %v", img, err)
+		}
+		desc.Annotations = map[string]string{imageNameAnnotation: img}
+		if ref.Tag != "" {
+			desc.Annotations[refNameAnnotation] = ref.Tag
+		}
+		saved[img] = desc
+		lock.Digests[img] = desc.Digest
+	}
+
+	var manifests []descriptor
+	for _, desc := range index.Manifests {
+		if _, ok := saved[desc.Annotations[imageNameAnnotation]]; !ok {
+			manifests = append(manifests, desc)
+		}
+	}
+	for _, img := range imgs {
+		if desc, ok := saved[img]; ok {
+			manifests = append(manifests, desc)
+			delete(saved, img)
+		}
+	}
+	index.Manifests = manifests
+	if err := l.writeIndex(index); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// pullManifest copies a manifest and all the content it refers to from a
+// registry into the layout. The reference is either a tag or a digest.
+func (l *OCILayout) pullManifest(ctx context.Context, reg *RegistryResolver, ref *Reference, reference string) (descriptor, error) {
+	resp, err := reg.do(ctx, &registryRequest{
+		method: http.MethodGet,
+		url:    reg.registryURL(ref, "manifests", reference),
+		ref:    ref,
+		header: manifestHeader(),
+	})
+	if err != nil {
+		return descriptor{}, err
+	}
+	defer resp.Body.Close()
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return descriptor{}, fmt.Errorf("reading manifest %q: %v", reference, err)
+	}
+	desc := descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(contents)),
+		Size:      int64(len(contents)),
+	}
+	if strings.Contains(reference, ":") && reference != desc.Digest {
+		return descriptor{}, fmt.Errorf("manifest %q has unexpected digest %q", reference, desc.Digest)
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(contents, m); err != nil {
+		return descriptor{}, fmt.Errorf("parsing manifest %q: %v", reference, err)
+	}
+	if m.MediaType != "" {
+		desc.MediaType = m.MediaType
+	}
+	switch {
+	case isIndex(desc.MediaType):
+		for _, child := range m.Manifests {
+			if _, err := l.pullManifest(ctx, reg, ref, child.Digest); err != nil {
+				return descriptor{}, err
+			}
+		}
+	case isManifest(desc.MediaType):
+		for _, blob := range manifestBlobs(m) {
+			if err := l.pullBlob(ctx, reg, ref, blob); err != nil {
+				return descriptor{}, err
+			}
+		}
+	default:
+		return descriptor{}, fmt.Errorf("manifest %q has unsupported media type %q", reference, desc.MediaType)
+	}
+
+	has, err := l.hasBlob(desc.Digest)
+	if err != nil {
+		return descriptor{}, err
+	}
+	if !has {
+		if err := l.writeBlob(desc.Digest, bytes.NewReader(contents)); err != nil {
+			return descriptor{}, err
+		}
+	}
+	return desc, nil
+}
+
+// manifestBlobs returns the config and layers of an image manifest, excluding
+// non-distributable layers that are fetched from elsewhere.
+func manifestBlobs(m *manifest) []descriptor {
+	var blobs []descriptor
+	if m.Config != nil {
+		blobs = append(blobs, *m.Config)
+	}
+	for _, layer := range m.Layers {
+		if len(layer.URLs) == 0 {
+			blobs = append(blobs, layer)
+		}
+	}
+	return blobs
+}
+
+// pullBlob copies a blob from a registry into the layout, unless it's there
+// already.
+func (l *OCILayout) pullBlob(ctx context.Context, reg *RegistryResolver, ref *Reference, blob descriptor) error {
+	has, err := l.hasBlob(blob.Digest)
+	if err != nil || has {
+		return err
+	}
+	resp, err := reg.do(ctx, &registryRequest{
+		method: http.MethodGet,
+		url:    reg.registryURL(ref, "blobs", blob.Digest),
+		ref:    ref,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return l.writeBlob(blob.Digest, resp.Body)
+}
+
+// Load pushes the images in the layout to a registry. The images keep their
+// repository path and tag, but their registry domain is replaced by target,
+// which may also contain a path prefix, such as `registry.local/mirror`. So
+// with that target, `gcr.io/foo/bar:1.0` is pushed to
+// `registry.local/mirror/foo/bar:1.0`.
+//
+// The returned RewriteRules map the original image references to the pushed
+// references.
+func (l *OCILayout) Load(ctx context.Context, target string, reg *RegistryResolver) (*RewriteRules, error) {
+	target = strings.TrimSuffix(target, "/")
+	if target == "" {
+		return nil, fmt.Errorf("target registry must be specified")
+	}
+	index, err := l.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	if len(index.Manifests) == 0 {
+		return nil, fmt.Errorf("no images found in OCI layout %q", l.Dir)
+	}
+
+	rules := &RewriteRules{}
+	for _, desc := range index.Manifests {
+		img := desc.Annotations[imageNameAnnotation]
+		if img == "" {
+			return nil, fmt.Errorf("manifest %q in OCI layout %q has no %s annotation", desc.Digest, l.Dir, imageNameAnnotation)
+		}
+		orig, err := ParseReference(img)
+		if err != nil {
+			return nil, err
+		}
+		pushed := &Reference{Name: target + "/" + orig.Repository, Tag: orig.Tag, Digest: orig.Digest}
+		ref, err := ParseReference(pushed.String())
+		if err != nil {
+			return nil, err
+		}
+		reference := ref.Tag
+		if reference == "" {
+			reference = desc.Digest
+		}
+		if err := l.pushManifest(ctx, reg, ref, desc, reference); err != nil {
+			return nil, fmt.Errorf("loading image %q: %v", img, err)
+		}
+		rules.Rules = append(rules.Rules, RewriteRule{Exact: img, Replacement: pushed.String()})
+	}
+	return rules, nil
+}
+
+// pushManifest pushes a manifest and all the content it refers to from the
+// layout to a registry. The reference is either a tag or a digest.
+func (l *OCILayout) pushManifest(ctx context.Context, reg *RegistryResolver, ref *Reference, desc descriptor, reference string) error {
+	contents, err := l.readBlob(desc.Digest)
+	if err != nil {
+		return err
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(contents, m); err != nil {
+		return fmt.Errorf("parsing manifest %q: %v", desc.Digest, err)
+	}
+	switch {
+	case isIndex(desc.MediaType):
+		for _, child := range m.Manifests {
+			if err := l.pushManifest(ctx, reg, ref, child, child.Digest); err != nil {
+				return err
+			}
+		}
+	case isManifest(desc.MediaType):
+		for _, blob := range manifestBlobs(m) {
+			if err := l.pushBlob(ctx, reg, ref, blob); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("manifest %q has unsupported media type %q", desc.Digest, desc.MediaType)
+	}
+
+	resp, err := reg.do(ctx, &registryRequest{
+		method: http.MethodPut,
+		url:    reg.registryURL(ref, "manifests", reference),
+		ref:    ref,
+		push:   true,
+		header: http.Header{"Content-Type": []string{desc.MediaType}},
+		body: func() (io.ReadCloser, int64, error) {
+			return ioutil.NopCloser(bytes.NewReader(contents)), int64(len(contents)), nil
+		},
+		okStatus: []int{http.StatusOK, http.StatusCreated},
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// pushBlob pushes a blob from the layout to a registry, unless it's there
+// already.
+func (l *OCILayout) pushBlob(ctx context.Context, reg *RegistryResolver, ref *Reference, blob descriptor) error {
+	resp, err := reg.do(ctx, &registryRequest{
+		method:   http.MethodHead,
+		url:      reg.registryURL(ref, "blobs", blob.Digest),
+		ref:      ref,
+		push:     true,
+		okStatus: []int{http.StatusOK, http.StatusNotFound},
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// Blobs are uploaded in a single request, after starting an upload
+	// session.
+	resp, err = reg.do(ctx, &registryRequest{
+		method:   http.MethodPost,
+		url:      reg.registryURL(ref, "blobs", "uploads/"),
+		ref:      ref,
+		push:     true,
+		okStatus: []int{http.StatusAccepted},
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location %q: %v", resp.Header.Get("Location"), err)
+	}
+	q := loc.Query()
+	q.Set("digest", blob.Digest)
+	loc.RawQuery = q.Encode()
+
+	resp, err = reg.do(ctx, &registryRequest{
+		method: http.MethodPut,
+		url:    loc.String(),
+		ref:    ref,
+		push:   true,
+		header: http.Header{"Content-Type": []string{mediaTypeOctetStream}},
+		body: func() (io.ReadCloser, int64, error) {
+			return l.openBlob(blob.Digest)
+		},
+		okStatus: []int{http.StatusCreated},
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
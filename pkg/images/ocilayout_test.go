@@ -0,0 +1,232 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+// addImage adds an image manifest with a config and a layer to a registry,
+// and returns the manifest.
+func (f *fakeRegistry) addImage(mediaType, name string) string {
+	config := fmt.Sprintf(`{"config": %q}`, name)
+	layer := "layer of " + name
+	f.blobs[digestOf(config)] = config
+	f.blobs[digestOf(layer)] = layer
+	return fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q, `+
+		`"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": %q, "size": %d}, `+
+		`"layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": %q, "size": %d}, `+
+		`{"mediaType": "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip", "digest": "sha256:%064d", "size": 1, "urls": ["https://example.com/layer"]}]}`,
+		mediaType, digestOf(config), len(config), digestOf(layer), len(layer), 0)
+}
+
+func TestOCILayout(t *testing.T) {
+	src := newFakeRegistry(t, "", nil)
+	amd64 := src.addImage(mediaTypeOCIManifest, "app-amd64")
+	arm64 := src.addImage(mediaTypeOCIManifest, "app-arm64")
+	src.manifests["foo/app:amd64"] = amd64
+	src.manifests["foo/app:arm64"] = arm64
+	appIndex := fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q, "manifests": [`+
+		`{"mediaType": %q, "digest": %q, "size": %d, "platform": {"architecture": "amd64", "os": "linux"}}, `+
+		`{"mediaType": %q, "digest": %q, "size": %d, "platform": {"architecture": "arm64", "os": "linux"}}]}`,
+		mediaTypeOCIIndex, mediaTypeOCIManifest, digestOf(amd64), len(amd64), mediaTypeOCIManifest, digestOf(arm64), len(arm64))
+	src.manifests["foo/app:1.0"] = appIndex
+	tool := src.addImage(mediaTypeDockerManifest, "tool")
+	src.manifests["foo/tool:2.0"] = tool
+
+	host := src.host()
+	appImage := host + "/foo/app:1.0"
+	toolImage := host + "/foo/tool@" + digestOf(tool)
+
+	dir, err := ioutil.TempDir("", "oci-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := &OCILayout{Dir: filepath.Join(dir, "layout")}
+
+	// Images are only saved once, even if they're referenced several times.
+	lock, err := layout.Save(context.Background(), []string{appImage, toolImage, appImage}, src.resolver(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expLock := map[string]string{appImage: digestOf(appIndex), toolImage: digestOf(tool)}
+	if !reflect.DeepEqual(lock.Digests, expLock) {
+		t.Errorf("got lock file digests %v, expected %v", lock.Digests, expLock)
+	}
+
+	marker, err := ioutil.ReadFile(filepath.Join(layout.Dir, "oci-layout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(marker) != `{"imageLayoutVersion":"1.0.0"}` {
+		t.Errorf("got oci-layout %s", marker)
+	}
+	index, err := layout.readIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expIndex := []descriptor{
+		{
+			MediaType:   mediaTypeOCIIndex,
+			Digest:      digestOf(appIndex),
+			Size:        int64(len(appIndex)),
+			Annotations: map[string]string{imageNameAnnotation: appImage, refNameAnnotation: "1.0"},
+		},
+		{
+			MediaType:   mediaTypeDockerManifest,
+			Digest:      digestOf(tool),
+			Size:        int64(len(tool)),
+			Annotations: map[string]string{imageNameAnnotation: toolImage},
+		},
+	}
+	if !reflect.DeepEqual(index.Manifests, expIndex) {
+		t.Errorf("got index manifests %+v, expected %+v", index.Manifests, expIndex)
+	}
+	var blobs []string
+	filepath.Walk(filepath.Join(layout.Dir, "blobs"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			blobs = append(blobs, path)
+		}
+		return err
+	})
+	// The blobs are the 4 manifests, and a config and layer for each of the
+	// 3 images. The non-distributable layers aren't saved.
+	if len(blobs) != 10 {
+		t.Errorf("got %d blobs, expected 10: %v", len(blobs), blobs)
+	}
+
+	// Saving again replaces the images with the same reference.
+	if _, err := layout.Save(context.Background(), []string{appImage}, src.resolver(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if index, err = layout.readIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 2 || index.Manifests[1].Annotations[imageNameAnnotation] != appImage {
+		t.Errorf("got index manifests %+v after saving again, expected the app image to be replaced", index.Manifests)
+	}
+
+	dst := newFakeRegistry(t, "bearer", nil)
+	target := dst.host() + "/mirror"
+	creds := &RegistryConfig{Auths: map[string]RegistryAuth{
+		dst.host(): {Auth: base64.StdEncoding.EncodeToString([]byte(fakeUser + ":" + fakePassword))},
+	}}
+	rules, err := layout.Load(context.Background(), target, dst.resolver(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expRules := []RewriteRule{
+		{Exact: toolImage, Replacement: target + "/foo/tool@" + digestOf(tool)},
+		{Exact: appImage, Replacement: target + "/foo/app:1.0"},
+	}
+	if !reflect.DeepEqual(rules.Rules, expRules) {
+		t.Errorf("got rewrite rules %+v, expected %+v", rules.Rules, expRules)
+	}
+
+	expManifests := map[string]string{
+		"mirror/foo/app:1.0":                appIndex,
+		"mirror/foo/app:" + digestOf(amd64): amd64,
+		"mirror/foo/app:" + digestOf(arm64): arm64,
+		"mirror/foo/tool:" + digestOf(tool): tool,
+	}
+	if !reflect.DeepEqual(dst.manifests, expManifests) {
+		t.Errorf("got pushed manifests %v, expected %v", dst.manifests, expManifests)
+	}
+	for dig, blob := range src.blobs {
+		if dst.blobs[dig] != blob {
+			t.Errorf("expected blob %q to be pushed", dig)
+		}
+	}
+
+	// Blobs that are already in the registry aren't pushed again.
+	uploads := dst.uploads
+	if _, err := layout.Load(context.Background(), target, dst.resolver(creds)); err != nil {
+		t.Fatal(err)
+	}
+	if dst.uploads != uploads {
+		t.Errorf("got %d uploads after loading again, expected %d", dst.uploads, uploads)
+	}
+}
+
+func TestOCILayout_InsecureRegistry(t *testing.T) {
+	src := newFakeRegistry(t, "", nil)
+	tool := src.addImage(mediaTypeDockerManifest, "tool")
+	src.manifests["foo/tool:2.0"] = tool
+	toolImage := src.host() + "/foo/tool:2.0"
+
+	dir, err := ioutil.TempDir("", "oci-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := &OCILayout{Dir: filepath.Join(dir, "layout")}
+	if _, err := layout.Save(context.Background(), []string{toolImage}, src.resolver(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newInsecureFakeRegistry(t, nil)
+	reg := dst.resolver(nil)
+	reg.InsecureRegistries = []string{dst.host()}
+	if _, err := layout.Load(context.Background(), dst.host()+"/mirror", reg); err != nil {
+		t.Fatal(err)
+	}
+	if dst.manifests["mirror/foo/tool:2.0"] != tool {
+		t.Errorf("got pushed manifests %v, expected the tool image to be pushed", dst.manifests)
+	}
+}
+
+func TestOCILayout_Errors(t *testing.T) {
+	src := newFakeRegistry(t, "", nil)
+	src.manifests["foo/schema1:1.0"] = `{"schemaVersion": 1, "mediaType": "application/vnd.docker.distribution.manifest.v1+prettyjws"}`
+
+	dir, err := ioutil.TempDir("", "oci-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := &OCILayout{Dir: dir}
+
+	testCases := []struct {
+		desc         string
+		img          string
+		expErrSubstr string
+	}{
+		{desc: "unknown tag", img: "/foo/app:1.0", expErrSubstr: "404"},
+		{desc: "unsupported manifest", img: "/foo/schema1:1.0", expErrSubstr: "unsupported media type"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := layout.Save(context.Background(), []string{src.host() + tc.img}, src.resolver(nil))
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Error(cerr)
+			}
+		})
+	}
+
+	_, err = (&OCILayout{Dir: filepath.Join(dir, "empty")}).Load(context.Background(), src.host(), src.resolver(nil))
+	if cerr := testutil.CheckErrorCases(err, "no images found"); cerr != nil {
+		t.Error(cerr)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"fmt"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
+)
+
+// DigestResolver resolves the tag of a container image to the digest of the
+// image manifest, such as `sha256:abcd...`.
+type DigestResolver interface {
+	Resolve(ctx context.Context, img string) (string, error)
+}
+
+// LockFile records the digests of tagged container images, so that images can
+// be pinned without access to their registries. It is usually read from a
+// YAML or JSON file of the form:
+//
+//	digests:
+//	  gcr.io/foo/bar:1.0: sha256:abcd...
+type LockFile struct {
+	// Digests are the image digests, keyed by tagged image reference.
+	Digests map[string]string `json:"digests,omitempty"`
+}
+
+// Resolve returns the digest recorded for an image.
+func (l *LockFile) Resolve(_ context.Context, img string) (string, error) {
+	dig, ok := l.Digests[img]
+	if !ok {
+		return "", fmt.Errorf("no digest found in lock file")
+	}
+	return dig, nil
+}
+
+var _ DigestResolver = &LockFile{}
+var _ DigestResolver = &RegistryResolver{}
+
+// isContainerImage is a find.Filter that only selects container images, and
+// not NodeConfig OS image URLs.
+func isContainerImage(fieldName, parentFieldName, _ string) bool {
+	return fieldName != "url" || parentFieldName != "osImage"
+}
+
+// ContainerImages returns the container images in the components, without
+// duplicates, in the order they're found. NodeConfig OS image URLs aren't
+// included, since they aren't in registries.
+func ContainerImages(comps []*bundle.Component) []string {
+	var imgs []string
+	seen := make(map[string]bool)
+	for _, ci := range find.NewImageFinder(comps).AllFilteredContainerImages(isContainerImage) {
+		if !seen[ci.Image] {
+			seen[ci.Image] = true
+			imgs = append(imgs, ci.Image)
+		}
+	}
+	return imgs
+}
+
+// PinAll replaces the tags of the container images in the components with the
+// digests returned by the resolver, so that `repo:tag` becomes
+// `repo@sha256:...`. Images that already have a digest are left unchanged.
+// The returned LockFile records the digest of every pinned image.
+//
+// The components are changed in-place, so they should be copied if the
+// originals are needed.
+func PinAll(ctx context.Context, comps []*bundle.Component, resolver DigestResolver) (*LockFile, error) {
+	lock := &LockFile{Digests: make(map[string]string)}
+	var err error
+	find.NewImageFinder(comps).WalkAllContainerImages(isContainerImage, func(key core.ClusterObjectKey, img string) string {
+		if err != nil {
+			return img
+		}
+		ref, perr := ParseReference(img)
+		if perr != nil {
+			err = fmt.Errorf("in %s %q of component %q: %v", key.Object.Kind, key.Object.Name, key.Component.ComponentName, perr)
+			return img
+		}
+		if ref.Digest != "" {
+			return img
+		}
+		dig, ok := lock.Digests[img]
+		if !ok {
+			var rerr error
+			if dig, rerr = resolver.Resolve(ctx, img); rerr != nil {
+				err = fmt.Errorf("resolving image %q in %s %q of component %q: %v", img, key.Object.Kind, key.Object.Name, key.Component.ComponentName, rerr)
+				return img
+			}
+			lock.Digests[img] = dig
+		}
+		return ref.Name + "@" + dig
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
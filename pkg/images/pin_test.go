@@ -0,0 +1,455 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+// fakeRegistry is an in-process registry serving the pull and push endpoints
+// of the OCI distribution API.
+type fakeRegistry struct {
+	// manifests are the manifest contents, keyed by '<repository>:<tag>'.
+	// Manifests can also be fetched by digest.
+	manifests map[string]string
+
+	// blobs are the blob contents, keyed by digest.
+	blobs map[string]string
+
+	// uploads counts the started blob uploads.
+	uploads int
+
+	// If omitDigest is true, the Docker-Content-Digest header isn't returned.
+	omitDigest bool
+
+	// auth is the required authentication: "", "basic" or "bearer".
+	auth string
+
+	// tokenRequests counts the requests to the token server.
+	tokenRequests int
+
+	server *httptest.Server
+}
+
+const (
+	fakeUser     = "user"
+	fakePassword = "secret"
+	fakeToken    = "token123"
+)
+
+func newFakeRegistry(t *testing.T, auth string, manifests map[string]string) *fakeRegistry {
+	if manifests == nil {
+		manifests = make(map[string]string)
+	}
+	f := &fakeRegistry{manifests: manifests, blobs: make(map[string]string), auth: auth}
+	f.server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// newInsecureFakeRegistry creates a fake registry that serves plain HTTP.
+func newInsecureFakeRegistry(t *testing.T, manifests map[string]string) *fakeRegistry {
+	if manifests == nil {
+		manifests = make(map[string]string)
+	}
+	f := &fakeRegistry{manifests: manifests, blobs: make(map[string]string)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// host returns the host:port of the registry.
+func (f *fakeRegistry) host() string {
+	return strings.TrimPrefix(strings.TrimPrefix(f.server.URL, "https://"), "http://")
+}
+
+// resolver returns a RegistryResolver that trusts the registry.
+func (f *fakeRegistry) resolver(config *RegistryConfig) *RegistryResolver {
+	r := NewRegistryResolver(config)
+	r.Client = f.server.Client()
+	return r
+}
+
+func (f *fakeRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte(fakeUser+":"+fakePassword))
+	if req.URL.Path == "/token" {
+		f.tokenRequests++
+		if req.Header.Get("Authorization") != basic || req.URL.Query().Get("service") != "fake" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token": %q}`, fakeToken)
+		return
+	}
+
+	switch f.auth {
+	case "basic":
+		if req.Header.Get("Authorization") != basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="fake"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case "bearer":
+		if req.Header.Get("Authorization") != "Bearer "+fakeToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, f.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		f.serveUpload(w, req, path)
+	case strings.Contains(path, "/blobs/"):
+		blob, ok := f.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet {
+			fmt.Fprint(w, blob)
+		}
+	case strings.Contains(path, "/manifests/"):
+		f.serveManifest(w, req, path)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeRegistry) serveManifest(w http.ResponseWriter, req *http.Request, path string) {
+	parts := strings.SplitN(path, "/manifests/", 2)
+	key := parts[0] + ":" + parts[1]
+	if req.Method == http.MethodPut {
+		body, _ := ioutil.ReadAll(req.Body)
+		if mediaTypeOf(string(body)) != req.Header.Get("Content-Type") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.manifests[key] = string(body)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	manifest, ok := f.manifests[key]
+	if !ok {
+		for k, m := range f.manifests {
+			if strings.HasPrefix(k, parts[0]+":") && digestOf(m) == parts[1] {
+				manifest, ok = m, true
+			}
+		}
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !f.omitDigest {
+		w.Header().Set("Docker-Content-Digest", digestOf(manifest))
+	}
+	if mt := mediaTypeOf(manifest); mt != "" {
+		w.Header().Set("Content-Type", mt)
+	}
+	if req.Method == http.MethodGet {
+		fmt.Fprint(w, manifest)
+	}
+}
+
+func (f *fakeRegistry) serveUpload(w http.ResponseWriter, req *http.Request, path string) {
+	switch req.Method {
+	case http.MethodPost:
+		f.uploads++
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s%d?state=abc", path, f.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(req.Body)
+		dig := req.URL.Query().Get("digest")
+		if req.URL.Query().Get("state") != "abc" || digestOf(string(body)) != dig {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[dig] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// mediaTypeOf returns the media type field of a manifest.
+func mediaTypeOf(manifest string) string {
+	m := struct {
+		MediaType string `json:"mediaType"`
+	}{}
+	json.Unmarshal([]byte(manifest), &m)
+	return m.MediaType
+}
+
+func digestOf(manifest string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest)))
+}
+
+func TestRegistryResolver(t *testing.T) {
+	manifests := map[string]string{
+		"foo/app:1.0":        `{"manifest": "app"}`,
+		"foo/app:latest":     `{"manifest": "latest"}`,
+		"library/nginx:1.25": `{"manifest": "nginx"}`,
+	}
+	creds := func(host string) *RegistryConfig {
+		return &RegistryConfig{Auths: map[string]RegistryAuth{
+			"https://" + host + "/v1/": {Auth: base64.StdEncoding.EncodeToString([]byte(fakeUser + ":" + fakePassword))},
+		}}
+	}
+
+	testCases := []struct {
+		desc         string
+		auth         string
+		omitDigest   bool
+		withCreds    bool
+		img          string
+		expDigest    string
+		expErrSubstr string
+	}{
+		{desc: "anonymous", img: "foo/app:1.0", expDigest: digestOf(manifests["foo/app:1.0"])},
+		{desc: "default tag", img: "foo/app", expDigest: digestOf(manifests["foo/app:latest"])},
+		{desc: "computed digest", omitDigest: true, img: "foo/app:1.0", expDigest: digestOf(manifests["foo/app:1.0"])},
+		{desc: "basic auth", auth: "basic", withCreds: true, img: "foo/app:1.0", expDigest: digestOf(manifests["foo/app:1.0"])},
+		{desc: "bearer token", auth: "bearer", withCreds: true, img: "foo/app:1.0", expDigest: digestOf(manifests["foo/app:1.0"])},
+		{desc: "missing credentials", auth: "basic", img: "foo/app:1.0", expErrSubstr: "registry requires credentials"},
+		{desc: "token denied", auth: "bearer", img: "foo/app:1.0", expErrSubstr: "fetching token"},
+		{desc: "unknown tag", img: "foo/app:2.0", expErrSubstr: "404"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			reg := newFakeRegistry(t, tc.auth, manifests)
+			reg.omitDigest = tc.omitDigest
+			var config *RegistryConfig
+			if tc.withCreds {
+				config = creds(reg.host())
+			}
+			r := reg.resolver(config)
+
+			// Resolve twice to check that tokens are reused.
+			for i := 0; i < 2; i++ {
+				got, err := r.Resolve(context.Background(), reg.host()+"/"+tc.img)
+				if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+					t.Fatal(cerr)
+				}
+				if err != nil {
+					return
+				}
+				if got != tc.expDigest {
+					t.Errorf("got digest %q, expected %q", got, tc.expDigest)
+				}
+			}
+			if tc.auth == "bearer" && reg.tokenRequests != 1 {
+				t.Errorf("got %d token requests, expected 1", reg.tokenRequests)
+			}
+		})
+	}
+}
+
+func TestRegistryResolver_InsecureRegistry(t *testing.T) {
+	manifests := map[string]string{"foo/app:1.0": `{"manifest": "app"}`}
+	reg := newInsecureFakeRegistry(t, manifests)
+	img := reg.host() + "/foo/app:1.0"
+
+	r := reg.resolver(nil)
+	if _, err := r.Resolve(context.Background(), img); err == nil {
+		t.Fatalf("Resolve(%q) over HTTPS: got no error, expected one", img)
+	}
+
+	r.InsecureRegistries = []string{reg.host()}
+	got, err := r.Resolve(context.Background(), img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := digestOf(manifests["foo/app:1.0"]); got != exp {
+		t.Errorf("got digest %q, expected %q", got, exp)
+	}
+}
+
+func TestContainerImages(t *testing.T) {
+	bun, err := converter.FromYAMLString(`
+kind: Bundle
+components:
+- spec:
+    componentName: app
+    objects:
+    - apiVersion: v1
+      kind: Pod
+      metadata:
+        name: app
+      spec:
+        containers:
+        - image: gcr.io/foo/app:1.0
+        - image: gcr.io/foo/sidecar:2.0
+        - image: gcr.io/foo/app:1.0
+- spec:
+    componentName: nodes
+    objects:
+    - apiVersion: bundleext.gke.io/v1alpha1
+      kind: NodeConfig
+      metadata:
+        name: nodes
+      osImage:
+        url: https://storage.googleapis.com/os/image.tar.gz
+`).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"gcr.io/foo/app:1.0", "gcr.io/foo/sidecar:2.0"}
+	if got := ContainerImages(bun.Components); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got images %v, expected %v", got, exp)
+	}
+}
+
+func TestPinAll(t *testing.T) {
+	manifests := map[string]string{
+		"foo/app:1.0":     `{"manifest": "app"}`,
+		"foo/sidecar:2.0": `{"manifest": "sidecar"}`,
+	}
+	reg := newFakeRegistry(t, "", manifests)
+	host := reg.host()
+
+	newBundle := func(appImage string) string {
+		return fmt.Sprintf(`
+kind: Bundle
+components:
+- spec:
+    componentName: app
+    objects:
+    - apiVersion: v1
+      kind: Pod
+      metadata:
+        name: app
+      spec:
+        containers:
+        - image: %[1]s/%[2]s
+        - image: %[1]s/foo/sidecar:2.0
+        - image: %[1]s/foo/pinned@sha256:0000
+    - apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: app
+      spec:
+        template:
+          spec:
+            containers:
+            - image: %[1]s/%[2]s
+- spec:
+    componentName: nodes
+    objects:
+    - apiVersion: bundleext.gke.io/v1alpha1
+      kind: NodeConfig
+      metadata:
+        name: nodes
+      osImage:
+        url: https://storage.googleapis.com/os/image.tar.gz
+`, host, appImage)
+	}
+
+	appDigest := digestOf(manifests["foo/app:1.0"])
+	sidecarDigest := digestOf(manifests["foo/sidecar:2.0"])
+
+	testCases := []struct {
+		desc         string
+		appImage     string
+		resolver     DigestResolver
+		expImages    []string
+		expErrSubstr string
+	}{
+		{
+			desc:     "registry",
+			appImage: "foo/app:1.0",
+			resolver: reg.resolver(nil),
+			expImages: []string{
+				"image: " + host + "/foo/app@" + appDigest,
+				"image: " + host + "/foo/sidecar@" + sidecarDigest,
+				"image: " + host + "/foo/pinned@sha256:0000",
+				"url: https://storage.googleapis.com/os/image.tar.gz",
+			},
+		},
+		{
+			desc:     "lock file",
+			appImage: "foo/app:1.0",
+			resolver: &LockFile{Digests: map[string]string{
+				host + "/foo/app:1.0":     "sha256:1111",
+				host + "/foo/sidecar:2.0": "sha256:2222",
+			}},
+			expImages: []string{
+				"image: " + host + "/foo/app@sha256:1111",
+				"image: " + host + "/foo/sidecar@sha256:2222",
+			},
+		},
+		{
+			desc:         "missing from lock file",
+			appImage:     "foo/app:1.0",
+			resolver:     &LockFile{},
+			expErrSubstr: "no digest found in lock file",
+		},
+		{
+			desc:         "unknown tag",
+			appImage:     "foo/app:3.0",
+			resolver:     reg.resolver(nil),
+			expErrSubstr: `/foo/app:3.0" in Pod "app" of component "app"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			bun, err := converter.FromYAMLString(newBundle(tc.appImage)).ToBundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			lock, err := PinAll(context.Background(), bun.Components, tc.resolver)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if len(lock.Digests) != 2 {
+				t.Errorf("got lock file digests %v, expected 2 digests", lock.Digests)
+			}
+			bunStr, err := converter.FromObject(bun).ToYAMLString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, exp := range tc.expImages {
+				if !strings.Contains(bunStr, exp) {
+					t.Errorf("expected bundle to contain %q, but got\n%s", exp, bunStr)
+				}
+			}
+			if strings.Contains(bunStr, ":1.0") {
+				t.Errorf("expected no tags to remain, but got\n%s", bunStr)
+			}
+		})
+	}
+}
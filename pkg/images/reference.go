@@ -0,0 +1,118 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// dockerHubDomain is the domain of images without a registry domain.
+	dockerHubDomain = "docker.io"
+
+	// dockerHubRegistry is the host serving the Docker Hub registry API.
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// defaultTag is the tag of images without a tag or digest.
+	defaultTag = "latest"
+)
+
+// Reference is a parsed container image reference of the form
+// `[<domain>/]<path>[:<tag>][@<digest>]`.
+type Reference struct {
+	// Name is the image name, exactly as written in the reference, without the
+	// tag or digest. For example, `gcr.io/foo/bar`.
+	Name string
+
+	// Domain is the registry domain of the image, such as `gcr.io`. It is
+	// `docker.io` for images without a domain.
+	Domain string
+
+	// Repository is the repository path within the registry, such as
+	// `foo/bar`. Docker Hub images without a namespace are in `library`.
+	Repository string
+
+	// Tag is the tag of the image, if any.
+	Tag string
+
+	// Digest is the digest of the image, if any. For example,
+	// `sha256:abcd...`.
+	Digest string
+}
+
+// ParseReference parses a container image reference.
+func ParseReference(img string) (*Reference, error) {
+	if img == "" {
+		return nil, fmt.Errorf("empty image reference")
+	}
+	ref := &Reference{}
+	name := img
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !strings.Contains(ref.Digest, ":") {
+			return nil, fmt.Errorf("invalid digest %q in image reference %q", ref.Digest, img)
+		}
+	}
+	if i := strings.LastIndexByte(name, ':'); i > strings.LastIndexByte(name, '/') {
+		name, ref.Tag = name[:i], name[i+1:]
+		if ref.Tag == "" {
+			return nil, fmt.Errorf("empty tag in image reference %q", img)
+		}
+	}
+	if name == "" || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+		return nil, fmt.Errorf("invalid image name in image reference %q", img)
+	}
+	ref.Name = name
+
+	ref.Domain, ref.Repository = dockerHubDomain, name
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		if first := name[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Domain, ref.Repository = first, name[i+1:]
+		}
+	}
+	if ref.Domain == dockerHubDomain && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref, nil
+}
+
+// Registry returns the host serving the registry API for the image.
+func (r *Reference) Registry() string {
+	if r.Domain == dockerHubDomain {
+		return dockerHubRegistry
+	}
+	return r.Domain
+}
+
+// TagOrDefault returns the tag of the image, or `latest` if there is none.
+func (r *Reference) TagOrDefault() string {
+	if r.Tag == "" {
+		return defaultTag
+	}
+	return r.Tag
+}
+
+// String returns the image reference.
+func (r *Reference) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
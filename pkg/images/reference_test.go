@@ -0,0 +1,81 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestParseReference(t *testing.T) {
+	testCases := []struct {
+		img          string
+		exp          *Reference
+		expRegistry  string
+		expErrSubstr string
+	}{
+		{
+			img:         "gcr.io/foo/bar:1.0",
+			exp:         &Reference{Name: "gcr.io/foo/bar", Domain: "gcr.io", Repository: "foo/bar", Tag: "1.0"},
+			expRegistry: "gcr.io",
+		},
+		{
+			img:         "localhost:5000/bar@sha256:abcd",
+			exp:         &Reference{Name: "localhost:5000/bar", Domain: "localhost:5000", Repository: "bar", Digest: "sha256:abcd"},
+			expRegistry: "localhost:5000",
+		},
+		{
+			img:         "gcr.io/foo/bar:1.0@sha256:abcd",
+			exp:         &Reference{Name: "gcr.io/foo/bar", Domain: "gcr.io", Repository: "foo/bar", Tag: "1.0", Digest: "sha256:abcd"},
+			expRegistry: "gcr.io",
+		},
+		{
+			img:         "nginx",
+			exp:         &Reference{Name: "nginx", Domain: "docker.io", Repository: "library/nginx"},
+			expRegistry: "registry-1.docker.io",
+		},
+		{
+			img:         "someone/app:2",
+			exp:         &Reference{Name: "someone/app", Domain: "docker.io", Repository: "someone/app", Tag: "2"},
+			expRegistry: "registry-1.docker.io",
+		},
+		{img: "", expErrSubstr: "empty image reference"},
+		{img: "gcr.io/foo:", expErrSubstr: "empty tag"},
+		{img: "gcr.io/foo@abcd", expErrSubstr: "invalid digest"},
+		{img: "gcr.io//foo", expErrSubstr: "invalid image name"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.img, func(t *testing.T) {
+			got, err := ParseReference(tc.img)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("ParseReference(%q) = %+v, expected %+v", tc.img, got, tc.exp)
+			}
+			if got.Registry() != tc.expRegistry {
+				t.Errorf("got registry %q, expected %q", got.Registry(), tc.expRegistry)
+			}
+			if got.String() != tc.img {
+				t.Errorf("got string %q, expected %q", got.String(), tc.img)
+			}
+		})
+	}
+}
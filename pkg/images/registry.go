@@ -0,0 +1,407 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// manifestMediaTypes are the manifest media types accepted from registries.
+// Indexes and manifest lists are preferred, so that multi-platform images are
+// pinned to the digest of the index rather than that of a single platform.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// RegistryConfig contains the credentials for registries. It has the same
+// form as the Docker config file, `~/.docker/config.json`, so that an
+// existing Docker config can be used:
+//
+//	{"auths": {"gcr.io": {"auth": "<base64 of user:password>"}}}
+type RegistryConfig struct {
+	// Auths are the credentials, keyed by registry domain. The keys may also be
+	// URLs such as `https://index.docker.io/v1/`.
+	Auths map[string]RegistryAuth `json:"auths,omitempty"`
+}
+
+// RegistryAuth contains the credentials for a registry, either as Auth or as
+// Username and Password.
+type RegistryAuth struct {
+	// Auth is the base64 encoding of `<username>:<password>`.
+	Auth string `json:"auth,omitempty"`
+
+	// Username for the registry.
+	Username string `json:"username,omitempty"`
+
+	// Password for the registry.
+	Password string `json:"password,omitempty"`
+}
+
+// credentials returns the username and password for a registry domain, and
+// whether there are any.
+func (c *RegistryConfig) credentials(domain string) (string, string, bool, error) {
+	if c == nil {
+		return "", "", false, nil
+	}
+	for key, a := range c.Auths {
+		if normalizeRegistryKey(key) != domain {
+			continue
+		}
+		if a.Auth == "" {
+			return a.Username, a.Password, a.Username != "" || a.Password != "", nil
+		}
+		dec, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid auth for registry %q: %v", key, err)
+		}
+		parts := strings.SplitN(string(dec), ":", 2)
+		if len(parts) != 2 {
+			return "", "", false, fmt.Errorf("invalid auth for registry %q: expected the form <username>:<password>", key)
+		}
+		return parts[0], parts[1], true, nil
+	}
+	return "", "", false, nil
+}
+
+// normalizeRegistryKey converts a key of RegistryConfig.Auths to a registry
+// domain.
+func normalizeRegistryKey(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	if i := strings.IndexByte(key, '/'); i >= 0 {
+		key = key[:i]
+	}
+	switch key {
+	case "index.docker.io", dockerHubRegistry:
+		return dockerHubDomain
+	}
+	return key
+}
+
+// RegistryResolver resolves image tags to digests using the OCI distribution
+// API of the image registries. It's also used to copy images between
+// registries and OCI image layouts.
+type RegistryResolver struct {
+	// Client is the HTTP client used to talk to registries. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// Config contains the registry credentials. If nil, registries are
+	// accessed anonymously.
+	Config *RegistryConfig
+
+	// InsecureRegistries are the registries, as host or host:port, that are
+	// accessed over plain HTTP rather than HTTPS, such as local test
+	// registries.
+	InsecureRegistries []string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewRegistryResolver creates a RegistryResolver that uses the specified
+// credentials, which may be nil.
+func NewRegistryResolver(config *RegistryConfig) *RegistryResolver {
+	return &RegistryResolver{Config: config}
+}
+
+// Resolve returns the digest of the manifest that an image's tag refers to.
+func (r *RegistryResolver) Resolve(ctx context.Context, img string) (string, error) {
+	ref, err := ParseReference(img)
+	if err != nil {
+		return "", err
+	}
+	rr := &registryRequest{
+		method: http.MethodHead,
+		url:    r.registryURL(ref, "manifests", ref.TagOrDefault()),
+		ref:    ref,
+		header: manifestHeader(),
+	}
+
+	resp, err := r.do(ctx, rr)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if dig := resp.Header.Get("Docker-Content-Digest"); dig != "" {
+		return dig, nil
+	}
+
+	// Registries aren't required to return the digest, in which case it's
+	// computed from the manifest itself.
+	rr.method = http.MethodGet
+	resp, err = r.do(ctx, rr)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("reading manifest of image %q: %v", img, err)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// registryRequest is a request to the registry API for an image repository.
+type registryRequest struct {
+	method string
+	url    string
+	ref    *Reference
+
+	// push is whether the request needs push access to the repository, rather
+	// than only pull access.
+	push bool
+
+	// header contains additional request headers.
+	header http.Header
+
+	// body opens the request body, and returns its size. It's called again if
+	// the request is retried after authenticating. If nil, there is no body.
+	body func() (io.ReadCloser, int64, error)
+
+	// okStatus are the response statuses that are successful. If empty, only
+	// http.StatusOK is.
+	okStatus []int
+}
+
+// registryURL returns the URL of a registry API endpoint for an image
+// repository, such as `https://gcr.io/v2/foo/bar/manifests/1.0`.
+func (r *RegistryResolver) registryURL(ref *Reference, endpoint, reference string) string {
+	scheme := "https"
+	if r.isInsecure(ref) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", scheme, ref.Registry(), ref.Repository, endpoint, reference)
+}
+
+// isInsecure returns whether the registry of an image is accessed over plain
+// HTTP.
+func (r *RegistryResolver) isInsecure(ref *Reference) bool {
+	for _, reg := range r.InsecureRegistries {
+		if reg == ref.Domain || reg == ref.Registry() {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestHeader returns the request headers for fetching manifests.
+func manifestHeader() http.Header {
+	return http.Header{"Accept": []string{strings.Join(manifestMediaTypes, ", ")}}
+}
+
+// do performs a registry API request, authenticating if the registry requires
+// it. The response is only returned if it is successful.
+func (r *RegistryResolver) do(ctx context.Context, rr *registryRequest) (*http.Response, error) {
+	scope := "repository:" + rr.ref.Repository + ":pull"
+	if rr.push {
+		scope += ",push"
+	}
+	resp, err := r.request(ctx, rr, r.authorization(rr.ref.Registry(), scope))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		auth, err := r.authorize(ctx, rr.ref, scope, challenge)
+		if err != nil {
+			return nil, fmt.Errorf("authenticating to registry %q: %v", rr.ref.Registry(), err)
+		}
+		if resp, err = r.request(ctx, rr, auth); err != nil {
+			return nil, err
+		}
+	}
+	okStatus := rr.okStatus
+	if len(okStatus) == 0 {
+		okStatus = []int{http.StatusOK}
+	}
+	for _, s := range okStatus {
+		if resp.StatusCode == s {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s %s: unexpected status %q", rr.method, rr.url, resp.Status)
+}
+
+// request performs a single HTTP request.
+func (r *RegistryResolver) request(ctx context.Context, rr *registryRequest, auth string) (*http.Response, error) {
+	req, err := http.NewRequest(rr.method, rr.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range rr.header {
+		req.Header[k] = v
+	}
+	if rr.body != nil {
+		body, size, err := rr.body()
+		if err != nil {
+			return nil, err
+		}
+		req.Body, req.ContentLength = body, size
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// authorization returns a cached Authorization header for a registry and
+// scope, if any.
+func (r *RegistryResolver) authorization(registry, scope string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokens[registry+" "+scope]
+}
+
+// authorize returns the Authorization header that answers an authentication
+// challenge, and caches it.
+func (r *RegistryResolver) authorize(ctx context.Context, ref *Reference, scope, challenge string) (string, error) {
+	user, pass, hasCreds, err := r.Config.credentials(ref.Domain)
+	if err != nil {
+		return "", err
+	}
+	scheme, params := parseChallenge(challenge)
+	var auth string
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCreds {
+			return "", fmt.Errorf("registry requires credentials, but none were configured")
+		}
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	case "bearer":
+		token, err := r.fetchToken(ctx, params, scope, user, pass, hasCreds)
+		if err != nil {
+			return "", err
+		}
+		auth = "Bearer " + token
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens == nil {
+		r.tokens = make(map[string]string)
+	}
+	r.tokens[ref.Registry()+" "+scope] = auth
+	return auth, nil
+}
+
+// fetchToken fetches a bearer token from the token server of a registry.
+func (r *RegistryResolver) fetchToken(ctx context.Context, params map[string]string, scope, user, pass string, hasCreds bool) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("bearer challenge has no realm")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %v", realm, err)
+	}
+	q := u.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	if s := params["scope"]; s != "" {
+		scope = s
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	var auth string
+	if hasCreds {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	}
+	resp, err := r.request(ctx, &registryRequest{method: http.MethodGet, url: u.String()}, auth)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching token from %q: unexpected status %q", realm, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("parsing token from %q: %v", realm, err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned from %q", realm)
+}
+
+// parseChallenge parses a WWW-Authenticate header of the form
+// `Bearer realm="...",service="...",scope="..."`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	challenge = strings.TrimSpace(challenge)
+	i := strings.IndexByte(challenge, ' ')
+	if i < 0 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:i], challenge[i+1:]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				val, rest = rest[1:], ""
+			} else {
+				val, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			val, rest = rest[:comma], rest[comma:]
+		} else {
+			val, rest = rest, ""
+		}
+		params[key] = val
+	}
+	return scheme, params
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package images provides functionality for transforming the container and
+// OS images referenced by components, for example to mirror them in a private
+// registry.
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/core"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/find"
+)
+
+// RewriteRules map image references to new image references. They are
+// usually read from a YAML or JSON file of the form:
+//
+//	rules:
+//	- prefix: gcr.io/foo
+//	  replacement: registry.local/foo
+//	- exact: k8s.gcr.io/pause:3.1
+//	  replacement: registry.local/pause:3.1
+type RewriteRules struct {
+	// Rules are the rewrite rules. An exact rule takes precedence over prefix
+	// rules, and the longest matching prefix takes precedence over shorter
+	// ones.
+	Rules []RewriteRule `json:"rules,omitempty"`
+}
+
+// RewriteRule rewrites the image references that match it. Exactly one of
+// Prefix or Exact must be specified.
+type RewriteRule struct {
+	// Prefix matches image references that start with the prefix, followed by
+	// the end of the reference or by one of '/', ':' or '@'. The prefix is
+	// replaced with the Replacement, so 'gcr.io/foo' matches
+	// 'gcr.io/foo/bar:1.0' but not 'gcr.io/foobar:1.0'.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Exact matches a single image reference, which is replaced with the
+	// Replacement.
+	Exact string `json:"exact,omitempty"`
+
+	// Replacement is the new image reference or prefix.
+	Replacement string `json:"replacement"`
+}
+
+// Validate checks that the rules are well formed.
+func (r *RewriteRules) Validate() error {
+	exact := make(map[string]bool)
+	prefix := make(map[string]bool)
+	for i, rule := range r.Rules {
+		switch {
+		case rule.Prefix != "" && rule.Exact != "":
+			return fmt.Errorf("rule %d: only one of prefix or exact may be specified", i)
+		case rule.Prefix == "" && rule.Exact == "":
+			return fmt.Errorf("rule %d: one of prefix or exact must be specified", i)
+		case rule.Replacement == "":
+			return fmt.Errorf("rule %d: replacement must be specified", i)
+		case rule.Exact != "" && exact[rule.Exact]:
+			return fmt.Errorf("rule %d: duplicate rule for image %q", i, rule.Exact)
+		case rule.Prefix != "" && prefix[rule.Prefix]:
+			return fmt.Errorf("rule %d: duplicate rule for prefix %q", i, rule.Prefix)
+		}
+		if rule.Exact != "" {
+			exact[rule.Exact] = true
+		} else {
+			prefix[rule.Prefix] = true
+		}
+	}
+	return nil
+}
+
+// Rewrite returns the rewritten image reference and whether any rule matched
+// it.
+func (r *RewriteRules) Rewrite(img string) (string, bool) {
+	var best *RewriteRule
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Exact != "" {
+			if rule.Exact == img {
+				return rule.Replacement, true
+			}
+			continue
+		}
+		if hasImagePrefix(img, rule.Prefix) && (best == nil || len(rule.Prefix) > len(best.Prefix)) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return img, false
+	}
+	return best.Replacement + strings.TrimPrefix(img, best.Prefix), true
+}
+
+// hasImagePrefix returns whether an image reference starts with a prefix that
+// ends at a boundary between the parts of the reference.
+func hasImagePrefix(img, prefix string) bool {
+	if !strings.HasPrefix(img, prefix) {
+		return false
+	}
+	if len(img) == len(prefix) || strings.HasSuffix(prefix, "/") {
+		return true
+	}
+	switch img[len(prefix)] {
+	case '/', ':', '@':
+		return true
+	}
+	return false
+}
+
+// RewriteAll rewrites the container images and NodeConfig OS image URLs in
+// the components according to the rules, and returns the images that matched
+// no rule. The components are changed in-place, so they should be copied if
+// the originals are needed.
+func RewriteAll(comps []*bundle.Component, rules *RewriteRules) []*find.ContainerImage {
+	var unmatched []*find.ContainerImage
+	find.NewImageFinder(comps).WalkAllImages(func(key core.ClusterObjectKey, img string) string {
+		newImg, ok := rules.Rewrite(img)
+		if !ok {
+			unmatched = append(unmatched, &find.ContainerImage{Key: key, Image: img})
+		}
+		return newImg
+	})
+	return unmatched
+}
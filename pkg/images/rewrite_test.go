@@ -0,0 +1,150 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+var testRules = &RewriteRules{
+	Rules: []RewriteRule{
+		{Prefix: "gcr.io/foo", Replacement: "registry.local/foo"},
+		{Prefix: "gcr.io/foo/special", Replacement: "registry.local/special"},
+		{Prefix: "https://storage.googleapis.com/", Replacement: "https://mirror.local/"},
+		{Exact: "k8s.gcr.io/pause:3.1", Replacement: "registry.local/pause:3.1"},
+		{Prefix: "k8s.gcr.io", Replacement: "registry.local/k8s"},
+	},
+}
+
+func TestRewrite(t *testing.T) {
+	testCases := []struct {
+		img      string
+		exp      string
+		expMatch bool
+	}{
+		{img: "gcr.io/foo/bar:1.0", exp: "registry.local/foo/bar:1.0", expMatch: true},
+		{img: "gcr.io/foo:1.0", exp: "registry.local/foo:1.0", expMatch: true},
+		{img: "gcr.io/foo@sha256:abcd", exp: "registry.local/foo@sha256:abcd", expMatch: true},
+		{img: "gcr.io/foo", exp: "registry.local/foo", expMatch: true},
+		{img: "gcr.io/foo/special/bar:1.0", exp: "registry.local/special/bar:1.0", expMatch: true},
+		{img: "gcr.io/foobar:1.0", exp: "gcr.io/foobar:1.0"},
+		{img: "k8s.gcr.io/pause:3.1", exp: "registry.local/pause:3.1", expMatch: true},
+		{img: "k8s.gcr.io/pause:3.2", exp: "registry.local/k8s/pause:3.2", expMatch: true},
+		{img: "https://storage.googleapis.com/os/image.tar.gz", exp: "https://mirror.local/os/image.tar.gz", expMatch: true},
+		{img: "docker.io/library/nginx", exp: "docker.io/library/nginx"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.img, func(t *testing.T) {
+			got, matched := testRules.Rewrite(tc.img)
+			if got != tc.exp || matched != tc.expMatch {
+				t.Errorf("Rewrite(%q) = (%q, %t), expected (%q, %t)", tc.img, got, matched, tc.exp, tc.expMatch)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		rules        []RewriteRule
+		expErrSubstr string
+	}{
+		{desc: "valid", rules: testRules.Rules},
+		{
+			desc:         "both prefix and exact",
+			rules:        []RewriteRule{{Prefix: "a", Exact: "b", Replacement: "c"}},
+			expErrSubstr: "rule 0: only one of prefix or exact",
+		},
+		{
+			desc:         "neither prefix nor exact",
+			rules:        []RewriteRule{{Replacement: "c"}},
+			expErrSubstr: "one of prefix or exact must be specified",
+		},
+		{
+			desc:         "no replacement",
+			rules:        []RewriteRule{{Prefix: "a"}},
+			expErrSubstr: "replacement must be specified",
+		},
+		{
+			desc:         "duplicate prefix",
+			rules:        []RewriteRule{{Prefix: "a", Replacement: "b"}, {Prefix: "a", Replacement: "c"}},
+			expErrSubstr: `rule 1: duplicate rule for prefix "a"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := (&RewriteRules{Rules: tc.rules}).Validate()
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Error(cerr)
+			}
+		})
+	}
+}
+
+func TestRewriteAll(t *testing.T) {
+	bun, err := converter.FromYAMLString(`
+kind: Bundle
+components:
+- spec:
+    componentName: app
+    objects:
+    - apiVersion: v1
+      kind: Pod
+      metadata:
+        name: app
+      spec:
+        initContainers:
+        - image: k8s.gcr.io/pause:3.1
+        containers:
+        - image: gcr.io/foo/app:1.0
+        - image: quay.io/other/sidecar:2.0
+- spec:
+    componentName: nodes
+    objects:
+    - apiVersion: bundleext.gke.io/v1alpha1
+      kind: NodeConfig
+      metadata:
+        name: nodes
+      osImage:
+        url: https://storage.googleapis.com/os/image.tar.gz
+`).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmatched := RewriteAll(bun.Components, testRules)
+	if len(unmatched) != 1 || unmatched[0].Image != "quay.io/other/sidecar:2.0" || unmatched[0].Key.Object.Name != "app" {
+		t.Errorf("got unmatched images %v, expected only quay.io/other/sidecar:2.0 in Pod app", unmatched)
+	}
+
+	bunStr, err := converter.FromObject(bun).ToYAMLString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		"image: registry.local/pause:3.1",
+		"image: registry.local/foo/app:1.0",
+		"image: quay.io/other/sidecar:2.0",
+		"url: https://mirror.local/os/image.tar.gz",
+	} {
+		if !strings.Contains(bunStr, exp) {
+			t.Errorf("expected bundle to contain %q, but got\n%s", exp, bunStr)
+		}
+	}
+}
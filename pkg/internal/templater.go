@@ -1,9 +1,15 @@
 package internal
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"sync/atomic"
 	"text/template"
+	"text/template/parse"
+	"time"
 
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/google/safetext/yamltemplate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -26,9 +32,17 @@ func HasSafeYAMLAnnotation(objmeta metav1.ObjectMeta) bool {
 
 // Templater provides templating functionality for YAML templating.
 type Templater struct {
+	name                 string
+	templateDoc          string
 	useSafeYAMLTemplater bool
 	yamlTemplater        *yamltemplate.Template
 	standardTemplater    *template.Template
+	limits               options.TemplateLimits
+
+	// funcs and opts are kept so that the template can be rebuilt with stop
+	// checks for executions with a timeout.
+	funcs map[string]interface{}
+	opts  []string
 }
 
 // NewTemplater creates a new Templater.
@@ -42,7 +56,7 @@ func NewTemplater(tmplName, templateDoc string, funcs map[string]interface{}, us
 		if err != nil {
 			return nil, err
 		}
-		return &Templater{useSafeYAMLTemplater: true, yamlTemplater: t}, nil
+		return &Templater{name: tmplName, templateDoc: templateDoc, useSafeYAMLTemplater: true, yamlTemplater: t, funcs: funcs}, nil
 	}
 
 	t := template.New(tmplName + "-tmpl")
@@ -53,11 +67,12 @@ func NewTemplater(tmplName, templateDoc string, funcs map[string]interface{}, us
 	if err != nil {
 		return nil, err
 	}
-	return &Templater{standardTemplater: t}, nil
+	return &Templater{name: tmplName, templateDoc: templateDoc, standardTemplater: t, funcs: funcs}, nil
 }
 
 // Option sets an option on the underlying Templater.
 func (t *Templater) Option(opt ...string) *Templater {
+	t.opts = append(t.opts, opt...)
 	if t.useSafeYAMLTemplater {
 		t.yamlTemplater = t.yamlTemplater.Option(opt...)
 	} else {
@@ -66,10 +81,320 @@ func (t *Templater) Option(opt ...string) *Templater {
 	return t
 }
 
-// Execute executes the template rendering.
+// Limits sets the limits that bound the execution of the template.
+func (t *Templater) Limits(limits options.TemplateLimits) *Templater {
+	t.limits = limits
+	return t
+}
+
+// Execute executes the template rendering. If the execution exceeds one of
+// the Templater's limits, an *options.TemplateLimitError is returned and
+// nothing is written to wr.
+//
+// Go templates can't be interrupted, so a template that times out keeps
+// running in the background until it next writes output, starts an iteration
+// of a range or invokes a template, at which point it fails.
 func (t *Templater) Execute(wr io.Writer, data any) error {
+	if t.limits == (options.TemplateLimits{}) {
+		return t.execute(wr, data)
+	}
+	if t.limits.MaxDepth > 0 && !withinDepth(t.name, t.templateDoc, t.limits.MaxDepth) {
+		return t.limitError(options.TemplateDepthLimit)
+	}
+
+	lw := &limitWriter{max: t.limits.MaxOutputBytes}
+	if t.limits.Timeout <= 0 {
+		if err := t.execute(lw, data); err != nil && !lw.exceeded {
+			return err
+		}
+		if lw.exceeded {
+			return t.limitError(options.TemplateOutputLimit)
+		}
+		_, err := lw.buf.WriteTo(wr)
+		return err
+	}
+
+	st, err := t.stoppable(&lw.stopped)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- st.execute(lw, data)
+	}()
+	timer := time.NewTimer(t.limits.Timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if lw.exceeded {
+			return t.limitError(options.TemplateOutputLimit)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = lw.buf.WriteTo(wr)
+		return err
+	case <-timer.C:
+		lw.stopped.Store(true)
+		return t.limitError(options.TemplateTimeoutLimit)
+	}
+}
+
+// execute executes the template rendering without limits.
+func (t *Templater) execute(wr io.Writer, data any) error {
 	if t.useSafeYAMLTemplater {
 		return t.yamlTemplater.Execute(wr, data)
 	}
 	return t.standardTemplater.Execute(wr, data)
 }
+
+// stopFuncName is the name of the template func that fails once the execution
+// of a template has been stopped.
+const stopFuncName = "_bundleStopCheck"
+
+// stoppable returns a copy of the Templater whose templates check whether stop
+// is set at the start of every block, including range bodies, and fail if it
+// is. Otherwise, a template that loops without writing output could never be
+// stopped.
+func (t *Templater) stoppable(stop *atomic.Bool) (*Templater, error) {
+	name := t.templateName()
+	trees := make(map[string]*parse.Tree)
+	tr := parse.New(name)
+	tr.Mode = parse.SkipFuncCheck
+	if _, err := tr.Parse(t.templateDoc, "", "", trees); err != nil {
+		return nil, err
+	}
+	check, err := stopCheckNode()
+	if err != nil {
+		return nil, err
+	}
+	for _, tree := range trees {
+		addStopChecks(tree.Root, check)
+	}
+	stopFuncs := map[string]interface{}{
+		stopFuncName: func() (string, error) {
+			if stop.Load() {
+				return "", errLimitExceeded
+			}
+			return "", nil
+		},
+	}
+
+	st := *t
+	if t.useSafeYAMLTemplater {
+		yt := yamltemplate.New(name)
+		if t.funcs != nil {
+			yt.Funcs(t.funcs)
+		}
+		yt.Funcs(stopFuncs)
+		for n, tree := range trees {
+			if _, err := yt.AddParseTree(n, tree); err != nil {
+				return nil, err
+			}
+		}
+		st.yamlTemplater = yt.Option(t.opts...)
+		return &st, nil
+	}
+
+	stdt := template.New(name)
+	if t.funcs != nil {
+		stdt.Funcs(t.funcs)
+	}
+	stdt.Funcs(stopFuncs)
+	for n, tree := range trees {
+		if _, err := stdt.AddParseTree(n, tree); err != nil {
+			return nil, err
+		}
+	}
+	st.standardTemplater = stdt.Option(t.opts...)
+	return &st, nil
+}
+
+// templateName returns the name of the underlying template.
+func (t *Templater) templateName() string {
+	if t.useSafeYAMLTemplater {
+		return t.yamlTemplater.Name()
+	}
+	return t.standardTemplater.Name()
+}
+
+// stopCheckNode returns an action that calls the stop func. The result is
+// assigned to a variable, so that the action writes nothing and the safe YAML
+// templater treats it as declarative.
+func stopCheckNode() (parse.Node, error) {
+	trees := make(map[string]*parse.Tree)
+	tr := parse.New("stop")
+	tr.Mode = parse.SkipFuncCheck
+	if _, err := tr.Parse("{{$_ := "+stopFuncName+"}}", "", "", trees); err != nil {
+		return nil, err
+	}
+	return trees["stop"].Root.Nodes[0], nil
+}
+
+// addStopChecks inserts a copy of the stop check at the start of a list and of
+// every list nested within it.
+func addStopChecks(list *parse.ListNode, check parse.Node) {
+	if list == nil {
+		return
+	}
+	for _, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.IfNode:
+			addStopChecks(n.List, check)
+			addStopChecks(n.ElseList, check)
+		case *parse.RangeNode:
+			addStopChecks(n.List, check)
+			addStopChecks(n.ElseList, check)
+		case *parse.WithNode:
+			addStopChecks(n.List, check)
+			addStopChecks(n.ElseList, check)
+		}
+	}
+	list.Nodes = append([]parse.Node{check.Copy()}, list.Nodes...)
+}
+
+// limitError returns the error for an exceeded limit.
+func (t *Templater) limitError(limit options.TemplateLimit) error {
+	return &options.TemplateLimitError{Template: t.name, Limit: limit, Limits: t.limits}
+}
+
+// errLimitExceeded is returned by a limitWriter to stop the execution of a
+// template.
+var errLimitExceeded = errors.New("template execution limit exceeded")
+
+// limitWriter buffers the output of a template, failing once the output
+// exceeds max bytes, if max is positive, or once the execution is stopped.
+type limitWriter struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+	stopped  atomic.Bool
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.stopped.Load() {
+		return 0, errLimitExceeded
+	}
+	if w.max > 0 && w.buf.Len()+len(p) > w.max {
+		w.exceeded = true
+		return 0, errLimitExceeded
+	}
+	return w.buf.Write(p)
+}
+
+// withinDepth returns whether the actions of a template are nested at most
+// maxDepth deep. Templates invoked with template actions are counted as nested
+// within the action, and recursive templates are never within the limit.
+func withinDepth(name, templateDoc string, maxDepth int) bool {
+	trees := make(map[string]*parse.Tree)
+	tr := parse.New(name)
+	tr.Mode = parse.SkipFuncCheck
+	if _, err := tr.Parse(templateDoc, "", "", trees); err != nil {
+		// The template has already been parsed successfully, so this shouldn't
+		// happen; leave it to execution to report any problem.
+		return true
+	}
+	c := &depthCounter{trees: trees, depths: make(map[string]int), visiting: make(map[string]bool)}
+	depth, ok := c.tree(name)
+	return ok && depth <= maxDepth
+}
+
+// depthCounter computes the nesting depth of the actions in a set of
+// templates.
+type depthCounter struct {
+	trees    map[string]*parse.Tree
+	depths   map[string]int
+	visiting map[string]bool
+}
+
+// tree returns the depth of the named template, and false if it's recursive.
+func (c *depthCounter) tree(name string) (int, bool) {
+	if d, ok := c.depths[name]; ok {
+		return d, true
+	}
+	if c.visiting[name] {
+		return 0, false
+	}
+	tr := c.trees[name]
+	if tr == nil {
+		// Undefined templates fail at execution time.
+		return 0, true
+	}
+	c.visiting[name] = true
+	d, ok := c.list(tr.Root)
+	c.visiting[name] = false
+	if ok {
+		c.depths[name] = d
+	}
+	return d, ok
+}
+
+// list returns the depth of a list of nodes, and false if it invokes a
+// recursive template.
+func (c *depthCounter) list(l *parse.ListNode) (int, bool) {
+	if l == nil {
+		return 0, true
+	}
+	max := 0
+	for _, n := range l.Nodes {
+		d, ok := c.node(n)
+		if !ok {
+			return 0, false
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max, true
+}
+
+// node returns the depth of a node, and false if it invokes a recursive
+// template.
+func (c *depthCounter) node(n parse.Node) (int, bool) {
+	var b *parse.BranchNode
+	switch n := n.(type) {
+	case *parse.IfNode:
+		b = &n.BranchNode
+	case *parse.RangeNode:
+		b = &n.BranchNode
+	case *parse.WithNode:
+		b = &n.BranchNode
+	case *parse.ListNode:
+		return c.list(n)
+	case *parse.TemplateNode:
+		d, ok := c.tree(n.Name)
+		return d + 1, ok
+	default:
+		return 0, true
+	}
+	d, ok := c.list(b.List)
+	if !ok {
+		return 0, false
+	}
+	e, ok := c.list(b.ElseList)
+	if !ok {
+		return 0, false
+	}
+	if elseChain(b.ElseList) {
+		// An 'else if' or 'else with' continues the action rather than nesting
+		// within it.
+		e--
+	}
+	if e > d {
+		d = e
+	}
+	return d + 1, true
+}
+
+// elseChain returns whether an else list is an 'else if' or 'else with'
+// action.
+func elseChain(l *parse.ListNode) bool {
+	if l == nil || len(l.Nodes) != 1 {
+		return false
+	}
+	switch l.Nodes[0].(type) {
+	case *parse.IfNode, *parse.WithNode:
+		return true
+	}
+	return false
+}
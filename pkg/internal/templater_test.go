@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+func TestExecuteTimeoutStopsTemplate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		safeYAML bool
+	}{
+		{desc: "standard templater"},
+		{desc: "safe YAML templater", safeYAML: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var calls atomic.Int64
+			funcs := map[string]interface{}{
+				"count": func() int64 { return calls.Add(1) },
+			}
+			// The loop never writes any output.
+			tmpl, err := NewTemplater("loop", `{{range .Items}}{{range $.Items}}{{$n := count}}{{end}}{{end}}`, funcs, tc.safeYAML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl.Limits(options.TemplateLimits{Timeout: 10 * time.Millisecond})
+
+			data := map[string]interface{}{"Items": make([]int, 100000)}
+			err = tmpl.Execute(io.Discard, data)
+			var lerr *options.TemplateLimitError
+			if !errors.As(err, &lerr) || lerr.Limit != options.TemplateTimeoutLimit {
+				t.Fatalf("Execute(): got error %v, expected a timeout", err)
+			}
+
+			// Give the template a moment to notice that it has been stopped.
+			time.Sleep(10 * time.Millisecond)
+			before := calls.Load()
+			time.Sleep(50 * time.Millisecond)
+			if after := calls.Load(); after != before {
+				t.Errorf("template kept running after the timeout: %d calls grew to %d", before, after)
+			}
+		})
+	}
+}
@@ -32,7 +32,7 @@ func ApplyCommon(ref bundle.ComponentReference, objs []*unstructured.Unstructure
 	for _, obj := range objs {
 		outObjects, err := objFn(obj, ref, opts)
 		if err != nil {
-			return nil, fmt.Errorf("for component %v, error in applying: %v", ref, err)
+			return nil, fmt.Errorf("for component %v, error in applying: %w", ref, err)
 		}
 		newObj = append(newObj, outObjects...)
 	}
@@ -47,6 +47,7 @@ type ApplierConfig func(*applier)
 type applier struct {
 	goTmplOptions        []string
 	useSafeYAMLTemplater bool
+	limits               options.TemplateLimits
 }
 
 // WithGoTmplOptions modifies NewApplier so that the returned Applier uses the
@@ -67,6 +68,15 @@ func WithSafeYAMLTemplaterOverride() ApplierConfig {
 	}
 }
 
+// WithTemplateLimits modifies NewApplier so that the returned Applier bounds
+// the execution of each template with the specified limits. Exceeding a limit
+// results in an *options.TemplateLimitError.
+func WithTemplateLimits(limits options.TemplateLimits) ApplierConfig {
+	return func(a *applier) {
+		a.limits = limits
+	}
+}
+
 // NewApplier creates a new options applier instance using the specified
 // ApplierConfigs.
 func NewApplier(opts ...ApplierConfig) options.Applier {
@@ -127,11 +137,12 @@ func (m *applier) applyOptions(obj *unstructured.Unstructured, ref bundle.Compon
 	for _, goTmplOpt := range m.goTmplOptions {
 		tmpl.Option(goTmplOpt)
 	}
+	tmpl.Limits(m.limits)
 
 	var buf bytes.Buffer
 	err = tmpl.Execute(&buf, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error executing template for object %v: %v", obj.GetName(), err)
+		return nil, fmt.Errorf("error executing template for object %v: %w", obj.GetName(), err)
 	}
 
 	// performed by go-yaml: https://godoc.org/gopkg.in/yaml.v2
@@ -15,8 +15,10 @@
 package gotmpl
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
@@ -551,3 +553,123 @@ func TestMissingValueError(t *testing.T) {
 		t.Errorf("During ApplyOptions(): got nil error, wanted non-nil error")
 	}
 }
+
+func TestTemplateLimits(t *testing.T) {
+	nested := `
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cm
+      data:
+        value: '{{range .Items}}{{range $.Items}}x{{end}}{{end}}'`
+
+	testCases := []struct {
+		desc      string
+		template  string
+		safeYAML  bool
+		items     int
+		limits    options.TemplateLimits
+		expLimit  options.TemplateLimit
+		expErrStr string
+	}{
+		{
+			desc:     "success: within limits",
+			template: nested,
+			items:    10,
+			limits:   options.TemplateLimits{Timeout: time.Minute, MaxOutputBytes: 1000, MaxDepth: 2},
+		},
+		{
+			desc: "success: else-if chain",
+			template: `
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cm
+      data:
+        value: '{{if eq .Value 1}}a{{else if eq .Value 2}}b{{else if eq .Value 3}}c{{end}}'`,
+			limits: options.TemplateLimits{MaxDepth: 1},
+		},
+		{
+			desc:      "error: output size",
+			template:  nested,
+			items:     100,
+			limits:    options.TemplateLimits{MaxOutputBytes: 1000},
+			expLimit:  options.TemplateOutputLimit,
+			expErrStr: `template "data-component-cm" exceeded the output size limit of 1000 bytes`,
+		},
+		{
+			desc:     "error: output size with safe YAML",
+			template: nested,
+			safeYAML: true,
+			items:    100,
+			limits:   options.TemplateLimits{MaxOutputBytes: 1000},
+			expLimit: options.TemplateOutputLimit,
+		},
+		{
+			desc:      "error: depth",
+			template:  nested,
+			items:     10,
+			limits:    options.TemplateLimits{MaxDepth: 1},
+			expLimit:  options.TemplateDepthLimit,
+			expErrStr: "exceeded the nesting depth limit of 1",
+		},
+		{
+			desc: "error: recursive template",
+			template: `
+    template: |
+      {{define "r"}}{{template "r" .}}{{end}}{{template "r" .}}`,
+			limits:   options.TemplateLimits{MaxDepth: 100},
+			expLimit: options.TemplateDepthLimit,
+		},
+		{
+			desc:      "error: timeout",
+			template:  nested,
+			items:     5000,
+			limits:    options.TemplateLimits{Timeout: 10 * time.Millisecond},
+			expLimit:  options.TemplateTimeoutLimit,
+			expErrStr: "exceeded the execution time limit of 10ms",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  componentName: data-component
+  objects:
+  - kind: ObjectTemplate
+    type: go-template
+    metadata:
+      name: cm` + tc.template).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfgs := []ApplierConfig{WithTemplateLimits(tc.limits)}
+			if tc.safeYAML {
+				cfgs = append(cfgs, WithSafeYAMLTemplaterOverride())
+			}
+			opts := options.JSONOptions{"Items": make([]interface{}, tc.items), "Value": 2}
+
+			_, err = NewApplier(cfgs...).ApplyOptions(comp, opts)
+			if tc.expLimit == "" {
+				if err != nil {
+					t.Fatalf("ApplyOptions(): got error %v, expected none", err)
+				}
+				return
+			}
+			var lerr *options.TemplateLimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("ApplyOptions(): got error %v, expected a TemplateLimitError", err)
+			}
+			if lerr.Limit != tc.expLimit {
+				t.Errorf("got limit %q, expected %q", lerr.Limit, tc.expLimit)
+			}
+			if !strings.Contains(err.Error(), tc.expErrStr) {
+				t.Errorf("got error %q, expected it to contain %q", err, tc.expErrStr)
+			}
+		})
+	}
+}
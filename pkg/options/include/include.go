@@ -0,0 +1,262 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package include is an applier that conditionally removes objects from a
+// component based on options.
+//
+// Any object in a component, including ObjectTemplates and PatchTemplates, may
+// have the annotation 'bundle.gke.io/include-if', whose value is an
+// expression. The object is kept only if the expression evaluates to true; if
+// it evaluates to false, the object is removed. The annotation is removed
+// from the objects that are kept. For example:
+//
+//	apiVersion: policy/v1
+//	kind: PodDisruptionBudget
+//	metadata:
+//	  name: etcd-pdb
+//	  annotations:
+//	    bundle.gke.io/include-if: "options.HighAvailability && options.Replicas > 1"
+//
+// Expressions are written in the Common Expression Language (CEL), described
+// at https://github.com/google/cel-spec. The options are available as the
+// variable 'options', a map, after the defaults from the OptionsSchemas of all
+// the templates in the component have been applied. Fields are accessed with
+// 'options.Foo.Bar' or 'options["foo-bar"]'. Accessing an option that isn't
+// set is an error, so optional options should be guarded with
+// 'has(options.Foo)'. Whole numbers in the options are integers, so they can
+// be compared with integer literals.
+//
+// Objects rendered from ObjectTemplates may have the annotation too, if the
+// applier is created WithTemplateAppliers; they're evaluated once rendered,
+// with the same options as the objects in the component.
+//
+// CEL is sandboxed: expressions have no access to the filesystem, network,
+// clock or environment, are guaranteed to terminate, and are further bounded
+// by a cost limit.
+package include
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+)
+
+const (
+	// IncludeIfAnnotation is the annotation containing the expression that
+	// decides whether an object is included.
+	IncludeIfAnnotation = "bundle.gke.io/include-if"
+
+	// DefaultCostLimit is the default bound on the cost of evaluating a single
+	// expression.
+	DefaultCostLimit uint64 = 1000000
+
+	// optionsVar is the name of the variable containing the options.
+	optionsVar = "options"
+)
+
+// ApplierConfig is a config option that can be passed to NewApplier.
+type ApplierConfig func(*applier)
+
+// applier removes objects whose include-if expression is false.
+type applier struct {
+	costLimit uint64
+
+	// templateAppliers render templates into objects, which are then
+	// filtered as well.
+	templateAppliers []options.Applier
+}
+
+// WithCostLimit modifies NewApplier so that the returned Applier bounds the
+// cost of evaluating each expression.
+func WithCostLimit(limit uint64) ApplierConfig {
+	return func(a *applier) {
+		a.costLimit = limit
+	}
+}
+
+// WithTemplateAppliers modifies NewApplier so that the returned Applier, after
+// filtering the objects, applies the given template appliers and then filters
+// the objects they rendered. Rendered objects are evaluated with the same
+// options as the templates, including the defaults from the schemas of the
+// templates, which are no longer in the component once rendered.
+func WithTemplateAppliers(appliers ...options.Applier) ApplierConfig {
+	return func(a *applier) {
+		a.templateAppliers = append(a.templateAppliers, appliers...)
+	}
+}
+
+// NewApplier creates a new options applier instance using the specified
+// ApplierConfigs.
+func NewApplier(opts ...ApplierConfig) options.Applier {
+	a := &applier{costLimit: DefaultCostLimit}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// ApplyOptions evaluates the include-if expression of every object in the
+// component and removes the objects whose expression is false. If there are
+// template appliers, they are then applied and the rendered objects are
+// evaluated in turn.
+func (a *applier) ApplyOptions(comp *bundle.Component, opts options.JSONOptions) (*bundle.Component, error) {
+	f := &filter{costLimit: a.costLimit, comp: comp, opts: opts}
+	comp, err := f.apply(comp.DeepCopy())
+	if err != nil {
+		return nil, err
+	}
+	if len(a.templateAppliers) == 0 {
+		return comp, nil
+	}
+	for _, ta := range a.templateAppliers {
+		comp, err = ta.ApplyOptions(comp, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.apply(comp)
+}
+
+// filter removes objects whose include-if expression is false. The CEL
+// environment and the defaulted options are only created once they're needed,
+// and are then reused for every component the filter is applied to.
+type filter struct {
+	costLimit uint64
+
+	// comp is the component whose schemas default the options. It's the
+	// component before any templates were rendered.
+	comp *bundle.Component
+	opts options.JSONOptions
+
+	env  *cel.Env
+	vars map[string]interface{}
+}
+
+// apply filters the objects of comp in place and returns it.
+func (f *filter) apply(comp *bundle.Component) (*bundle.Component, error) {
+	hasConditions := false
+	for _, obj := range comp.Spec.Objects {
+		if _, ok := obj.GetAnnotations()[IncludeIfAnnotation]; ok {
+			hasConditions = true
+			break
+		}
+	}
+	if !hasConditions {
+		return comp, nil
+	}
+
+	if f.env == nil {
+		opts, err := defaultedOptions(f.comp, f.opts)
+		if err != nil {
+			return nil, err
+		}
+		env, err := cel.NewEnv(cel.Declarations(
+			decls.NewVar(optionsVar, decls.NewMapType(decls.String, decls.Dyn)),
+		))
+		if err != nil {
+			return nil, err
+		}
+		f.env = env
+		f.vars = map[string]interface{}{optionsVar: options.NormalizeNumbers(opts)}
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, obj := range comp.Spec.Objects {
+		annot := obj.GetAnnotations()
+		expr, ok := annot[IncludeIfAnnotation]
+		if !ok {
+			objs = append(objs, obj)
+			continue
+		}
+		include, err := f.evaluate(expr)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating %s annotation %q for object %s %q in component %v: %v",
+				IncludeIfAnnotation, expr, obj.GetKind(), obj.GetName(), comp.ComponentReference(), err)
+		}
+		if !include {
+			continue
+		}
+		delete(annot, IncludeIfAnnotation)
+		if len(annot) == 0 {
+			annot = nil
+		}
+		obj.SetAnnotations(annot)
+		objs = append(objs, obj)
+	}
+	comp.Spec.Objects = objs
+	return comp, nil
+}
+
+// evaluate compiles and evaluates a single boolean expression.
+func (f *filter) evaluate(expr string) (bool, error) {
+	ast, iss := f.env.Compile(expr)
+	if iss.Err() != nil {
+		return false, iss.Err()
+	}
+	prg, err := f.env.Program(ast, cel.CostLimit(f.costLimit))
+	if err != nil {
+		return false, err
+	}
+	val, _, err := prg.Eval(f.vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a bool, but was %v", val.Type())
+	}
+	return bool(b), nil
+}
+
+// defaultedOptions applies the defaults from the merged options schema of the
+// component to the options.
+func defaultedOptions(comp *bundle.Component, opts options.JSONOptions) (options.JSONOptions, error) {
+	if opts == nil {
+		opts = options.JSONOptions{}
+	}
+	schema, _, err := openapi.ComponentSchema(comp)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.Properties) == 0 {
+		return opts, nil
+	}
+	// Only defaulting is wanted here; the templates themselves validate that
+	// required options are present.
+	clearRequired(schema)
+
+	// Defaulting modifies the options in place.
+	opts, err = openapi.ApplyDefaults(options.Merge(opts), schema)
+	if err != nil {
+		return nil, fmt.Errorf("applying schema defaults for component %v: %v", comp.ComponentReference(), err)
+	}
+	return opts, nil
+}
+
+// clearRequired removes the required fields from a schema and its properties.
+func clearRequired(schema *apiextv1beta1.JSONSchemaProps) {
+	schema.Required = nil
+	for k, p := range schema.Properties {
+		clearRequired(&p)
+		schema.Properties[k] = p
+	}
+}
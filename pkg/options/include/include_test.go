@@ -0,0 +1,154 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package include
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+var includeComponent = `
+kind: Component
+spec:
+  componentName: inc
+  objects:
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: always
+  - apiVersion: policy/v1
+    kind: PodDisruptionBudget
+    metadata:
+      name: pdb
+      annotations:
+        bundle.gke.io/include-if: "options.HighAvailability && options.Replicas > 1"
+        keep: me
+  - apiVersion: monitoring.coreos.com/v1
+    kind: ServiceMonitor
+    metadata:
+      name: monitor
+      annotations:
+        bundle.gke.io/include-if: "has(options.Monitoring) && options.Monitoring.Enabled"
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: ObjectTemplate
+    type: go-template
+    metadata:
+      name: tmpl
+      annotations:
+        bundle.gke.io/include-if: "options.Replicas >= 3"
+    optionsSchema:
+      properties:
+        HighAvailability:
+          type: boolean
+          default: true
+        Replicas:
+          type: integer
+    template: "kind: Pod"
+`
+
+func TestApplyOptions(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		opts         string
+		expNames     []string
+		expErrSubstr string
+	}{
+		{
+			desc:     "defaults apply",
+			opts:     "Replicas: 3",
+			expNames: []string{"always", "pdb", "tmpl"},
+		},
+		{
+			desc:     "excluded",
+			opts:     "{Replicas: 1, Monitoring: {Enabled: true}}",
+			expNames: []string{"always", "monitor"},
+		},
+		{
+			desc:     "override default",
+			opts:     "{Replicas: 2, HighAvailability: false}",
+			expNames: []string{"always"},
+		},
+		{
+			desc:         "error: missing option",
+			opts:         "HighAvailability: true",
+			expErrSubstr: "no such key: Replicas",
+		},
+		{
+			desc:         "error: not a bool",
+			opts:         "{Replicas: 1, Monitoring: {Enabled: yes-please}}",
+			expErrSubstr: "no such overload",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(includeComponent).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts, err := converter.FromYAMLString(tc.opts).ToJSONMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newComp, err := NewApplier().ApplyOptions(comp, options.JSONOptions(opts))
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			var names []string
+			for _, obj := range newComp.Spec.Objects {
+				names = append(names, obj.GetName())
+				if _, ok := obj.GetAnnotations()[IncludeIfAnnotation]; ok {
+					t.Errorf("object %q still has the %s annotation", obj.GetName(), IncludeIfAnnotation)
+				}
+				if obj.GetName() == "pdb" && obj.GetAnnotations()["keep"] != "me" {
+					t.Errorf("other annotations should be kept, but got %v", obj.GetAnnotations())
+				}
+			}
+			if !reflect.DeepEqual(names, tc.expNames) {
+				t.Errorf("got objects %v, expected %v", names, tc.expNames)
+			}
+		})
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  objects:
+  - kind: ConfigMap
+    metadata:
+      name: cm
+      annotations:
+        bundle.gke.io/include-if: "options.Items.all(x, options.Items.all(y, x != y || x == y))"
+`).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []interface{}
+	for i := 0; i < 200; i++ {
+		items = append(items, float64(i))
+	}
+	_, err = NewApplier(WithCostLimit(1000)).ApplyOptions(comp, options.JSONOptions{"Items": items})
+	if cerr := testutil.CheckErrorCases(err, "cost limit exceeded"); cerr != nil {
+		t.Error(cerr)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"time"
+)
+
+// TemplateLimits bound the execution of go templates. A zero value for a
+// limit means that it is not enforced.
+type TemplateLimits struct {
+	// Timeout is the maximum time a single template execution may take.
+	Timeout time.Duration
+
+	// MaxOutputBytes is the maximum number of bytes a single template execution
+	// may produce.
+	MaxOutputBytes int
+
+	// MaxDepth is the maximum nesting depth of the actions in a template. Each
+	// if, range, with and template action adds one level, and the actions in a
+	// template invoked with a template action are counted as nested within it.
+	// Recursive templates exceed any depth limit.
+	MaxDepth int
+}
+
+// TemplateLimit identifies one of the TemplateLimits.
+type TemplateLimit string
+
+const (
+	// TemplateTimeoutLimit is the TemplateLimits.Timeout limit.
+	TemplateTimeoutLimit TemplateLimit = "Timeout"
+
+	// TemplateOutputLimit is the TemplateLimits.MaxOutputBytes limit.
+	TemplateOutputLimit TemplateLimit = "MaxOutputBytes"
+
+	// TemplateDepthLimit is the TemplateLimits.MaxDepth limit.
+	TemplateDepthLimit TemplateLimit = "MaxDepth"
+)
+
+// TemplateLimitError is returned when the execution of a template exceeds one
+// of its TemplateLimits.
+type TemplateLimitError struct {
+	// Template is the name of the template.
+	Template string
+
+	// Limit is the limit that was exceeded.
+	Limit TemplateLimit
+
+	// Limits are the limits the template was executed with.
+	Limits TemplateLimits
+}
+
+// Error returns the error message.
+func (e *TemplateLimitError) Error() string {
+	switch e.Limit {
+	case TemplateTimeoutLimit:
+		return fmt.Sprintf("template %q exceeded the execution time limit of %v", e.Template, e.Limits.Timeout)
+	case TemplateOutputLimit:
+		return fmt.Sprintf("template %q exceeded the output size limit of %d bytes", e.Template, e.Limits.MaxOutputBytes)
+	case TemplateDepthLimit:
+		return fmt.Sprintf("template %q exceeded the nesting depth limit of %d", e.Template, e.Limits.MaxDepth)
+	default:
+		return fmt.Sprintf("template %q exceeded the %s limit", e.Template, e.Limit)
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint statically checks Go templates against their options schemas.
+//
+// The linter parses the go-template ObjectTemplates, PatchTemplates and
+// PatchTemplateBuilders in a component and collects the option paths that
+// each template references, such as 'Image.Repo' for '{{.Image.Repo}}'. It
+// reports two kinds of issues:
+//
+//   - A template references an option path that isn't declared in its
+//     schema. This usually indicates a typo that would otherwise only be
+//     found when the template is applied.
+//   - A schema declares a property that no template in the component ever
+//     references.
+//
+// For a PatchTemplateBuilder, the template is checked against both the
+// BuildSchema and the TargetSchema, since the builder passes target options
+// through to the PatchTemplate it builds.
+//
+// The analysis is conservative: references whose path can't be determined
+// statically, such as fields of range elements or of variables bound to
+// function results, are ignored, and templates without a schema aren't
+// checked for missing paths.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+)
+
+// IssueType is the type of a lint issue.
+type IssueType string
+
+const (
+	// MissingFromSchema indicates that a template references an option path
+	// that isn't declared in its schema.
+	MissingFromSchema IssueType = "MissingFromSchema"
+
+	// UnusedProperty indicates that a schema declares a property that no
+	// template references.
+	UnusedProperty IssueType = "UnusedProperty"
+)
+
+// Issue is a single problem found by the linter.
+type Issue struct {
+	// Type of the issue.
+	Type IssueType
+
+	// Component is the name of the component containing the templates.
+	Component string
+
+	// Path is the dot-separated option path.
+	Path string
+
+	// Templates identifies the templates involved, in the form Kind/name. For
+	// MissingFromSchema, this is the template with the reference; for
+	// UnusedProperty, these are the templates whose schemas declare the
+	// property.
+	Templates []string
+}
+
+// String returns the string form of the issue.
+func (i *Issue) String() string {
+	switch i.Type {
+	case MissingFromSchema:
+		return fmt.Sprintf("component %q: %s references option %q, which is not declared in its schema", i.Component, strings.Join(i.Templates, ", "), i.Path)
+	default:
+		return fmt.Sprintf("component %q: option %q is declared by %s but not used by any template", i.Component, i.Path, strings.Join(i.Templates, ", "))
+	}
+}
+
+// template is a parsed template and its schema.
+type template struct {
+	source string
+	schema *apiextv1beta1.JSONSchemaProps
+	refs   []string
+}
+
+// Bundle lints every component in a bundle.
+func Bundle(bun *bundle.Bundle) ([]*Issue, error) {
+	var out []*Issue
+	for _, comp := range bun.Components {
+		issues, err := Component(comp)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, issues...)
+	}
+	return out, nil
+}
+
+// Component lints the templates in a component. The issues are sorted by type
+// and then by path.
+func Component(comp *bundle.Component) ([]*Issue, error) {
+	name := comp.Spec.ComponentName
+	tmpls, err := parseTemplates(comp.Spec.Objects)
+	if err != nil {
+		return nil, fmt.Errorf("for component %v: %v", comp.ComponentReference(), err)
+	}
+
+	var issues []*Issue
+	var allRefs []string
+	for _, t := range tmpls {
+		allRefs = append(allRefs, t.refs...)
+		if t.schema == nil {
+			continue
+		}
+		for _, ref := range t.refs {
+			if !declared(t.schema, strings.Split(ref, ".")) {
+				issues = append(issues, &Issue{
+					Type:      MissingFromSchema,
+					Component: name,
+					Path:      ref,
+					Templates: []string{t.source},
+				})
+			}
+		}
+	}
+
+	unused := make(map[string][]string)
+	for _, t := range tmpls {
+		if t.schema == nil {
+			continue
+		}
+		for _, p := range unusedProperties(t.schema, "", allRefs) {
+			unused[p] = append(unused[p], t.source)
+		}
+	}
+	for p, sources := range unused {
+		issues = append(issues, &Issue{
+			Type:      UnusedProperty,
+			Component: name,
+			Path:      p,
+			Templates: sources,
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Type != issues[j].Type {
+			return issues[i].Type < issues[j].Type
+		}
+		return issues[i].Path < issues[j].Path
+	})
+	return issues, nil
+}
+
+// parseTemplates parses the Go templates in a list of objects, collecting the
+// option paths that each references.
+func parseTemplates(objs []*unstructured.Unstructured) ([]*template, error) {
+	var out []*template
+	for _, obj := range objs {
+		var text string
+		var schema *apiextv1beta1.JSONSchemaProps
+		switch obj.GetKind() {
+		case "ObjectTemplate":
+			tmpl := &bundle.ObjectTemplate{}
+			if err := converter.FromUnstructured(obj).ToObject(tmpl); err != nil {
+				return nil, fmt.Errorf("while converting object %q to ObjectTemplate: %v", obj.GetName(), err)
+			}
+			if tmpl.Type != bundle.TemplateTypeGo {
+				continue
+			}
+			text, schema = tmpl.Template, tmpl.OptionsSchema
+		case "PatchTemplate":
+			tmpl := &bundle.PatchTemplate{}
+			if err := converter.FromUnstructured(obj).ToObject(tmpl); err != nil {
+				return nil, fmt.Errorf("while converting object %q to PatchTemplate: %v", obj.GetName(), err)
+			}
+			text, schema = tmpl.Template, tmpl.OptionsSchema
+		case "PatchTemplateBuilder":
+			tmpl := &bundle.PatchTemplateBuilder{}
+			if err := converter.FromUnstructured(obj).ToObject(tmpl); err != nil {
+				return nil, fmt.Errorf("while converting object %q to PatchTemplateBuilder: %v", obj.GetName(), err)
+			}
+			text, schema = tmpl.Template, builderSchema(tmpl)
+		default:
+			continue
+		}
+
+		source := obj.GetKind() + "/" + obj.GetName()
+		refs, err := References(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %s: %v", source, err)
+		}
+		out = append(out, &template{source: source, schema: schema, refs: refs})
+	}
+	return out, nil
+}
+
+// builderSchema returns the combined build and target schemas of a
+// PatchTemplateBuilder, or nil if it has neither.
+func builderSchema(ptb *bundle.PatchTemplateBuilder) *apiextv1beta1.JSONSchemaProps {
+	var schemas []*openapi.TemplateSchema
+	for _, s := range []*apiextv1beta1.JSONSchemaProps{ptb.BuildSchema, ptb.TargetSchema} {
+		if s != nil {
+			schemas = append(schemas, &openapi.TemplateSchema{Kind: "PatchTemplateBuilder", Name: ptb.GetName(), Schema: s})
+		}
+	}
+	if len(schemas) == 0 {
+		return nil
+	}
+	merged, _ := openapi.MergeSchemas(schemas)
+	return merged
+}
+
+// declared returns whether an option path is declared in a schema. Paths
+// below a schema that allows arbitrary properties are always declared.
+func declared(schema *apiextv1beta1.JSONSchemaProps, path []string) bool {
+	if len(path) == 0 || path[0] == "" {
+		return true
+	}
+	if openObject(schema) {
+		return true
+	}
+	prop, ok := schema.Properties[path[0]]
+	if !ok {
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Allows {
+			if schema.AdditionalProperties.Schema == nil {
+				return true
+			}
+			return declared(schema.AdditionalProperties.Schema, path[1:])
+		}
+		return false
+	}
+	return declared(&prop, path[1:])
+}
+
+// openObject returns whether a schema places no constraints on the names of
+// its properties.
+func openObject(schema *apiextv1beta1.JSONSchemaProps) bool {
+	if schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+		return true
+	}
+	// An object without declared properties, or a schema without a type, is
+	// treated as free-form.
+	return len(schema.Properties) == 0 && schema.AdditionalProperties == nil && (schema.Type == "" || schema.Type == "object")
+}
+
+// unusedProperties returns the paths of the properties in a schema that are
+// not covered by any reference. A reference covers a property if it refers to
+// the property, to one of its parents, or to one of its children. Only the
+// top-most unused property of a subtree is returned.
+func unusedProperties(schema *apiextv1beta1.JSONSchemaProps, prefix string, refs []string) []string {
+	var out []string
+	for k, prop := range schema.Properties {
+		p := k
+		if prefix != "" {
+			p = prefix + "." + k
+		}
+		covered, partial := false, false
+		for _, ref := range refs {
+			if ref == "" || ref == p || strings.HasPrefix(p, ref+".") {
+				covered = true
+				break
+			}
+			if strings.HasPrefix(ref, p+".") {
+				partial = true
+			}
+		}
+		switch {
+		case covered:
+		case partial:
+			out = append(out, unusedProperties(&prop, p, refs)...)
+		default:
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// References parses a Go template and returns the sorted, de-duplicated option
+// paths that it references. The empty path indicates that the template refers
+// to the options as a whole, as in '{{toYaml .}}'. Functions aren't checked,
+// so templates may use functions that are only available at apply time.
+func References(text string) ([]string, error) {
+	trees := make(map[string]*parse.Tree)
+	t := parse.New("lint")
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(text, "", "", trees); err != nil {
+		return nil, err
+	}
+
+	c := &collector{refs: make(map[string]bool)}
+	for _, tree := range trees {
+		if tree.Root == nil {
+			continue
+		}
+		// Defined templates are assumed to be invoked with the options.
+		c.walk(tree.Root, rootPath(), map[string]*optPath{})
+	}
+
+	var out []string
+	for r := range c.refs {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// optPath is an option path that a template value is known to refer to. A nil
+// *optPath means the value is not known to come from the options.
+type optPath struct {
+	elems []string
+}
+
+func rootPath() *optPath {
+	return &optPath{}
+}
+
+// child returns the path extended with more elements.
+func (p *optPath) child(elems ...string) *optPath {
+	if p == nil {
+		return nil
+	}
+	return &optPath{elems: append(append([]string{}, p.elems...), elems...)}
+}
+
+// collector collects references while walking template parse trees.
+type collector struct {
+	refs map[string]bool
+}
+
+// add records a reference to the path.
+func (c *collector) add(p *optPath) {
+	if p != nil {
+		c.refs[strings.Join(p.elems, ".")] = true
+	}
+}
+
+// walk walks a node, where dot is the path of the template's dot and vars
+// are the paths of variables in scope.
+func (c *collector) walk(node parse.Node, dot *optPath, vars map[string]*optPath) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, dot, vars)
+		}
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, dot, vars)
+	case *parse.IfNode:
+		c.pipe(n.Pipe, dot, vars)
+		c.walk(n.List, dot, copyVars(vars))
+		c.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.WithNode:
+		inner := copyVars(vars)
+		p := c.pipe(n.Pipe, dot, inner)
+		c.walk(n.List, p, inner)
+		c.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.RangeNode:
+		inner := copyVars(vars)
+		c.pipe(n.Pipe, dot, inner)
+		// The range element isn't a fixed option path.
+		for _, d := range n.Pipe.Decl {
+			inner[d.Ident[0]] = nil
+		}
+		c.walk(n.List, nil, inner)
+		c.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.TemplateNode:
+		c.pipe(n.Pipe, dot, vars)
+	}
+}
+
+// pipe walks a pipeline, returning the option path that the pipeline
+// evaluates to, if known. Variables declared by the pipeline are added to
+// vars.
+func (c *collector) pipe(pipe *parse.PipeNode, dot *optPath, vars map[string]*optPath) *optPath {
+	if pipe == nil {
+		return nil
+	}
+	var result *optPath
+	for i, cmd := range pipe.Cmds {
+		p := c.command(cmd, dot, vars)
+		if i == len(pipe.Cmds)-1 {
+			result = p
+		}
+	}
+	for _, d := range pipe.Decl {
+		vars[d.Ident[0]] = result
+	}
+	return result
+}
+
+// command walks a command, returning the option path that it evaluates to, if
+// known.
+func (c *collector) command(cmd *parse.CommandNode, dot *optPath, vars map[string]*optPath) *optPath {
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+	// {{index .Foo "bar" "baz"}} refers to Foo.bar.baz.
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && id.Ident == "index" && len(cmd.Args) > 1 {
+		p := c.arg(cmd.Args[1], dot, vars)
+		for _, a := range cmd.Args[2:] {
+			s, ok := a.(*parse.StringNode)
+			if !ok {
+				c.add(p)
+				p = nil
+				c.arg(a, dot, vars)
+				continue
+			}
+			p = p.child(s.Text)
+		}
+		c.add(p)
+		return p
+	}
+
+	var result *optPath
+	for _, a := range cmd.Args {
+		p := c.arg(a, dot, vars)
+		c.add(p)
+		if len(cmd.Args) == 1 {
+			result = p
+		}
+	}
+	return result
+}
+
+// arg returns the option path that an argument refers to, if known, walking
+// any nested pipelines.
+func (c *collector) arg(node parse.Node, dot *optPath, vars map[string]*optPath) *optPath {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return dot.child(n.Ident...)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			return rootPath().child(n.Ident[1:]...)
+		}
+		return vars[n.Ident[0]].child(n.Ident[1:]...)
+	case *parse.ChainNode:
+		return c.arg(n.Node, dot, vars).child(n.Field...)
+	case *parse.PipeNode:
+		return c.pipe(n, dot, copyVars(vars))
+	}
+	return nil
+}
+
+// copyVars copies variables for a new scope.
+func copyVars(vars map[string]*optPath) map[string]*optPath {
+	out := make(map[string]*optPath, len(vars))
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+)
+
+func TestReferences(t *testing.T) {
+	testCases := []struct {
+		desc string
+		tmpl string
+		exp  []string
+	}{
+		{
+			desc: "fields",
+			tmpl: `name: {{.Name}}
+image: {{.Image.Repo}}:{{.Image.Tag}}`,
+			exp: []string{"Image.Repo", "Image.Tag", "Name"},
+		},
+		{
+			desc: "with and if",
+			tmpl: `{{if .Enabled}}{{with .Image}}{{.Repo}}{{$.Name}}{{end}}{{end}}`,
+			exp:  []string{"Enabled", "Image", "Image.Repo", "Name"},
+		},
+		{
+			desc: "range elements are ignored",
+			tmpl: `{{range .Hosts}}{{.Name}}{{$.Domain}}{{end}}`,
+			exp:  []string{"Domain", "Hosts"},
+		},
+		{
+			desc: "variables, index and functions",
+			tmpl: `{{$img := .Image}}{{$img.Repo}}{{index .Labels "app"}}{{printf "%s" .Namespace | quote}}`,
+			exp:  []string{"Image", "Image.Repo", "Labels.app", "Namespace"},
+		},
+		{
+			desc: "whole options",
+			tmpl: `{{toYaml .}}`,
+			exp:  []string{""},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := References(tc.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got references %q, expected %q", got, tc.exp)
+			}
+		})
+	}
+}
+
+var lintComponent = `
+kind: Component
+spec:
+  componentName: lint-comp
+  objects:
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: ObjectTemplate
+    metadata:
+      name: pod
+    type: go-template
+    template: |
+      kind: Pod
+      metadata:
+        namespace: {{.Namespce}}
+      spec:
+        image: {{.Image.Repo}}
+    optionsSchema:
+      properties:
+        Namespace:
+          type: string
+        Image:
+          type: object
+          properties:
+            Repo:
+              type: string
+            Tag:
+              type: string
+        Extra:
+          type: object
+          x-kubernetes-preserve-unknown-fields: true
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: PatchTemplate
+    metadata:
+      name: patch
+    template: |
+      metadata:
+        labels: {{.Extra.labels}}
+    optionsSchema:
+      properties:
+        Extra:
+          type: object
+          x-kubernetes-preserve-unknown-fields: true
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: PatchTemplateBuilder
+    metadata:
+      name: builder
+    template: |
+      spec:
+        replicas: {{.BuildReplicas}}
+        cpu: {{.CPU}}
+    buildSchema:
+      properties:
+        BuildReplicas:
+          type: integer
+        Unused:
+          type: string
+    targetSchema:
+      properties:
+        CPU:
+          type: string
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: ObjectTemplate
+    metadata:
+      name: starlark
+    type: starlark
+    template: "objects = [options['Whatever']]"
+`
+
+func TestComponent(t *testing.T) {
+	comp, err := converter.FromYAMLString(lintComponent).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Component(comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	exp := []string{
+		`component "lint-comp": ObjectTemplate/pod references option "Namespce", which is not declared in its schema`,
+		`component "lint-comp": option "Image.Tag" is declared by ObjectTemplate/pod but not used by any template`,
+		`component "lint-comp": option "Namespace" is declared by ObjectTemplate/pod but not used by any template`,
+		`component "lint-comp": option "Unused" is declared by PatchTemplateBuilder/builder but not used by any template`,
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got issues\n%q\nexpected\n%q", got, exp)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  componentName: bad
+  objects:
+  - kind: PatchTemplate
+    metadata:
+      name: bad
+    template: "{{.Foo"
+`).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Component(comp); err == nil {
+		t.Error("expected parse error")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Merge deep-merges several options into a new JSONOptions, where values in
+// later options take precedence over values in earlier options. The inputs are
+// not modified.
+//
+// Merging follows these rules:
+//
+//   - If both values for a key are maps, they are merged recursively.
+//   - Otherwise, the later value replaces the earlier value. In particular,
+//     lists are never merged or appended: a later list replaces an earlier
+//     list in its entirety, as does a later scalar replace an earlier map.
+//   - An explicit null replaces the earlier value with null.
+func Merge(opts ...JSONOptions) JSONOptions {
+	out := JSONOptions{}
+	for _, o := range opts {
+		mergeMaps(out, o)
+	}
+	return out
+}
+
+// mergeMaps merges src into dst, copying maps from src so that dst never
+// shares maps with src.
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := asMap(v)
+		if !srcIsMap {
+			dst[k] = v
+			continue
+		}
+		dstMap, dstIsMap := asMap(dst[k])
+		if !dstIsMap {
+			dstMap = make(map[string]interface{})
+		}
+		mergeMaps(dstMap, srcMap)
+		dst[k] = dstMap
+	}
+}
+
+// asMap returns a value as a map, if it is one.
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case JSONOptions:
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// SplitPath splits a dot-separated option path, such as "foo.bar.biff", into
+// its elements. A literal dot in a key may be escaped with a backslash, as in
+// "annotations.example\.com/foo".
+func SplitPath(path string) []string {
+	var out []string
+	var cur strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '\\' && i+1 < len(path) && path[i+1] == '.' {
+			cur.WriteByte('.')
+			i++
+			continue
+		}
+		if c == '.' {
+			out = append(out, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	return append(out, cur.String())
+}
+
+// SetPath sets a value in the options at the given path, creating
+// intermediate maps as necessary. It is an error if an intermediate value
+// exists and isn't a map.
+func SetPath(opts JSONOptions, path []string, val interface{}) error {
+	if len(path) == 0 {
+		return fmt.Errorf("option path was empty")
+	}
+	cur := map[string]interface{}(opts)
+	for i, key := range path[:len(path)-1] {
+		if key == "" {
+			return fmt.Errorf("empty key in option path %q", strings.Join(path, "."))
+		}
+		next, ok := cur[key]
+		if !ok || next == nil {
+			m := make(map[string]interface{})
+			cur[key] = m
+			cur = m
+			continue
+		}
+		m, isMap := asMap(next)
+		if !isMap {
+			return fmt.Errorf("cannot set option %q: %q is a %T, not a map", strings.Join(path, "."), strings.Join(path[:i+1], "."), next)
+		}
+		cur = m
+	}
+	last := path[len(path)-1]
+	if last == "" {
+		return fmt.Errorf("empty key in option path %q", strings.Join(path, "."))
+	}
+	cur[last] = val
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestMerge(t *testing.T) {
+	testCases := []struct {
+		desc string
+		opts []JSONOptions
+		exp  JSONOptions
+	}{
+		{
+			desc: "no options",
+			exp:  JSONOptions{},
+		},
+		{
+			desc: "nested maps merge",
+			opts: []JSONOptions{
+				{"a": map[string]interface{}{"b": 1, "c": map[string]interface{}{"d": 2}}},
+				{"a": map[string]interface{}{"c": map[string]interface{}{"e": 3}}},
+			},
+			exp: JSONOptions{"a": map[string]interface{}{"b": 1, "c": map[string]interface{}{"d": 2, "e": 3}}},
+		},
+		{
+			desc: "lists are replaced",
+			opts: []JSONOptions{
+				{"a": []interface{}{1, 2}},
+				{"a": []interface{}{3}},
+			},
+			exp: JSONOptions{"a": []interface{}{3}},
+		},
+		{
+			desc: "scalar replaces map",
+			opts: []JSONOptions{
+				{"a": map[string]interface{}{"b": 1}},
+				{"a": "foo"},
+			},
+			exp: JSONOptions{"a": "foo"},
+		},
+		{
+			desc: "null replaces value",
+			opts: []JSONOptions{
+				{"a": "foo"},
+				{"a": nil},
+			},
+			exp: JSONOptions{"a": nil},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := Merge(tc.opts...)
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got %v, expected %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotModifyInputs(t *testing.T) {
+	a := JSONOptions{"a": map[string]interface{}{"b": 1}}
+	b := JSONOptions{"a": map[string]interface{}{"c": 2}}
+	Merge(a, b)
+	if exp := (JSONOptions{"a": map[string]interface{}{"b": 1}}); !reflect.DeepEqual(a, exp) {
+		t.Errorf("first input was modified to %v", a)
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		opts         JSONOptions
+		path         string
+		val          interface{}
+		exp          JSONOptions
+		expErrSubstr string
+	}{
+		{
+			desc: "create nested",
+			opts: JSONOptions{},
+			path: "a.b.c",
+			val:  "foo",
+			exp:  JSONOptions{"a": map[string]interface{}{"b": map[string]interface{}{"c": "foo"}}},
+		},
+		{
+			desc: "escaped dot",
+			opts: JSONOptions{"a": map[string]interface{}{"x": 1}},
+			path: `a.example\.com/foo`,
+			val:  true,
+			exp:  JSONOptions{"a": map[string]interface{}{"x": 1, "example.com/foo": true}},
+		},
+		{
+			desc:         "error: intermediate is not a map",
+			opts:         JSONOptions{"a": "foo"},
+			path:         "a.b",
+			val:          1,
+			expErrSubstr: "not a map",
+		},
+		{
+			desc:         "error: empty key",
+			opts:         JSONOptions{},
+			path:         "a..b",
+			val:          1,
+			expErrSubstr: "empty key",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := SetPath(tc.opts, SplitPath(tc.path), tc.val)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(tc.opts, tc.exp) {
+				t.Errorf("got %v, expected %v", tc.opts, tc.exp)
+			}
+		})
+	}
+}
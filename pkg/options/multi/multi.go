@@ -20,7 +20,10 @@ import (
 	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/gotmpl"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/include"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/patchtmpl"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/replacetmpl"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/starlarktmpl"
 )
 
 type applier struct {
@@ -32,11 +35,22 @@ func NewApplier(appliers []options.Applier) options.Applier {
 	return &applier{appliers: appliers[:]}
 }
 
-// NewDefaultApplier creates a default multi-applier
+// NewDefaultApplier creates a default multi-applier. Objects and templates
+// are first included or excluded based on their include-if annotations, so
+// that excluded templates are never rendered. The objects rendered from
+// ObjectTemplates are then included or excluded in the same way.
+//
+// ReplacementTemplates are applied after ObjectTemplates are rendered, either
+// before or after PatchTemplates, depending on their phase.
 func NewDefaultApplier() options.Applier {
 	return NewApplier([]options.Applier{
-		gotmpl.NewApplier(),
+		include.NewApplier(include.WithTemplateAppliers(
+			gotmpl.NewApplier(),
+			starlarktmpl.NewApplier(),
+		)),
+		replacetmpl.NewApplier(replacetmpl.WithPhase(bundle.ReplacementPhaseBeforePatches)),
 		patchtmpl.NewDefaultApplier(),
+		replacetmpl.NewApplier(replacetmpl.WithPhase(bundle.ReplacementPhaseAfterPatches)),
 	})
 }
 
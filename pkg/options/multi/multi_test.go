@@ -98,3 +98,58 @@ spec:
 		t.Errorf("got component\n%s", newCompStr)
 	}
 }
+
+func TestMultiApply_IncludeRendered(t *testing.T) {
+	component := `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: go-template
+    optionsSchema:
+      properties:
+        Monitoring:
+          type: boolean
+          default: true
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: always
+      ---
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: monitoring
+        annotations:
+          bundle.gke.io/include-if: "options.Monitoring"
+      ---
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: ha
+        annotations:
+          bundle.gke.io/include-if: "has(options.HA) && options.HA"
+`
+
+	comp, err := converter.FromYAMLString(component).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newComp, err := NewDefaultApplier().ApplyOptions(comp, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, obj := range newComp.Spec.Objects {
+		names = append(names, obj.GetName())
+		if _, ok := obj.GetAnnotations()["bundle.gke.io/include-if"]; ok {
+			t.Errorf("object %q still has the include-if annotation", obj.GetName())
+		}
+	}
+	if got, exp := strings.Join(names, ","), "always,monitoring"; got != exp {
+		t.Errorf("got objects %q, expected %q", got, exp)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "math"
+
+// NormalizeNumbers returns a copy of a JSON value where whole numbers are
+// int64 rather than float64. Options decoded from YAML or JSON represent all
+// numbers as float64, but CEL requires integers to be int64, as they are for
+// custom resources.
+func NormalizeNumbers(val interface{}) interface{} {
+	switch v := val.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < (1<<53) {
+			return int64(v)
+		}
+		return v
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			out = append(out, NormalizeNumbers(e))
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = NormalizeNumbers(e)
+		}
+		return out
+	case JSONOptions:
+		return NormalizeNumbers(map[string]interface{}(v))
+	default:
+		return val
+	}
+}
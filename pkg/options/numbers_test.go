@@ -0,0 +1,41 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeNumbers(t *testing.T) {
+	opts := JSONOptions{
+		"Replicas": float64(3),
+		"Ratio":    0.5,
+		"Ports":    []interface{}{float64(80), float64(443)},
+		"Image":    map[string]interface{}{"Tag": "v1", "Size": float64(1 << 60)},
+	}
+	exp := map[string]interface{}{
+		"Replicas": int64(3),
+		"Ratio":    0.5,
+		"Ports":    []interface{}{int64(80), int64(443)},
+		"Image":    map[string]interface{}{"Tag": "v1", "Size": float64(1 << 60)},
+	}
+	if got := NormalizeNumbers(opts); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %v, expected %v", got, exp)
+	}
+	if opts["Replicas"] != float64(3) {
+		t.Errorf("the options were modified: %v", opts)
+	}
+}
@@ -15,17 +15,27 @@
 package openapi
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/go-openapi/validate/post"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 )
 
-// ApplyDefaults adds defaults to options, using an OpenAPI schema.
+// ApplyDefaults adds defaults to options, using an OpenAPI schema. Any CEL
+// rules in the schema are evaluated against the defaulted options.
 func ApplyDefaults(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSchemaProps) (options.JSONOptions, error) {
-	res, err := ValidateOptions(opts, optSchema)
+	if optSchema == nil || opts == nil {
+		return opts, nil
+	}
+	intOptSchema, err := toInternal(optSchema)
+	if err != nil {
+		return nil, err
+	}
+	res, err := validateOpenAPI(opts, intOptSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -39,5 +49,36 @@ func ApplyDefaults(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSchema
 		return nil, fmt.Errorf("Could not convert option data to map[string]interface{}. was: %v", data)
 	}
 
+	ruleErrs, err := validateRules(jsonData, intOptSchema, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleErrs) > 0 {
+		return nil, rulesError(ruleErrs)
+	}
+
 	return jsonData, nil
 }
+
+// defaultedCopy returns a copy of the options with the schema defaults
+// applied, leaving the options themselves unchanged.
+func defaultedCopy(opts options.JSONOptions, intOptSchema *apiextensions.JSONSchemaProps) (map[string]interface{}, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var cp map[string]interface{}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	res, err := validateOpenAPI(cp, intOptSchema)
+	if err != nil {
+		return nil, err
+	}
+	post.ApplyDefaults(res)
+	defaulted, ok := res.Data().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Could not convert option data to map[string]interface{}. was: %v", res.Data())
+	}
+	return defaulted, nil
+}
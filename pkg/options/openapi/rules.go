@@ -0,0 +1,102 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateRules evaluates the CEL rules declared in the schema with
+// x-kubernetes-validations against the options. Schemas with rules must be
+// structural, as for CRDs; an object schema that lists properties but omits
+// its type is treated as type 'object'.
+func validateRules(opts map[string]interface{}, intOptSchema *apiextensions.JSONSchemaProps, fldPath *field.Path) (field.ErrorList, error) {
+	if !hasRules(intOptSchema) {
+		return nil, nil
+	}
+	s := intOptSchema.DeepCopy()
+	inferObjectTypes(s)
+	ss, err := structuralschema.NewStructural(s)
+	if err != nil {
+		return nil, fmt.Errorf("schemas with x-kubernetes-validations must be structural: %v", err)
+	}
+	if serrs := structuralschema.ValidateStructural(nil, ss); len(serrs) > 0 {
+		return nil, fmt.Errorf("schemas with x-kubernetes-validations must be structural: %v", serrs.ToAggregate())
+	}
+
+	validator := cel.NewValidator(ss, cel.PerCallLimit)
+	errs, _ := validator.Validate(context.Background(), fldPath, ss, options.NormalizeNumbers(opts), nil, cel.RuntimeCELCostBudget)
+	return errs, nil
+}
+
+// rulesError combines rule violations into a single error, with the field
+// paths rooted at 'options'.
+func rulesError(ruleErrs field.ErrorList) error {
+	errs := field.ErrorList{}
+	for _, rerr := range ruleErrs {
+		errs = append(errs, prefixFieldError(field.NewPath("options"), rerr))
+	}
+	return errs.ToAggregate()
+}
+
+// hasRules returns whether a schema or any of its children declare CEL rules.
+func hasRules(s *apiextensions.JSONSchemaProps) bool {
+	if s == nil {
+		return false
+	}
+	if len(s.XValidations) > 0 {
+		return true
+	}
+	for _, p := range s.Properties {
+		if hasRules(&p) {
+			return true
+		}
+	}
+	if s.Items != nil && hasRules(s.Items.Schema) {
+		return true
+	}
+	if s.AdditionalProperties != nil && hasRules(s.AdditionalProperties.Schema) {
+		return true
+	}
+	return false
+}
+
+// inferObjectTypes sets the type of schemas that have properties but no type
+// to 'object'. Options schemas commonly omit the type at the root.
+func inferObjectTypes(s *apiextensions.JSONSchemaProps) {
+	if s == nil {
+		return
+	}
+	if s.Type == "" && !s.XIntOrString && (len(s.Properties) > 0 || s.AdditionalProperties != nil) {
+		s.Type = "object"
+	}
+	for k, p := range s.Properties {
+		inferObjectTypes(&p)
+		s.Properties[k] = p
+	}
+	if s.Items != nil {
+		inferObjectTypes(s.Items.Schema)
+	}
+	if s.AdditionalProperties != nil {
+		inferObjectTypes(s.AdditionalProperties.Schema)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+)
+
+// TemplateSchema is an options schema along with the template that declared
+// it.
+type TemplateSchema struct {
+	// Kind of the template object, such as ObjectTemplate or PatchTemplate.
+	Kind string
+
+	// Name of the template object.
+	Name string
+
+	// Schema is the OptionsSchema declared by the template.
+	Schema *apiextv1beta1.JSONSchemaProps
+}
+
+// Source returns a human readable identifier for the template.
+func (t *TemplateSchema) Source() string {
+	return t.Kind + "/" + t.Name
+}
+
+// SchemaConflict describes an option property that was declared
+// inconsistently by two or more templates.
+type SchemaConflict struct {
+	// Path is the dot-separated path of the property in the options.
+	Path string
+
+	// Reason describes what differs between the definitions.
+	Reason string
+
+	// Sources identifies the templates that declared the property.
+	Sources []string
+}
+
+// String returns the string form of the conflict.
+func (c *SchemaConflict) String() string {
+	return fmt.Sprintf("option %q: %s (declared by %s)", c.Path, c.Reason, strings.Join(c.Sources, ", "))
+}
+
+// ComponentTemplateSchemas returns the options schemas of all the
+// ObjectTemplates and PatchTemplates in a component, in object order.
+// Templates without an OptionsSchema are skipped.
+func ComponentTemplateSchemas(comp *bundle.Component) ([]*TemplateSchema, error) {
+	return TemplateSchemas(comp.Spec.Objects)
+}
+
+// TemplateSchemas returns the options schemas of all the ObjectTemplates and
+// PatchTemplates in a list of objects. Other objects and templates without an
+// OptionsSchema are skipped.
+func TemplateSchemas(objs []*unstructured.Unstructured) ([]*TemplateSchema, error) {
+	var out []*TemplateSchema
+	for _, obj := range objs {
+		var schema *apiextv1beta1.JSONSchemaProps
+		switch obj.GetKind() {
+		case "ObjectTemplate":
+			tmpl := &bundle.ObjectTemplate{}
+			if err := converter.FromUnstructured(obj).ToObject(tmpl); err != nil {
+				return nil, fmt.Errorf("while converting object %q to ObjectTemplate: %v", obj.GetName(), err)
+			}
+			schema = tmpl.OptionsSchema
+		case "PatchTemplate":
+			tmpl := &bundle.PatchTemplate{}
+			if err := converter.FromUnstructured(obj).ToObject(tmpl); err != nil {
+				return nil, fmt.Errorf("while converting object %q to PatchTemplate: %v", obj.GetName(), err)
+			}
+			schema = tmpl.OptionsSchema
+		}
+		if schema == nil {
+			continue
+		}
+		out = append(out, &TemplateSchema{
+			Kind:   obj.GetKind(),
+			Name:   obj.GetName(),
+			Schema: schema,
+		})
+	}
+	return out, nil
+}
+
+// ComponentSchema merges all the options schemas in a component into a single
+// object schema. Conflicting definitions of the same property are returned
+// alongside the merged schema; for conflicting properties, the first
+// definition wins.
+func ComponentSchema(comp *bundle.Component) (*apiextv1beta1.JSONSchemaProps, []*SchemaConflict, error) {
+	schemas, err := ComponentTemplateSchemas(comp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("for component %v: %v", comp.ComponentReference(), err)
+	}
+	merged, conflicts := MergeSchemas(schemas)
+	return merged, conflicts, nil
+}
+
+// BundleSchemas merges the options schemas for each component in a bundle,
+// returning the merged schemas keyed by component name. The returned
+// conflicts are prefixed with the component name.
+func BundleSchemas(bun *bundle.Bundle) (map[string]*apiextv1beta1.JSONSchemaProps, []*SchemaConflict, error) {
+	out := make(map[string]*apiextv1beta1.JSONSchemaProps)
+	var conflicts []*SchemaConflict
+	for _, comp := range bun.Components {
+		name := comp.Spec.ComponentName
+		if _, ok := out[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate component name %q in bundle", name)
+		}
+		merged, cfs, err := ComponentSchema(comp)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range cfs {
+			c.Path = name + ":" + c.Path
+		}
+		out[name] = merged
+		conflicts = append(conflicts, cfs...)
+	}
+	return out, conflicts, nil
+}
+
+// MergeSchemas merges the given schemas into a single object schema.
+// Properties are merged recursively; required fields are unioned. When two
+// schemas declare the same property with a different type or a different
+// default, a SchemaConflict is recorded and the first definition is kept.
+func MergeSchemas(schemas []*TemplateSchema) (*apiextv1beta1.JSONSchemaProps, []*SchemaConflict) {
+	m := &schemaMerger{sources: make(map[string]string)}
+	out := &apiextv1beta1.JSONSchemaProps{Type: "object"}
+	for _, ts := range schemas {
+		m.mergeInto(out, ts.Schema.DeepCopy(), "", ts.Source())
+	}
+	sort.Slice(m.conflicts, func(i, j int) bool {
+		return m.conflicts[i].Path < m.conflicts[j].Path
+	})
+	return out, m.conflicts
+}
+
+// schemaMerger keeps track of state while merging schemas.
+type schemaMerger struct {
+	// sources records which template first declared each property path.
+	sources map[string]string
+
+	conflicts []*SchemaConflict
+}
+
+// mergeInto merges the properties of src into dst, where both are object
+// schemas located at path.
+func (m *schemaMerger) mergeInto(dst, src *apiextv1beta1.JSONSchemaProps, path, source string) {
+	dst.Required = unionStrings(dst.Required, src.Required)
+	if src.AdditionalProperties != nil && dst.AdditionalProperties == nil {
+		dst.AdditionalProperties = src.AdditionalProperties
+	}
+	if len(src.Properties) == 0 {
+		return
+	}
+	if dst.Properties == nil {
+		dst.Properties = make(map[string]apiextv1beta1.JSONSchemaProps)
+	}
+
+	// Iterate in sorted order so that conflict reporting is deterministic.
+	var keys []string
+	for k := range src.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		srcProp := src.Properties[k]
+		propPath := k
+		if path != "" {
+			propPath = path + "." + k
+		}
+		dstProp, ok := dst.Properties[k]
+		if !ok {
+			dst.Properties[k] = srcProp
+			m.recordSources(&srcProp, propPath, source)
+			continue
+		}
+		if reason := conflictReason(&dstProp, &srcProp); reason != "" {
+			m.conflicts = append(m.conflicts, &SchemaConflict{
+				Path:    propPath,
+				Reason:  reason,
+				Sources: []string{m.sources[propPath], source},
+			})
+			continue
+		}
+		if dstProp.Type == "" {
+			dstProp.Type = srcProp.Type
+		}
+		if dstProp.Default == nil {
+			dstProp.Default = srcProp.Default
+		}
+		if dstProp.Description == "" {
+			dstProp.Description = srcProp.Description
+		}
+		if len(srcProp.Properties) > 0 || len(srcProp.Required) > 0 {
+			m.mergeInto(&dstProp, &srcProp, propPath, source)
+		}
+		dst.Properties[k] = dstProp
+	}
+}
+
+// recordSources records the source for a property and all its children.
+func (m *schemaMerger) recordSources(prop *apiextv1beta1.JSONSchemaProps, path, source string) {
+	m.sources[path] = source
+	for k, child := range prop.Properties {
+		m.recordSources(&child, path+"."+k, source)
+	}
+}
+
+// conflictReason returns a non-empty reason if two definitions of the same
+// property are incompatible.
+func conflictReason(a, b *apiextv1beta1.JSONSchemaProps) string {
+	if a.Type != "" && b.Type != "" && a.Type != b.Type {
+		return fmt.Sprintf("conflicting types %q and %q", a.Type, b.Type)
+	}
+	if a.Default != nil && b.Default != nil && !reflect.DeepEqual(a.Default, b.Default) {
+		return fmt.Sprintf("conflicting defaults %s and %s", a.Default.Raw, b.Default.Raw)
+	}
+	return ""
+}
+
+// unionStrings returns the union of two string lists, preserving the order of
+// first appearance.
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+)
+
+var schemaComponent = `
+apiVersion: bundle.gke.io/v1alpha1
+kind: Component
+spec:
+  componentName: schema-comp
+  version: 1.0.0
+  objects:
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: ObjectTemplate
+    metadata:
+      name: obj-tmpl
+    type: go-template
+    template: "kind: Pod"
+    optionsSchema:
+      required:
+      - Namespace
+      properties:
+        Namespace:
+          type: string
+          default: kube-system
+        Image:
+          type: object
+          properties:
+            Repo:
+              type: string
+  - apiVersion: bundle.gke.io/v1alpha1
+    kind: PatchTemplate
+    metadata:
+      name: patch-tmpl
+    template: "kind: Pod"
+    optionsSchema:
+      required:
+      - Replicas
+      properties:
+        Namespace:
+          type: string
+        Replicas:
+          type: integer
+        Image:
+          type: object
+          properties:
+            Tag:
+              type: string
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: not-a-template
+`
+
+func TestComponentSchema(t *testing.T) {
+	comp, err := converter.FromYAMLString(schemaComponent).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, conflicts, err := ComponentSchema(comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("got conflicts %v but expected none", conflicts)
+	}
+
+	var props []string
+	for k := range schema.Properties {
+		props = append(props, k)
+	}
+	sort.Strings(props)
+	if exp := []string{"Image", "Namespace", "Replicas"}; !reflect.DeepEqual(props, exp) {
+		t.Errorf("got properties %v, expected %v", props, exp)
+	}
+
+	var imgProps []string
+	for k := range schema.Properties["Image"].Properties {
+		imgProps = append(imgProps, k)
+	}
+	sort.Strings(imgProps)
+	if exp := []string{"Repo", "Tag"}; !reflect.DeepEqual(imgProps, exp) {
+		t.Errorf("got Image properties %v, expected %v", imgProps, exp)
+	}
+
+	if exp := []string{"Namespace", "Replicas"}; !reflect.DeepEqual(schema.Required, exp) {
+		t.Errorf("got required %v, expected %v", schema.Required, exp)
+	}
+	if def := schema.Properties["Namespace"].Default; def == nil || string(def.Raw) != `"kube-system"` {
+		t.Errorf("got Namespace default %v, expected kube-system", def)
+	}
+}
+
+func TestMergeSchemasConflicts(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		component    string
+		expConflicts []string
+	}{
+		{
+			desc: "conflicting types",
+			component: `
+spec:
+  objects:
+  - kind: ObjectTemplate
+    metadata:
+      name: a
+    optionsSchema:
+      properties:
+        Port:
+          type: string
+  - kind: PatchTemplate
+    metadata:
+      name: b
+    optionsSchema:
+      properties:
+        Port:
+          type: integer
+`,
+			expConflicts: []string{`option "Port": conflicting types "string" and "integer" (declared by ObjectTemplate/a, PatchTemplate/b)`},
+		},
+		{
+			desc: "conflicting nested defaults",
+			component: `
+spec:
+  objects:
+  - kind: ObjectTemplate
+    metadata:
+      name: a
+    optionsSchema:
+      properties:
+        Foo:
+          type: object
+          properties:
+            Bar:
+              type: string
+              default: biff
+  - kind: ObjectTemplate
+    metadata:
+      name: b
+    optionsSchema:
+      properties:
+        Foo:
+          type: object
+          properties:
+            Bar:
+              type: string
+              default: bam
+`,
+			expConflicts: []string{`option "Foo.Bar": conflicting defaults "biff" and "bam" (declared by ObjectTemplate/a, ObjectTemplate/b)`},
+		},
+		{
+			desc: "identical definitions",
+			component: `
+spec:
+  objects:
+  - kind: ObjectTemplate
+    metadata:
+      name: a
+    optionsSchema:
+      properties:
+        Foo:
+          type: string
+          default: biff
+  - kind: PatchTemplate
+    metadata:
+      name: b
+    optionsSchema:
+      properties:
+        Foo:
+          type: string
+          default: biff
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(tc.component).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, conflicts, err := ComponentSchema(comp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, c.String())
+			}
+			if !reflect.DeepEqual(got, tc.expConflicts) {
+				t.Errorf("got conflicts %q, expected %q", got, tc.expConflicts)
+			}
+		})
+	}
+}
+
+func TestBundleSchemas(t *testing.T) {
+	bun, err := converter.FromYAMLString(`
+kind: Bundle
+components:
+- spec:
+    componentName: comp-a
+    objects:
+    - kind: ObjectTemplate
+      metadata:
+        name: a
+      optionsSchema:
+        properties:
+          Foo:
+            type: string
+- spec:
+    componentName: comp-b
+    objects:
+    - kind: ObjectTemplate
+      metadata:
+        name: b
+      optionsSchema:
+        properties:
+          Foo:
+            type: integer
+`).ToBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas, conflicts, err := BundleSchemas(bun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("got conflicts %v, but components should be merged independently", conflicts)
+	}
+	if got := schemas["comp-a"].Properties["Foo"].Type; got != "string" {
+		t.Errorf("got comp-a Foo type %q, expected string", got)
+	}
+	if got := schemas["comp-b"].Properties["Foo"].Type; got != "integer" {
+		t.Errorf("got comp-b Foo type %q, expected integer", got)
+	}
+}
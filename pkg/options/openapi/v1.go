@@ -0,0 +1,73 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"github.com/go-openapi/validate"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+// The bundle types store options schemas as apiextensions v1beta1
+// JSONSchemaProps. The v1 and v1beta1 schemas have the same serialized form,
+// so a schema copied from a v1 CRD can be used directly as an OptionsSchema,
+// BuildSchema or TargetSchema, including the x-kubernetes-int-or-string and
+// x-kubernetes-validations extensions. The functions below are for callers
+// that already have a v1 schema in its Go form.
+
+// FromV1 converts a v1 schema to the v1beta1 schema used by the bundle types.
+func FromV1(in *apiextv1.JSONSchemaProps) (*apiextv1beta1.JSONSchemaProps, error) {
+	if in == nil {
+		return nil, nil
+	}
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(in, internal, nil /* conversion scope */); err != nil {
+		return nil, err
+	}
+	out := &apiextv1beta1.JSONSchemaProps{}
+	if err := apiextv1beta1.Convert_apiextensions_JSONSchemaProps_To_v1beta1_JSONSchemaProps(internal, out, nil /* conversion scope */); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ToV1 converts a v1beta1 schema used by the bundle types to a v1 schema.
+func ToV1(in *apiextv1beta1.JSONSchemaProps) (*apiextv1.JSONSchemaProps, error) {
+	if in == nil {
+		return nil, nil
+	}
+	internal, err := toInternal(in)
+	if err != nil {
+		return nil, err
+	}
+	out := &apiextv1.JSONSchemaProps{}
+	if err := apiextv1.Convert_apiextensions_JSONSchemaProps_To_v1_JSONSchemaProps(internal, out, nil /* conversion scope */); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ValidateOptionsV1 validates options against a v1 schema. See
+// ValidateOptions.
+func ValidateOptionsV1(opts options.JSONOptions, optSchema *apiextv1.JSONSchemaProps) (*validate.Result, error) {
+	s, err := FromV1(optSchema)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateOptions(opts, s)
+}
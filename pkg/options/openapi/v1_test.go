@@ -0,0 +1,105 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"testing"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+var v1Schema = `
+type: object
+required:
+- port
+x-kubernetes-validations:
+- rule: "!has(self.tls) || self.tls.enabled || self.port != 443"
+  message: port 443 requires TLS
+properties:
+  port:
+    x-kubernetes-int-or-string: true
+    default: 80
+  tls:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+    properties:
+      enabled:
+        type: boolean
+  hosts:
+    type: array
+    x-kubernetes-list-type: set
+    items:
+      type: string
+`
+
+func TestV1RoundTrip(t *testing.T) {
+	orig := &apiextv1beta1.JSONSchemaProps{}
+	if err := converter.FromYAMLString(v1Schema).ToObject(orig); err != nil {
+		t.Fatal(err)
+	}
+	origJSON, err := converter.FromObject(orig).ToJSONString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1, err := ToV1(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromV1(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backJSON, err := converter.FromObject(back).ToJSONString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origJSON != backJSON {
+		t.Errorf("round trip was lossy:\ngot  %s\nwant %s", backJSON, origJSON)
+	}
+}
+
+func TestValidateOptionsV1(t *testing.T) {
+	schema := &apiextv1.JSONSchemaProps{}
+	if err := converter.FromYAMLString(v1Schema).ToObject(schema); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		desc         string
+		opts         string
+		expErrSubstr string
+	}{
+		{desc: "success", opts: "port: https"},
+		{desc: "success: tls", opts: "{port: 443, tls: {enabled: true}}"},
+		{desc: "fail: type", opts: "{port: 80, hosts: a}", expErrSubstr: "hosts in body must be of type array"},
+		{desc: "fail: rule", opts: "{port: 443, tls: {enabled: false}}", expErrSubstr: "port 443 requires TLS"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts, err := converter.FromYAMLString(tc.opts).ToJSONMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ValidateOptionsV1(opts, schema)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Error(cerr)
+			}
+		})
+	}
+}
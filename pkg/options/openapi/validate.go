@@ -17,13 +17,17 @@
 package openapi
 
 import (
+	"strings"
+
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	goerrors "github.com/go-openapi/errors"
 	"github.com/go-openapi/spec"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/validate"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	kspec "k8s.io/kube-openapi/pkg/validation/spec"
 )
 
@@ -31,13 +35,52 @@ import (
 // contains helpers for manipulating the data and also getting feedback on
 // errors, and so may be non-nil in the case of an error when the error is the
 // result of openapi validation failure.
+//
+// In addition to the OpenAPI validation, any CEL rules declared with
+// x-kubernetes-validations are evaluated against the options.
 func ValidateOptions(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSchemaProps) (*validate.Result, error) {
+	result, ruleErrs, err := validateOptions(opts, optSchema)
+	if err != nil {
+		return result, err
+	}
+	if len(ruleErrs) > 0 {
+		return result, rulesError(ruleErrs)
+	}
+	return result, nil
+}
+
+// validateOptions validates the options against the OpenAPI schema and, if
+// that succeeds, against the schema's CEL rules with the schema defaults
+// applied. The rule violations are
+// returned separately from OpenAPI validation failures.
+func validateOptions(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSchemaProps) (*validate.Result, field.ErrorList, error) {
 	if optSchema == nil || opts == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
+	intOptSchema, err := toInternal(optSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := validateOpenAPI(opts, intOptSchema)
+	if err != nil {
+		return result, nil, err
+	}
+	// The rules are evaluated against the defaulted options, as they are when
+	// the defaults are applied, so that rules may read fields with defaults.
+	defaulted, err := defaultedCopy(opts, intOptSchema)
+	if err != nil {
+		return result, nil, err
+	}
+	ruleErrs, err := validateRules(defaulted, intOptSchema, nil)
+	if err != nil {
+		return result, nil, err
+	}
+	return result, ruleErrs, nil
+}
 
-	// We need to convert to the internal JSONSchemaProps, because that's what
-	// the conversion library deals with.
+// toInternal converts a v1beta1 schema to the internal JSONSchemaProps,
+// because that's what the conversion and validation libraries deal with.
+func toInternal(optSchema *apiextv1beta1.JSONSchemaProps) (*apiextensions.JSONSchemaProps, error) {
 	intOptSchema := &apiextensions.JSONSchemaProps{}
 
 	// TODO(kashomon): Should I make a runtime scheme and use that to convert
@@ -49,7 +92,12 @@ func ValidateOptions(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSche
 	if err != nil {
 		return nil, err
 	}
+	return intOptSchema, nil
+}
 
+// validateOpenAPI validates the options against the OpenAPI form of an
+// internal schema.
+func validateOpenAPI(opts options.JSONOptions, intOptSchema *apiextensions.JSONSchemaProps) (*validate.Result, error) {
 	k8sOpenapiSchema := &kspec.Schema{}
 	if err := validation.ConvertJSONSchemaProps(intOptSchema, k8sOpenapiSchema); err != nil {
 		return nil, err
@@ -73,3 +121,76 @@ func ValidateOptions(opts options.JSONOptions, optSchema *apiextv1beta1.JSONSche
 	}
 	return result, nil
 }
+
+// ValidateTemplateOptions validates options against each of the template
+// schemas without applying them, so that all the violations can be reported
+// at once before any rendering happens. The field path of each error has the
+// form <Kind>[<name>].<option path>.
+func ValidateTemplateOptions(opts options.JSONOptions, schemas []*TemplateSchema) field.ErrorList {
+	errs := field.ErrorList{}
+	if opts == nil {
+		return errs
+	}
+	for _, ts := range schemas {
+		p := field.NewPath(ts.Kind).Key(ts.Name)
+		res, ruleErrs, err := validateOptions(opts, ts.Schema)
+		if err == nil {
+			for _, rerr := range ruleErrs {
+				errs = append(errs, prefixFieldError(p, rerr))
+			}
+			continue
+		}
+		if res == nil || len(res.Errors) == 0 {
+			// Schema conversion failures aren't validation results.
+			errs = append(errs, field.InternalError(p, err))
+			continue
+		}
+		for _, verr := range flattenValidationErrors(res.Errors) {
+			errs = append(errs, validationToFieldError(p, verr))
+		}
+	}
+	return errs
+}
+
+// flattenValidationErrors expands composite errors returned by the OpenAPI
+// validator.
+func flattenValidationErrors(errs []error) []error {
+	var out []error
+	for _, err := range errs {
+		if ce, ok := err.(*goerrors.CompositeError); ok {
+			out = append(out, flattenValidationErrors(ce.Errors)...)
+			continue
+		}
+		out = append(out, err)
+	}
+	return out
+}
+
+// validationToFieldError converts an OpenAPI validation error into a
+// field.Error rooted at the template path.
+func validationToFieldError(p *field.Path, err error) *field.Error {
+	verr, ok := err.(*goerrors.Validation)
+	if !ok {
+		return field.Invalid(p, nil, err.Error())
+	}
+	if verr.Name != "" && verr.Name != "." {
+		for _, elem := range strings.Split(strings.TrimPrefix(verr.Name, "."), ".") {
+			p = p.Child(elem)
+		}
+	}
+	if verr.Code() == goerrors.RequiredFailCode {
+		return field.Required(p, verr.Error())
+	}
+	return field.Invalid(p, verr.Value, verr.Error())
+}
+
+// prefixFieldError roots a field error, whose path is relative to the
+// options, at the template path.
+func prefixFieldError(p *field.Path, err *field.Error) *field.Error {
+	out := *err
+	out.Field = p.String()
+	if err.Field != "" && err.Field != "<nil>" {
+		out.Field += "." + err.Field
+	}
+	return &out
+}
@@ -15,6 +15,9 @@
 package openapi
 
 import (
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
@@ -60,6 +63,126 @@ properties:
 `,
 			expErrSubstr: "validation failure",
 		},
+		{
+			desc:   "success: int-or-string with int",
+			object: "port: 8080",
+			schema: `
+properties:
+  port:
+    x-kubernetes-int-or-string: true
+`,
+		},
+		{
+			desc:   "success: int-or-string with string",
+			object: "port: http",
+			schema: `
+properties:
+  port:
+    x-kubernetes-int-or-string: true
+`,
+		},
+		{
+			desc:   "fail: int-or-string with bool",
+			object: "port: true",
+			schema: `
+properties:
+  port:
+    x-kubernetes-int-or-string: true
+`,
+			expErrSubstr: "validation failure",
+		},
+		{
+			desc: "success: cel rule",
+			object: `
+minReplicas: 1
+maxReplicas: 3
+`,
+			schema: `
+x-kubernetes-validations:
+- rule: self.minReplicas <= self.maxReplicas
+  message: minReplicas must not exceed maxReplicas
+properties:
+  minReplicas:
+    type: integer
+  maxReplicas:
+    type: integer
+`,
+		},
+		{
+			desc: "fail: cel rule",
+			object: `
+minReplicas: 5
+maxReplicas: 3
+`,
+			schema: `
+x-kubernetes-validations:
+- rule: self.minReplicas <= self.maxReplicas
+  message: minReplicas must not exceed maxReplicas
+properties:
+  minReplicas:
+    type: integer
+  maxReplicas:
+    type: integer
+`,
+			expErrSubstr: "minReplicas must not exceed maxReplicas",
+		},
+		{
+			desc:   "success: cel rule reads defaulted field",
+			object: "minReplicas: 1",
+			schema: `
+x-kubernetes-validations:
+- rule: self.minReplicas <= self.maxReplicas
+  message: minReplicas must not exceed maxReplicas
+properties:
+  minReplicas:
+    type: integer
+  maxReplicas:
+    type: integer
+    default: 3
+`,
+		},
+		{
+			desc:   "fail: cel rule reads defaulted field",
+			object: "minReplicas: 5",
+			schema: `
+x-kubernetes-validations:
+- rule: self.minReplicas <= self.maxReplicas
+  message: minReplicas must not exceed maxReplicas
+properties:
+  minReplicas:
+    type: integer
+  maxReplicas:
+    type: integer
+    default: 3
+`,
+			expErrSubstr: "minReplicas must not exceed maxReplicas",
+		},
+		{
+			desc:   "fail: nested cel rule",
+			object: "image: {repo: gcr.io/foo}",
+			schema: `
+properties:
+  image:
+    type: object
+    properties:
+      repo:
+        type: string
+        x-kubernetes-validations:
+        - rule: self.startsWith('k8s.gcr.io/')
+`,
+			expErrSubstr: "image.repo",
+		},
+		{
+			desc:   "fail: cel rule in non-structural schema",
+			object: "foo: bar",
+			schema: `
+x-kubernetes-validations:
+- rule: has(self.foo)
+properties:
+  foo: {}
+`,
+			expErrSubstr: "must be structural",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -74,6 +197,9 @@ properties:
 				t.Fatal(err)
 			}
 			res, err := ValidateOptions(obj, schema)
+			if _, ok := obj["maxReplicas"]; ok && !strings.Contains(tc.object, "maxReplicas") {
+				t.Errorf("defaults were applied to the validated options: %v", obj)
+			}
 
 			cerr := testutil.CheckErrorCases(err, tc.expErrSubstr)
 			if cerr != nil {
@@ -89,3 +215,97 @@ properties:
 		})
 	}
 }
+
+func TestValidateTemplateOptions(t *testing.T) {
+	comp, err := converter.FromYAMLString(`
+spec:
+  objects:
+  - kind: ObjectTemplate
+    metadata:
+      name: obj-tmpl
+    optionsSchema:
+      required:
+      - Name
+      properties:
+        Name:
+          type: string
+        Replicas:
+          type: integer
+  - kind: PatchTemplate
+    metadata:
+      name: patch-tmpl
+    optionsSchema:
+      properties:
+        Image:
+          type: object
+          properties:
+            Tag:
+              type: string
+              x-kubernetes-validations:
+              - rule: self != 'latest'
+                message: must be pinned
+`).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := ComponentTemplateSchemas(comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc      string
+		opts      string
+		expFields []string
+	}{
+		{
+			desc: "success",
+			opts: `
+Name: foo
+Replicas: 3
+Image:
+  Tag: v1
+`,
+		},
+		{
+			desc: "rule violation",
+			opts: `
+Name: foo
+Image:
+  Tag: latest
+`,
+			expFields: []string{"PatchTemplate[patch-tmpl].Image.Tag"},
+		},
+		{
+			desc: "all violations reported",
+			opts: `
+Replicas: three
+Image:
+  Tag: 1
+`,
+			expFields: []string{
+				"ObjectTemplate[obj-tmpl].Name",
+				"ObjectTemplate[obj-tmpl].Replicas",
+				"PatchTemplate[patch-tmpl].Image.Tag",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts, err := converter.FromYAMLString(tc.opts).ToJSONMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := ValidateTemplateOptions(opts, schemas)
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, tc.expFields) {
+				t.Errorf("got error fields %v, expected %v. Errors: %v", fields, tc.expFields, errs.ToAggregate())
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patchtmpl
+
+import (
+	"fmt"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// bundleApplier applies bundle-level patch templates to components.
+type bundleApplier struct {
+	*applier
+
+	patchTemplates []*bundle.PatchTemplate
+}
+
+// NewBundleApplier creates a new options applier instance that applies
+// bundle-level PatchTemplates, such as those in Bundle.PatchTemplates, to the
+// objects of each component it is given. The PatchTemplates in the components
+// themselves are left untouched, so this applier is meant to run after the
+// applier for the component's own patches. The ApplierConfigs are the same as
+// for NewApplierWithConfig; the filter options select which of the
+// bundle-level PatchTemplates to apply.
+func NewBundleApplier(pts []*bundle.PatchTemplate, opts ...ApplierConfig) options.Applier {
+	return &bundleApplier{
+		applier:        NewApplierWithConfig(opts...).(*applier),
+		patchTemplates: pts,
+	}
+}
+
+// ApplyOptions applies the bundle-level PatchTemplates to the component
+// objects.
+func (a *bundleApplier) ApplyOptions(comp *bundle.Component, p options.JSONOptions) (*bundle.Component, error) {
+	comp = comp.DeepCopy()
+	var ptObjs []*unstructured.Unstructured
+	for _, pt := range a.patchTemplates {
+		obj, err := ToUnstructured(pt)
+		if err != nil {
+			return nil, err
+		}
+		ptObjs = append(ptObjs, obj)
+	}
+
+	tfil := &filter.Options{}
+	if a.tmplFilter != nil {
+		*tfil = *a.tmplFilter
+	}
+	tfil.Kinds = []string{"PatchTemplate"}
+	tfil.InvertMatch = false
+	ptObjs = filter.NewFilter().SelectObjects(ptObjs, tfil)
+	if len(ptObjs) < 1 {
+		return comp, nil
+	}
+
+	patches, objs, err := a.makePatches(ptObjs, comp.Spec.Objects, p)
+	if err != nil {
+		return nil, fmt.Errorf("applying bundle patch templates to component %v: %w", comp.ComponentReference(), err)
+	}
+	crds, err := a.crdSchemas(comp)
+	if err != nil {
+		return nil, err
+	}
+	newObjs, err := options.ApplyCommon(comp.ComponentReference(), objs, p, objectApplier(a.scheme, crds, patches))
+	comp.Spec.Objects = newObjs
+	return comp, err
+}
+
+// ToUnstructured converts a PatchTemplate into an unstructured object, filling
+// in the apiVersion and kind, which are usually omitted for PatchTemplates
+// inlined in a Bundle.
+func ToUnstructured(pt *bundle.PatchTemplate) (*unstructured.Unstructured, error) {
+	pt = pt.DeepCopy()
+	if pt.APIVersion == "" {
+		pt.APIVersion = "bundle.gke.io/v1alpha1"
+	}
+	if pt.Kind == "" {
+		pt.Kind = "PatchTemplate"
+	}
+	by, err := converter.FromObject(pt).ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("while converting PatchTemplate %q: %v", pt.GetName(), err)
+	}
+	return converter.FromJSON(by).ToUnstructured()
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patchtmpl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+var bundleWithPatches = `
+kind: Bundle
+components:
+- kind: Component
+  spec:
+    componentName: etcd
+    version: 1.2.3
+    objects:
+    - apiVersion: apps/v1
+      kind: DaemonSet
+      metadata:
+        name: etcd-ds
+    - apiVersion: v1
+      kind: Pod
+      metadata:
+        name: etcd-pod
+    - kind: PatchTemplate
+      template: |
+        metadata:
+          namespace: etcd-ns
+- kind: Component
+  spec:
+    componentName: kube-proxy
+    version: 2.0.0
+    objects:
+    - apiVersion: apps/v1
+      kind: DaemonSet
+      metadata:
+        name: proxy-ds
+patchTemplates:
+- metadata:
+    annotations:
+      policy: tolerations
+  template: |
+    apiVersion: apps/v1
+    kind: DaemonSet
+    spec:
+      template:
+        spec:
+          tolerations:
+          - operator: {{.Operator}}
+- metadata:
+    annotations:
+      policy: labels
+  template: |
+    metadata:
+      labels:
+        component-version: v1
+  selector:
+    componentNames:
+    - etcd*
+    componentVersions:
+    - 1.*
+`
+
+func TestBundleApplier(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		customFilter *filter.Options
+		exp          map[string][]string
+		expNot       map[string][]string
+	}{
+		{
+			desc: "all bundle patches",
+			exp: map[string][]string{
+				"etcd":       {"namespace: etcd-ns", "operator: Exists", "component-version: v1"},
+				"kube-proxy": {"operator: Exists"},
+			},
+			expNot: map[string][]string{
+				"etcd":       {"kind: PatchTemplate"},
+				"kube-proxy": {"component-version: v1"},
+			},
+		},
+		{
+			desc:         "filtered bundle patches",
+			customFilter: &filter.Options{Annotations: map[string]string{"policy": "labels"}},
+			exp: map[string][]string{
+				"etcd": {"namespace: etcd-ns", "component-version: v1"},
+			},
+			expNot: map[string][]string{
+				"etcd":       {"operator: Exists"},
+				"kube-proxy": {"operator: Exists"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			bun, err := converter.FromYAMLString(bundleWithPatches).ToBundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := options.JSONOptions{"Operator": "Exists"}
+			compApplier := NewApplierWithConfig(WithFilterOpts(tc.customFilter))
+			bunApplier := NewBundleApplier(bun.PatchTemplates, WithFilterOpts(tc.customFilter))
+			for _, comp := range bun.Components {
+				comp, err := compApplier.ApplyOptions(comp, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				comp, err = bunApplier.ApplyOptions(comp, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				compBytes, err := converter.FromObject(comp).ToYAML()
+				if err != nil {
+					t.Fatal(err)
+				}
+				compStr := string(compBytes)
+				name := comp.Spec.ComponentName
+				for _, s := range tc.exp[name] {
+					if !strings.Contains(compStr, s) {
+						t.Errorf("component %s: got %s, expected it to contain %q", name, compStr, s)
+					}
+				}
+				for _, s := range tc.expNot[name] {
+					if strings.Contains(compStr, s) {
+						t.Errorf("component %s: got %s, expected it not to contain %q", name, compStr, s)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patchtmpl
+
+import (
+	"fmt"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+)
+
+const (
+	// crdKind is the kind of CustomResourceDefinitions.
+	crdKind = "CustomResourceDefinition"
+
+	// crdGroup is the API group of CustomResourceDefinitions.
+	crdGroup = "apiextensions.k8s.io"
+)
+
+// crdSchemas holds the OpenAPI schemas of custom resources, keyed by
+// GroupVersionKind.
+type crdSchemas map[schema.GroupVersionKind]*apiextv1beta1.JSONSchemaProps
+
+// isCRD returns whether an object is a CustomResourceDefinition.
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == crdKind && obj.GroupVersionKind().Group == crdGroup
+}
+
+// addObjects adds the schemas of every CustomResourceDefinition in the
+// objects. Other objects are ignored.
+func (c crdSchemas) addObjects(objs []*unstructured.Unstructured) error {
+	for _, obj := range objs {
+		if !isCRD(obj) {
+			continue
+		}
+		if err := c.add(obj); err != nil {
+			return fmt.Errorf("reading schema from CustomResourceDefinition %q: %v", obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// add adds the schemas of an apiextensions.k8s.io/v1 or v1beta1
+// CustomResourceDefinition, one for each served version.
+func (c crdSchemas) add(obj *unstructured.Unstructured) error {
+	switch obj.GroupVersionKind().Version {
+	case "v1":
+		crd := &apiextv1.CustomResourceDefinition{}
+		if err := converter.FromUnstructured(obj).ToObject(crd); err != nil {
+			return err
+		}
+		for _, v := range crd.Spec.Versions {
+			if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			s, err := openapi.FromV1(v.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return err
+			}
+			c[schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}] = s
+		}
+	case "v1beta1":
+		crd := &apiextv1beta1.CustomResourceDefinition{}
+		if err := converter.FromUnstructured(obj).ToObject(crd); err != nil {
+			return err
+		}
+		versions := []string{crd.Spec.Version}
+		for _, v := range crd.Spec.Versions {
+			versions = append(versions, v.Name)
+		}
+		for _, name := range versions {
+			s := crdVersionSchema(crd, name)
+			if name == "" || s == nil {
+				continue
+			}
+			c[schema.GroupVersionKind{Group: crd.Spec.Group, Version: name, Kind: crd.Spec.Names.Kind}] = s
+		}
+	default:
+		return fmt.Errorf("unsupported apiVersion %q", obj.GetAPIVersion())
+	}
+	return nil
+}
+
+// crdVersionSchema returns the schema for a version of a v1beta1
+// CustomResourceDefinition, preferring a per-version schema over the
+// top-level validation.
+func crdVersionSchema(crd *apiextv1beta1.CustomResourceDefinition, version string) *apiextv1beta1.JSONSchemaProps {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version && v.Schema != nil && v.Schema.OpenAPIV3Schema != nil {
+			return v.Schema.OpenAPIV3Schema
+		}
+	}
+	if crd.Spec.Validation != nil {
+		return crd.Spec.Validation.OpenAPIV3Schema
+	}
+	return nil
+}
+
+// patchMeta returns the strategic merge patch metadata for an object, if
+// there is a schema for its kind.
+func (c crdSchemas) patchMeta(obj *unstructured.Unstructured) (strategicpatch.LookupPatchMeta, bool) {
+	s, ok := c[obj.GroupVersionKind()]
+	if !ok {
+		return nil, false
+	}
+	return &schemaPatchMeta{schema: s, root: true}, true
+}
+
+// schemaPatchMeta looks up strategic merge patch metadata from a structural
+// OpenAPI schema, as found in CustomResourceDefinitions.
+//
+// Lists with 'x-kubernetes-list-type: map' and a single key in
+// 'x-kubernetes-list-map-keys' are merged using the key as the merge key, and
+// lists with 'x-kubernetes-list-type: set' are merged as sets. All other
+// lists, including maps keyed by several keys, which strategic merge patch
+// can't express, are replaced. Maps with 'x-kubernetes-map-type: atomic' are
+// replaced, and all other maps are merged. Fields not in the schema, such as
+// those under 'x-kubernetes-preserve-unknown-fields', are treated as
+// free-form.
+type schemaPatchMeta struct {
+	// schema is the schema of the current value. It is nil for free-form
+	// values.
+	schema *apiextv1beta1.JSONSchemaProps
+
+	// root is true for the schema of the object itself, whose metadata is
+	// always an ObjectMeta.
+	root bool
+}
+
+var _ strategicpatch.LookupPatchMeta = &schemaPatchMeta{}
+
+// LookupPatchMetadataForStruct returns the schema and patch metadata for a
+// field of a map.
+func (s *schemaPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	if s.root && key == "metadata" {
+		m, err := strategicpatch.NewPatchMetaFromStruct(&metav1.ObjectMeta{})
+		return m, strategicpatch.PatchMeta{}, err
+	}
+	sub := s.field(key)
+	var pm strategicpatch.PatchMeta
+	if sub != nil && sub.XMapType != nil && *sub.XMapType == "atomic" {
+		pm.SetPatchStrategies([]string{"replace"})
+	}
+	return &schemaPatchMeta{schema: sub}, pm, nil
+}
+
+// LookupPatchMetadataForSlice returns the schema of the items and the patch
+// metadata for a list field of a map.
+func (s *schemaPatchMeta) LookupPatchMetadataForSlice(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	if s.root && key == "metadata" {
+		return nil, strategicpatch.PatchMeta{}, fmt.Errorf("field %q is not a list", key)
+	}
+	sub := s.field(key)
+	var pm strategicpatch.PatchMeta
+	if sub == nil {
+		return &schemaPatchMeta{}, pm, nil
+	}
+	if sub.XListType != nil {
+		switch *sub.XListType {
+		case "map":
+			if len(sub.XListMapKeys) == 1 {
+				pm.SetPatchStrategies([]string{"merge"})
+				pm.SetPatchMergeKey(sub.XListMapKeys[0])
+			}
+		case "set":
+			pm.SetPatchStrategies([]string{"merge"})
+		}
+	}
+	var items *apiextv1beta1.JSONSchemaProps
+	if sub.Items != nil {
+		items = sub.Items.Schema
+	}
+	return &schemaPatchMeta{schema: items}, pm, nil
+}
+
+// Name returns the type of the current value.
+func (s *schemaPatchMeta) Name() string {
+	if s.schema == nil {
+		return ""
+	}
+	return s.schema.Type
+}
+
+// field returns the schema for a field of a map, or nil if the field has no
+// schema.
+func (s *schemaPatchMeta) field(key string) *apiextv1beta1.JSONSchemaProps {
+	if s.schema == nil {
+		return nil
+	}
+	if p, ok := s.schema.Properties[key]; ok {
+		return &p
+	}
+	if s.schema.AdditionalProperties != nil {
+		return s.schema.AdditionalProperties.Schema
+	}
+	return nil
+}
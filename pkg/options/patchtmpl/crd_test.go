@@ -0,0 +1,284 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patchtmpl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var widgetCRDV1 = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              ports:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys:
+                - name
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                    port:
+                      type: integer
+              hosts:
+                type: array
+                x-kubernetes-list-type: set
+                items:
+                  type: string
+              args:
+                type: array
+                items:
+                  type: string
+              selector:
+                type: object
+                x-kubernetes-map-type: atomic
+                additionalProperties:
+                  type: string
+              extra:
+                type: object
+                x-kubernetes-preserve-unknown-fields: true
+`
+
+var widgetCRDV1beta1 = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  version: v1
+  names:
+    kind: Widget
+    plural: widgets
+  validation:
+    openAPIV3Schema:
+      type: object
+      properties:
+        spec:
+          type: object
+          properties:
+            ports:
+              type: array
+              x-kubernetes-list-type: map
+              x-kubernetes-list-map-keys:
+              - name
+              items:
+                type: object
+`
+
+var widget = `
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  labels:
+    foo: bar
+spec:
+  ports:
+  - name: http
+    port: 80
+  - name: https
+    port: 443
+  hosts:
+  - a.example.com
+  args:
+  - --verbose
+  selector:
+    app: widget
+    tier: web
+  extra:
+    nested:
+      keep: me
+`
+
+var widgetPatch = `
+kind: PatchTemplate
+template: |
+  apiVersion: example.com/v1
+  kind: Widget
+  metadata:
+    labels:
+      biff: bam
+  spec:
+    ports:
+    - name: https
+      port: 8443
+    - name: metrics
+      port: 9090
+    hosts:
+    - b.example.com
+    args:
+    - --quiet
+    selector:
+      app: other
+    extra:
+      nested:
+        added: value
+`
+
+func TestPatchWithCRD(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		compObjs  []string
+		extraCRDs []string
+		expSpec   string
+		expErr    string
+	}{
+		{
+			desc:     "CRD in component",
+			compObjs: []string{widgetCRDV1, widget, widgetPatch},
+			expSpec: `
+ports:
+- name: http
+  port: 80
+- name: https
+  port: 8443
+- name: metrics
+  port: 9090
+hosts:
+- b.example.com
+- a.example.com
+args:
+- --quiet
+selector:
+  app: other
+extra:
+  nested:
+    keep: me
+    added: value
+`,
+		},
+		{
+			desc:      "v1beta1 CRD from config",
+			compObjs:  []string{widget, widgetPatch},
+			extraCRDs: []string{widgetCRDV1beta1},
+			expSpec: `
+ports:
+- name: http
+  port: 80
+- name: https
+  port: 8443
+- name: metrics
+  port: 9090
+hosts:
+- b.example.com
+args:
+- --quiet
+selector:
+  app: other
+  tier: web
+extra:
+  nested:
+    keep: me
+    added: value
+`,
+		},
+		{
+			desc:     "no CRD",
+			compObjs: []string{widget, widgetPatch},
+			expErr:   "type not registered in scheme and no CustomResourceDefinition found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  componentName: widgets
+`).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range tc.compObjs {
+				comp.Spec.Objects = append(comp.Spec.Objects, mustUnstructured(t, o))
+			}
+			var crds []*unstructured.Unstructured
+			for _, o := range tc.extraCRDs {
+				crds = append(crds, mustUnstructured(t, o))
+			}
+
+			newComp, err := NewApplierWithConfig(WithCRDs(crds...)).ApplyOptions(comp, nil)
+			if cerr := testutil.CheckErrorCases(err, tc.expErr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got *unstructured.Unstructured
+			for _, o := range newComp.Spec.Objects {
+				if o.GetKind() == "Widget" {
+					got = o
+				}
+			}
+			if got == nil {
+				t.Fatalf("no Widget in %v", newComp.Spec.Objects)
+			}
+			if labels := got.GetLabels(); labels["foo"] != "bar" || labels["biff"] != "bam" {
+				t.Errorf("got labels %v, expected merged labels", labels)
+			}
+			expMap, err := converter.FromYAMLString(tc.expSpec).ToJSONMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotSpec, err := converter.FromObject(got.Object["spec"]).ToJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotMap, err := converter.FromJSON(gotSpec).ToJSONMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotMap, expMap) {
+				t.Errorf("got spec\n%s\nexpected\n%s", gotSpec, tc.expSpec)
+			}
+		})
+	}
+}
+
+func mustUnstructured(t *testing.T, s string) *unstructured.Unstructured {
+	t.Helper()
+	obj, err := converter.FromYAMLString(s).ToUnstructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
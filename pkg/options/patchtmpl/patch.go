@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"text/template"
 
@@ -48,6 +49,14 @@ type applier struct {
 
 	// Set the template options
 	templateOpts []string
+
+	// crds are CustomResourceDefinitions whose schemas are used to
+	// strategic-merge-patch custom resources, in addition to those in the
+	// component.
+	crds []*unstructured.Unstructured
+
+	// limits bound the execution of the patch templates.
+	limits options.TemplateLimits
 }
 
 // WithPatcherScheme modifies NewApplierWithConfig so that the returned Applier
@@ -82,6 +91,25 @@ func WithIncludeTemplates(include bool) ApplierConfig {
 	}
 }
 
+// WithCRDs modifies NewApplierWithConfig so that the returned Applier uses the
+// schemas of the specified CustomResourceDefinitions to strategic-merge-patch
+// custom resources. The CustomResourceDefinitions in a component are always
+// used for its objects.
+func WithCRDs(crds ...*unstructured.Unstructured) ApplierConfig {
+	return func(a *applier) {
+		a.crds = append(a.crds, crds...)
+	}
+}
+
+// WithTemplateLimits modifies NewApplierWithConfig so that the returned
+// Applier bounds the execution of each patch template with the specified
+// limits. Exceeding a limit results in an *options.TemplateLimitError.
+func WithTemplateLimits(limits options.TemplateLimits) ApplierConfig {
+	return func(a *applier) {
+		a.limits = limits
+	}
+}
+
 // NewApplierWithConfig creates a new options applier instance with the
 // specified config options.
 func NewApplierWithConfig(opts ...ApplierConfig) options.Applier {
@@ -130,11 +158,28 @@ func (a *applier) ApplyOptions(comp *bundle.Component, p options.JSONOptions) (*
 	if err != nil {
 		return nil, err
 	}
-	newObjs, err := options.ApplyCommon(comp.ComponentReference(), objs, p, objectApplier(a.scheme, patches))
+	crds, err := a.crdSchemas(comp)
+	if err != nil {
+		return nil, err
+	}
+	newObjs, err := options.ApplyCommon(comp.ComponentReference(), objs, p, objectApplier(a.scheme, crds, patches))
 	comp.Spec.Objects = newObjs
 	return comp, err
 }
 
+// crdSchemas collects the custom resource schemas from the configured
+// CustomResourceDefinitions and those in the component.
+func (a *applier) crdSchemas(comp *bundle.Component) (crdSchemas, error) {
+	crds := make(crdSchemas)
+	if err := crds.addObjects(a.crds); err != nil {
+		return nil, err
+	}
+	if err := crds.addObjects(comp.Spec.Objects); err != nil {
+		return nil, fmt.Errorf("in component %v: %v", comp.ComponentReference(), err)
+	}
+	return crds, nil
+}
+
 // A parsedPatch has had options applied and has been converted both into raw
 // bytes and unstructured.
 type parsedPatch struct {
@@ -147,6 +192,10 @@ type parsedPatch struct {
 	// selector for determining which objects to patch.
 	selector *bundle.ObjectSelector
 
+	// name, if not empty, is the metadata.name of the only objects to patch.
+	// It's set for patches from multi-document templates.
+	name string
+
 	// type of merging logic to use for the patch
 	patchType bundle.PatchType
 }
@@ -213,7 +262,7 @@ func (a *applier) makePatches(ptObjs, objs []*unstructured.Unstructured, opts op
 		if err != nil {
 			return nil, nil, fmt.Errorf("parsing patch template %d, %s: %v", j, pto.Template, err)
 		}
-		tmpl = tmpl.Option(a.templateOpts...)
+		tmpl = tmpl.Option(a.templateOpts...).Limits(a.limits)
 
 		newOpts := opts
 		if pto.OptionsSchema != nil {
@@ -227,65 +276,118 @@ func (a *applier) makePatches(ptObjs, objs []*unstructured.Unstructured, opts op
 		var buf bytes.Buffer
 		err = tmpl.Execute(&buf, newOpts)
 		if err != nil {
-			return nil, nil, fmt.Errorf("while applying options to patch template %d: %v", j, err)
+			return nil, nil, fmt.Errorf("while applying options to patch template %d: %w", j, err)
 		}
 
-		// Convert the patch into a JSONMap to prepare for Strategic Merge Patch.
-		by := buf.Bytes()
-		jsonMap := make(map[string]interface{})
-		err = converter.FromYAML(by).ToObject(&jsonMap)
-		if err != nil {
-			return nil, nil, fmt.Errorf("while converting patch template %d: %v", j, err)
+		// A template may render several YAML documents, each of which is a
+		// separate patch.
+		docs := splitDocuments(buf.Bytes())
+		multiDoc := len(docs) > 1
+		for _, by := range docs {
+			pat, err := parsePatch(by, pto.Selector, patchType, multiDoc)
+			if err != nil {
+				return nil, nil, fmt.Errorf("while converting patch template %d: %v", j, err)
+			}
+			patches = append(patches, pat)
 		}
+	}
+	return patches, objs, nil
+}
 
-		// Neither Kind nor APIVersion are allowed as patchable fields in a
-		// PatchTemplate -- we don't want to change the schema of the objects we're
-		// patching. So, instead remove them from the PatchTemplate and add them as
-		// an additional selector parameter (which supports the previous behavior).
-		pKind := ""
-		pAPIVersion := ""
-		if jsonMap["kind"] != nil {
-			var ok bool
-			pKind, ok = jsonMap["kind"].(string)
-			if !ok {
-				return nil, nil, fmt.Errorf("found non-string type %T for Kind field for patch %s", jsonMap["kind"], string(by))
-			}
-			delete(jsonMap, "kind")
+// multiDocSeparator matches the separators of a multi-document YAML stream.
+var multiDocSeparator = regexp.MustCompile("(^|\n)---")
+
+// onlyWhitespace matches empty documents.
+var onlyWhitespace = regexp.MustCompile(`^\s*$`)
+
+// splitDocuments splits a rendered patch template into its YAML documents,
+// skipping empty ones. A template that renders nothing but whitespace still
+// produces a single, empty patch.
+func splitDocuments(by []byte) [][]byte {
+	var docs [][]byte
+	for _, doc := range multiDocSeparator.Split(string(by), -1) {
+		if !onlyWhitespace.MatchString(doc) {
+			docs = append(docs, []byte(doc))
 		}
-		if jsonMap["apiVersion"] != nil {
-			var ok bool
-			pAPIVersion, ok = jsonMap["apiVersion"].(string)
-			if !ok {
-				return nil, nil, fmt.Errorf("found a non-string APIVersion field for patch %s", string(by))
-			}
-			delete(jsonMap, "apiVersion")
+	}
+	if len(docs) == 0 {
+		return [][]byte{by}
+	}
+	return docs
+}
+
+// parsePatch converts a single rendered patch document into a parsedPatch. If
+// the patch comes from a multi-document template, its metadata.name also
+// restricts which objects it applies to.
+func parsePatch(by []byte, sel *bundle.ObjectSelector, patchType bundle.PatchType, multiDoc bool) (*parsedPatch, error) {
+	// Convert the patch into a JSONMap to prepare for Strategic Merge Patch.
+	jsonMap := make(map[string]interface{})
+	if err := converter.FromYAML(by).ToObject(&jsonMap); err != nil {
+		return nil, err
+	}
+
+	// Neither Kind nor APIVersion are allowed as patchable fields in a
+	// PatchTemplate -- we don't want to change the schema of the objects we're
+	// patching. So, instead remove them from the PatchTemplate and add them as
+	// an additional selector parameter (which supports the previous behavior).
+	pKind := ""
+	pAPIVersion := ""
+	if jsonMap["kind"] != nil {
+		var ok bool
+		pKind, ok = jsonMap["kind"].(string)
+		if !ok {
+			return nil, fmt.Errorf("found non-string type %T for Kind field for patch %s", jsonMap["kind"], string(by))
 		}
+		delete(jsonMap, "kind")
+	}
+	if jsonMap["apiVersion"] != nil {
+		var ok bool
+		pAPIVersion, ok = jsonMap["apiVersion"].(string)
+		if !ok {
+			return nil, fmt.Errorf("found a non-string APIVersion field for patch %s", string(by))
+		}
+		delete(jsonMap, "apiVersion")
+	}
 
-		selector := pto.Selector
-		if pKind != "" {
-			if selector == nil {
-				selector = &bundle.ObjectSelector{}
-			}
-			if pAPIVersion != "" {
-				pKind = pAPIVersion + "," + pKind
-			}
-			selector.Kinds = append(selector.Kinds, pKind)
+	// Each patch gets its own copy of the selector, since the kind is added
+	// to it.
+	selector := sel.DeepCopy()
+	if pKind != "" {
+		if selector == nil {
+			selector = &bundle.ObjectSelector{}
+		}
+		if pAPIVersion != "" {
+			pKind = pAPIVersion + "," + pKind
 		}
+		selector.Kinds = append(selector.Kinds, pKind)
+	}
+	if err := filter.OptionsFromObjectSelector(selector).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
 
-		patches = append(patches, &parsedPatch{
-			raw:       by,
-			jsonMap:   jsonMap,
-			selector:  selector,
-			patchType: patchType,
-		})
+	name := ""
+	if multiDoc {
+		meta, _ := jsonMap["metadata"].(map[string]interface{})
+		if n, ok := meta["name"]; ok {
+			if name, ok = n.(string); !ok {
+				return nil, fmt.Errorf("found non-string type %T for metadata.name field for patch %s", n, string(by))
+			}
+		}
 	}
-	return patches, objs, nil
+
+	return &parsedPatch{
+		raw:       by,
+		jsonMap:   jsonMap,
+		selector:  selector,
+		name:      name,
+		patchType: patchType,
+	}, nil
 }
 
 // objectApplier creates a patch object-handler. For each patch, the object
 // applier function checks whether a patch can be applied, and if so, then
 // applies it.
-func objectApplier(scheme *PatcherScheme, patches []*parsedPatch) options.ObjHandler {
+func objectApplier(scheme *PatcherScheme, crds crdSchemas, patches []*parsedPatch) options.ObjHandler {
 	return func(obj *unstructured.Unstructured, ref bundle.ComponentReference, _ options.JSONOptions) ([]*unstructured.Unstructured, error) {
 		objJSON := obj.Object
 
@@ -308,9 +410,18 @@ func objectApplier(scheme *PatcherScheme, patches []*parsedPatch) options.ObjHan
 		kubeObj, decodeErr := runtime.Decode(deserializer, objByt)
 		_, isUnstructured := kubeObj.(*unstructured.Unstructured)
 		strategicWillFail := runtime.IsNotRegisteredError(decodeErr) || isUnstructured
-		objSchema, objSchemaErr := strategicpatch.NewPatchMetaFromStruct(kubeObj)
+
+		var objSchema strategicpatch.LookupPatchMeta
+		var objSchemaErr error
+		if crdSchema, ok := crds.patchMeta(obj); ok && strategicWillFail {
+			// Custom resources can be strategic-merge-patched using the schema
+			// from their CustomResourceDefinition.
+			objSchema, strategicWillFail = crdSchema, false
+		} else {
+			objSchema, objSchemaErr = strategicpatch.NewPatchMetaFromStruct(kubeObj)
+		}
 		for _, pat := range patches {
-			if !canApplyPatch(pat, obj) {
+			if !canApplyPatch(pat, obj, ref) {
 				continue
 			}
 
@@ -331,7 +442,7 @@ func objectApplier(scheme *PatcherScheme, patches []*parsedPatch) options.ObjHan
 				if strategicWillFail {
 					// Strategic merge patch can't handle unstructured.Unstructured or
 					// unregistered objects, so return an error.
-					return nil, fmt.Errorf("while converting object %q of kind %q and apiVersion %q: type not registered in scheme", obj.GetName(), obj.GetKind(), obj.GetAPIVersion())
+					return nil, fmt.Errorf("while converting object %q of kind %q and apiVersion %q: type not registered in scheme and no CustomResourceDefinition found", obj.GetName(), obj.GetKind(), obj.GetAPIVersion())
 				}
 				if objSchemaErr != nil {
 					return nil, fmt.Errorf("while getting patch meta from object %s: %v", string(objByt), objSchemaErr)
@@ -355,9 +466,14 @@ func objectApplier(scheme *PatcherScheme, patches []*parsedPatch) options.ObjHan
 }
 
 // canApplyPatch determines whether a patch can be applied to an object. It
-// checks to ensure that if the patch defines a name,
-func canApplyPatch(pat *parsedPatch, obj *unstructured.Unstructured) bool {
-	return filter.MatchesObject(obj, filter.OptionsFromObjectSelector(pat.selector))
+// checks to ensure that both the object and the component containing it
+// match the patch's selector.
+func canApplyPatch(pat *parsedPatch, obj *unstructured.Unstructured, ref bundle.ComponentReference) bool {
+	if pat.name != "" && pat.name != obj.GetName() {
+		return false
+	}
+	fopts := filter.OptionsFromObjectSelector(pat.selector)
+	return filter.MatchesComponentReference(ref, fopts) && filter.MatchesObject(obj, fopts)
 }
 
 var floatConversionError = errors.New("error converting to float")
@@ -21,6 +21,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
 )
 
@@ -271,6 +272,169 @@ spec:
 			expMatchSubstrs:   []string{"namespace: zed", "namespace: dorp"},
 			expNoMatchSubstrs: []string{"namespace: derper"},
 		},
+		{
+			desc: "success: patch, name glob and match expressions",
+			opts: map[string]interface{}{
+				"Name": "zed",
+			},
+			component: `
+kind: Component
+spec:
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: etcd-a
+      namespace: dorp
+      labels:
+        tier: canary
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: etcd-b
+      namespace: derpper
+      labels:
+        tier: stable
+  - kind: PatchTemplate
+    template: |
+      metadata:
+        namespace: {{.Name}}
+    selector:
+      kinds:
+      - Pod.
+      names:
+      - etcd-*
+      matchExpressions:
+      - key: tier
+        operator: NotIn
+        values:
+        - canary
+`,
+			expMatchSubstrs:   []string{"namespace: zed", "namespace: dorp"},
+			expNoMatchSubstrs: []string{"namespace: derpper"},
+		},
+		{
+			desc: "error: invalid selector pattern",
+			component: `
+kind: Component
+spec:
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: foof
+  - kind: PatchTemplate
+    template: |
+      metadata:
+        namespace: zed
+    selector:
+      names:
+      - "regex:("
+`,
+			expErrSubstr: "patch template 0: invalid selector",
+		},
+		{
+			desc: "success: multi-document patch, one patch per object",
+			opts: map[string]interface{}{
+				"Pods": []interface{}{
+					map[string]interface{}{"Name": "foo", "Namespace": "foo-ns"},
+					map[string]interface{}{"Name": "bar", "Namespace": "bar-ns"},
+				},
+			},
+			component: `
+kind: Component
+spec:
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: foo
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: bar
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: biff
+      namespace: biff-ns
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: foo
+      namespace: svc-ns
+  - kind: PatchTemplate
+    template: |
+      {{- range .Pods }}
+      ---
+      kind: Pod
+      metadata:
+        name: {{.Name}}
+        namespace: {{.Namespace}}
+      {{- end }}
+`,
+			removeTemplates: true,
+			expMatchSubstrs: []string{
+				"name: foo\n      namespace: foo-ns",
+				"name: bar\n      namespace: bar-ns",
+				"name: biff\n      namespace: biff-ns",
+				"name: foo\n      namespace: svc-ns",
+			},
+		},
+		{
+			desc: "success: multi-document patch, template selector still applies",
+			component: `
+kind: Component
+spec:
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: foo
+      labels:
+        app: foo
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: bar
+  - kind: PatchTemplate
+    selector:
+      labels:
+        app: ""
+    template: |
+      metadata:
+        name: foo
+        namespace: foo-ns
+      ---
+      metadata:
+        name: bar
+        namespace: bar-ns
+`,
+			removeTemplates:   true,
+			expMatchSubstrs:   []string{"namespace: foo-ns"},
+			expNoMatchSubstrs: []string{"namespace: bar-ns"},
+		},
+		{
+			desc: "success: single patch with document separator is not multi-document",
+			component: `
+kind: Component
+spec:
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: bar
+  - kind: PatchTemplate
+    template: |
+      ---
+      kind: Pod
+      metadata:
+        name: foo
+        namespace: foo-ns
+`,
+			removeTemplates: true,
+			expMatchSubstrs: []string{"name: foo\n      namespace: foo-ns"},
+		},
 		{
 			desc: "success: two patches, one object",
 			opts: map[string]interface{}{
@@ -671,6 +835,44 @@ spec:
 	}
 }
 
+func TestPatchTemplateLimits(t *testing.T) {
+	comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  componentName: limits
+  objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: foo
+  - kind: PatchTemplate
+    template: |
+      kind: Pod
+      metadata:
+        annotations:
+          {{- range $i, $_ := .Items}}
+          a{{$i}}: {{range $.Items}}x{{end}}
+          {{- end}}
+`).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options.JSONOptions{"Items": make([]interface{}, 20)}
+
+	if _, err := NewApplierWithConfig(WithTemplateLimits(options.TemplateLimits{MaxOutputBytes: 1000, MaxDepth: 2})).ApplyOptions(comp, opts); err != nil {
+		t.Fatalf("ApplyOptions() within limits: got error %v", err)
+	}
+
+	_, err = NewApplierWithConfig(WithTemplateLimits(options.TemplateLimits{MaxOutputBytes: 100})).ApplyOptions(comp, opts)
+	var lerr *options.TemplateLimitError
+	if !errors.As(err, &lerr) || lerr.Limit != options.TemplateOutputLimit {
+		t.Fatalf("ApplyOptions() exceeding output size: got error %v, expected a TemplateLimitError for %s", err, options.TemplateOutputLimit)
+	}
+	if !strings.Contains(err.Error(), "patch template 0") {
+		t.Errorf("got error %q, expected it to name the patch template", err)
+	}
+}
+
 func TestConvertToFloat(t *testing.T) {
 	testCases := []struct {
 		desc   string
@@ -0,0 +1,218 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replacetmpl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// pathElem is a single element of a field path.
+type pathElem struct {
+	// field is the name of a map field. It is empty for list elements.
+	field string
+
+	// key and value select the element of a list whose field key has the
+	// value, for path elements of the form '[key=value]'.
+	key, value string
+
+	// index selects the element of a list by index, for integer path
+	// elements. It is -1 otherwise.
+	index int
+}
+
+// String returns the string form of the path element.
+func (e pathElem) String() string {
+	switch {
+	case e.key != "":
+		return "[" + e.key + "=" + e.value + "]"
+	case e.index >= 0:
+		return strconv.Itoa(e.index)
+	default:
+		return e.field
+	}
+}
+
+// isList returns whether the path element selects an element of a list.
+func (e pathElem) isList() bool {
+	return e.key != "" || e.index >= 0
+}
+
+// parseFieldPath parses a dot-separated field path such as
+// 'spec.containers.[name=app].image'. The value in a '[key=value]' element
+// may contain dots.
+func parseFieldPath(p string) ([]pathElem, error) {
+	if p == "" {
+		return nil, fmt.Errorf("empty field path")
+	}
+	var elems []pathElem
+	for len(p) > 0 {
+		var seg string
+		if p[0] == '[' {
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in field path")
+			}
+			seg, p = p[:end+1], p[end+1:]
+			if len(p) > 0 && p[0] != '.' {
+				return nil, fmt.Errorf("expected '.' after %q in field path", seg)
+			}
+		} else if i := strings.IndexByte(p, '.'); i >= 0 {
+			seg, p = p[:i], p[i:]
+		} else {
+			seg, p = p, ""
+		}
+		if len(p) > 0 {
+			// Skip the separator, which must not end the path.
+			p = p[1:]
+			if p == "" {
+				return nil, fmt.Errorf("field path ends with '.'")
+			}
+		}
+		elem, err := parsePathElem(seg)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// parsePathElem parses a single path element.
+func parsePathElem(seg string) (pathElem, error) {
+	if seg == "" {
+		return pathElem{}, fmt.Errorf("empty element in field path")
+	}
+	if strings.HasPrefix(seg, "[") {
+		kv := strings.SplitN(seg[1:len(seg)-1], "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return pathElem{}, fmt.Errorf("list element %q in field path must have the form [key=value]", seg)
+		}
+		return pathElem{key: kv[0], value: kv[1], index: -1}, nil
+	}
+	if i, err := strconv.Atoi(seg); err == nil {
+		if i < 0 {
+			return pathElem{}, fmt.Errorf("negative index %d in field path", i)
+		}
+		return pathElem{index: i}, nil
+	}
+	return pathElem{field: seg, index: -1}, nil
+}
+
+// pathString returns the string form of a field path.
+func pathString(path []pathElem) string {
+	var parts []string
+	for _, e := range path {
+		parts = append(parts, e.String())
+	}
+	return strings.Join(parts, ".")
+}
+
+// child returns the value selected by a path element, and whether it was
+// found. An error is returned if the value has the wrong type for the path
+// element.
+func child(val interface{}, e pathElem) (interface{}, bool, error) {
+	if !e.isList() {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("expected a map for field %q, but found %T", e.field, val)
+		}
+		c, ok := m[e.field]
+		return c, ok, nil
+	}
+	l, ok := val.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("expected a list for %q, but found %T", e, val)
+	}
+	i := listIndex(l, e)
+	if i < 0 {
+		return nil, false, nil
+	}
+	return l[i], true, nil
+}
+
+// listIndex returns the index of the list element selected by a path
+// element, or -1 if there is none.
+func listIndex(l []interface{}, e pathElem) int {
+	if e.key == "" {
+		if e.index < len(l) {
+			return e.index
+		}
+		return -1
+	}
+	for i, item := range l {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := m[e.key]; ok && fmt.Sprint(v) == e.value {
+			return i
+		}
+	}
+	return -1
+}
+
+// getField returns the value at a field path, and whether it was found.
+func getField(obj map[string]interface{}, path []pathElem) (interface{}, bool, error) {
+	var val interface{} = obj
+	for _, e := range path {
+		c, ok, err := child(val, e)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		val = c
+	}
+	return val, true, nil
+}
+
+// setField sets the value at a field path to a copy of val, and returns
+// whether it was set. If create is true, missing map fields along the path
+// are created; missing list elements never are.
+func setField(obj map[string]interface{}, path []pathElem, val interface{}, create bool) (bool, error) {
+	var cur interface{} = obj
+	for i, e := range path {
+		last := i == len(path)-1
+		c, ok, err := child(cur, e)
+		if err != nil {
+			return false, err
+		}
+		if !ok && (!create || e.isList()) {
+			return false, nil
+		}
+		if last {
+			val = runtime.DeepCopyJSONValue(val)
+			if !e.isList() {
+				cur.(map[string]interface{})[e.field] = val
+			} else {
+				l := cur.([]interface{})
+				l[listIndex(l, e)] = val
+			}
+			return true, nil
+		}
+		if !ok {
+			if path[i+1].isList() {
+				// Lists can't be created, since their elements can't be.
+				return false, nil
+			}
+			c = make(map[string]interface{})
+			cur.(map[string]interface{})[e.field] = c
+		}
+		cur = c
+	}
+	return false, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replacetmpl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	testCases := []struct {
+		path         string
+		exp          string
+		expErrSubstr string
+	}{
+		{path: "metadata.name", exp: "metadata.name"},
+		{path: "spec.containers.[name=app].env.0.value", exp: "spec.containers.[name=app].env.0.value"},
+		{path: "data.[host=a.example.com].port", exp: "data.[host=a.example.com].port"},
+		{path: "", expErrSubstr: "empty field path"},
+		{path: "spec..name", expErrSubstr: "empty element"},
+		{path: "spec.", expErrSubstr: "ends with '.'"},
+		{path: "spec.[name=app", expErrSubstr: "unterminated"},
+		{path: "spec.[name]", expErrSubstr: "must have the form [key=value]"},
+		{path: "spec.[a=b]c", expErrSubstr: "expected '.'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			got, err := parseFieldPath(tc.path)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if s := pathString(got); s != tc.exp {
+				t.Errorf("got path %q, expected %q", s, tc.exp)
+			}
+		})
+	}
+}
+
+func TestGetAndSetField(t *testing.T) {
+	newObj := func() map[string]interface{} {
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:1"},
+					map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		path     string
+		val      interface{}
+		create   bool
+		expSet   bool
+		expValue interface{}
+		expErr   string
+	}{
+		{desc: "by key", path: "spec.containers.[name=sidecar].image", val: "sidecar:2", expSet: true, expValue: "sidecar:2"},
+		{desc: "by index", path: "spec.containers.0.image", val: "app:2", expSet: true, expValue: "app:2"},
+		{desc: "missing field", path: "spec.replicas", val: int64(3)},
+		{desc: "create field", path: "spec.template.replicas", val: int64(3), create: true, expSet: true, expValue: int64(3)},
+		{desc: "missing list element", path: "spec.containers.[name=nope].image", val: "x", create: true},
+		{desc: "index out of range", path: "spec.containers.5.image", val: "x"},
+		{desc: "map instead of list", path: "spec.[name=app]", val: "x", expErr: "expected a list"},
+		{desc: "list instead of map", path: "spec.containers.name", val: "x", expErr: "expected a map"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			obj := newObj()
+			path, err := parseFieldPath(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, err := setField(obj, path, tc.val, tc.create)
+			if cerr := testutil.CheckErrorCases(err, tc.expErr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if set != tc.expSet {
+				t.Fatalf("got set %t, expected %t", set, tc.expSet)
+			}
+			got, found, err := getField(obj, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.expSet {
+				if found {
+					t.Errorf("got value %v, expected field to not exist", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.expValue) {
+				t.Errorf("got value %v, expected %v", got, tc.expValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replacetmpl is an applier that copies values between the objects of
+// a component, as described by ReplacementTemplates.
+package replacetmpl
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/filter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+// defaultFieldPath is the field path of a source, if not specified.
+const defaultFieldPath = "metadata.name"
+
+// templateKinds are the kinds of the templates in a component, which are never
+// the targets of replacements unless a target selector names their kind.
+var templateKinds = map[string]bool{
+	"ObjectTemplate":        true,
+	"ObjectTemplateBuilder": true,
+	"PatchTemplate":         true,
+	"PatchTemplateBuilder":  true,
+	"ReplacementTemplate":   true,
+}
+
+// ApplierConfig is a config option that can be passed to NewApplier.
+type ApplierConfig func(*applier)
+
+// applier applies ReplacementTemplates.
+type applier struct {
+	// phase of the ReplacementTemplates to apply.
+	phase bundle.ReplacementPhase
+
+	// If includeTemplates is true, applied ReplacementTemplates will be
+	// included in the component objects.
+	includeTemplates bool
+}
+
+// WithPhase modifies NewApplier so that the returned Applier only applies
+// ReplacementTemplates of the specified phase.
+func WithPhase(phase bundle.ReplacementPhase) ApplierConfig {
+	return func(a *applier) {
+		a.phase = phase
+	}
+}
+
+// WithIncludeTemplates modifies NewApplier so that the returned Applier
+// includes or doesn't include the applied ReplacementTemplates.
+func WithIncludeTemplates(include bool) ApplierConfig {
+	return func(a *applier) {
+		a.includeTemplates = include
+	}
+}
+
+// NewApplier creates a new options applier instance using the specified
+// ApplierConfigs. By default, it applies the ReplacementTemplates of the
+// AfterPatches phase.
+func NewApplier(opts ...ApplierConfig) options.Applier {
+	a := &applier{phase: bundle.ReplacementPhaseAfterPatches}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// ApplyOptions applies the ReplacementTemplates of the applier's phase to the
+// component objects, in the order they appear in the component. The options
+// are not used.
+func (a *applier) ApplyOptions(comp *bundle.Component, _ options.JSONOptions) (*bundle.Component, error) {
+	comp = comp.DeepCopy()
+
+	var tmpls []*bundle.ReplacementTemplate
+	var objs []*unstructured.Unstructured
+	for _, obj := range comp.Spec.Objects {
+		if obj.GetKind() != "ReplacementTemplate" {
+			objs = append(objs, obj)
+			continue
+		}
+		rt := &bundle.ReplacementTemplate{}
+		if err := converter.FromUnstructured(obj).ToObject(rt); err != nil {
+			return nil, fmt.Errorf("while converting object %q to ReplacementTemplate: %v", obj.GetName(), err)
+		}
+		phase := rt.Phase
+		if phase == "" {
+			phase = bundle.ReplacementPhaseAfterPatches
+		}
+		switch phase {
+		case bundle.ReplacementPhaseBeforePatches, bundle.ReplacementPhaseAfterPatches:
+		default:
+			return nil, fmt.Errorf("unknown phase %q for ReplacementTemplate %q", rt.Phase, rt.GetName())
+		}
+		if phase != a.phase {
+			objs = append(objs, obj)
+			continue
+		}
+		if a.includeTemplates {
+			objs = append(objs, obj)
+		}
+		tmpls = append(tmpls, rt)
+	}
+
+	for _, rt := range tmpls {
+		for i, r := range rt.Replacements {
+			if err := replace(r, objs); err != nil {
+				return nil, fmt.Errorf("for component %v, replacement %d of ReplacementTemplate %q: %v",
+					comp.ComponentReference(), i, rt.GetName(), err)
+			}
+		}
+	}
+	comp.Spec.Objects = objs
+	return comp, nil
+}
+
+// replace applies a single replacement to the objects, modifying them in
+// place.
+func replace(r bundle.Replacement, objs []*unstructured.Unstructured) error {
+	src, err := findSource(r.Source, objs)
+	if err != nil {
+		return err
+	}
+	srcFieldPath := r.Source.FieldPath
+	if srcFieldPath == "" {
+		srcFieldPath = defaultFieldPath
+	}
+	srcPath, err := parseFieldPath(srcFieldPath)
+	if err != nil {
+		return fmt.Errorf("source field path %q: %v", srcFieldPath, err)
+	}
+	val, found, err := getField(src.Object, srcPath)
+	if err != nil {
+		return fmt.Errorf("source field path %q of %s %q: %v", srcFieldPath, src.GetKind(), src.GetName(), err)
+	}
+	if !found {
+		return fmt.Errorf("source field path %q not found in %s %q", srcFieldPath, src.GetKind(), src.GetName())
+	}
+
+	for j, t := range r.Targets {
+		fopts := filter.OptionsFromObjectSelector(t.Selector)
+		if err := fopts.Validate(); err != nil {
+			return fmt.Errorf("target %d: invalid selector: %v", j, err)
+		}
+		var paths [][]pathElem
+		for _, fp := range t.FieldPaths {
+			p, err := parseFieldPath(fp)
+			if err != nil {
+				return fmt.Errorf("target %d: field path %q: %v", j, fp, err)
+			}
+			paths = append(paths, p)
+		}
+		for _, obj := range objs {
+			if obj == src || (templateKinds[obj.GetKind()] && !selectsKind(t.Selector, obj)) || !filter.MatchesObject(obj, fopts) {
+				continue
+			}
+			for _, p := range paths {
+				if _, err := setField(obj.Object, p, val, t.Create); err != nil {
+					return fmt.Errorf("target %d: field path %q of %s %q: %v", j, pathString(p), obj.GetKind(), obj.GetName(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// selectsKind returns whether the selector explicitly names the kind of the
+// object, which is required for templates to be targets.
+func selectsKind(sel *bundle.ObjectSelector, obj *unstructured.Unstructured) bool {
+	return sel != nil && len(sel.Kinds) > 0 && filter.MatchesObject(obj, &filter.Options{Kinds: sel.Kinds})
+}
+
+// findSource returns the single object matching the source.
+func findSource(s bundle.ReplacementSource, objs []*unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	fopts := &filter.Options{}
+	if s.Kind != "" {
+		fopts.Kinds = []string{s.Kind}
+	}
+	if s.Name != "" {
+		fopts.Names = []string{s.Name}
+	}
+	if err := fopts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid source: %v", err)
+	}
+	var matches []*unstructured.Unstructured
+	for _, obj := range objs {
+		if !templateKinds[obj.GetKind()] && filter.MatchesObject(obj, fopts) {
+			matches = append(matches, obj)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no object found for source with kind %q and name %q", s.Kind, s.Name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d objects found for source with kind %q and name %q, but expected exactly one", len(matches), s.Kind, s.Name)
+	}
+}
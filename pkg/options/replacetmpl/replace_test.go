@@ -0,0 +1,295 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replacetmpl
+
+import (
+	"strings"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+var objects = `
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: backend-svc
+    spec:
+      ports:
+      - name: http
+        port: 8080
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config-abc123
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: frontend
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+            env:
+            - name: BACKEND_HOST
+              value: PLACEHOLDER
+            - name: BACKEND_PORT
+              value: PLACEHOLDER
+          volumes:
+          - name: config
+            configMap:
+              name: config
+`
+
+func TestApplyOptions(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		templates       string
+		phase           bundle.ReplacementPhase
+		includeTemplate bool
+		expMatchSubstrs []string
+		expNoMatch      []string
+		expErrSubstr    string
+	}{
+		{
+			desc: "success: name, port and configmap",
+			templates: `
+  - kind: ReplacementTemplate
+    metadata:
+      name: wiring
+    replacements:
+    - source:
+        kind: Service
+        name: backend-svc
+      targets:
+      - selector:
+          kinds: [Deployment]
+        fieldPaths:
+        - spec.template.spec.containers.[name=app].env.[name=BACKEND_HOST].value
+    - source:
+        kind: Service
+        name: backend-svc
+        fieldPath: spec.ports.[name=http].port
+      targets:
+      - selector:
+          kinds: [Deployment]
+        fieldPaths:
+        - spec.template.spec.containers.[name=app].env.[name=BACKEND_PORT].value
+    - source:
+        kind: ConfigMap
+        name: config-*
+      targets:
+      - fieldPaths:
+        - spec.template.spec.volumes.[name=config].configMap.name
+`,
+			expMatchSubstrs: []string{
+				"name: BACKEND_HOST\n              value: backend-svc",
+				"name: BACKEND_PORT\n              value: 8080",
+				"configMap:\n              name: config-abc123",
+			},
+			expNoMatch: []string{"PLACEHOLDER", "ReplacementTemplate"},
+		},
+		{
+			desc: "success: create missing field",
+			templates: `
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Service
+      targets:
+      - selector:
+          kinds: [Deployment]
+        fieldPaths:
+        - metadata.annotations.backend
+        create: true
+`,
+			expMatchSubstrs: []string{"annotations:\n        backend: backend-svc"},
+		},
+		{
+			desc: "success: missing target field is left alone",
+			templates: `
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Service
+      targets:
+      - fieldPaths:
+        - metadata.annotations.backend
+`,
+			expNoMatch: []string{"backend: backend-svc"},
+		},
+		{
+			desc: "success: other phase is left alone",
+			templates: `
+  - kind: ReplacementTemplate
+    phase: BeforePatches
+    replacements:
+    - source:
+        kind: Service
+      targets:
+      - fieldPaths:
+        - metadata.name
+`,
+			expMatchSubstrs: []string{"kind: ReplacementTemplate", "name: frontend"},
+		},
+		{
+			desc:            "success: include templates",
+			phase:           bundle.ReplacementPhaseBeforePatches,
+			includeTemplate: true,
+			templates: `
+  - kind: ReplacementTemplate
+    phase: BeforePatches
+    replacements:
+    - source:
+        kind: ConfigMap
+      targets:
+      - selector:
+          kinds: [Deployment]
+        fieldPaths:
+        - spec.template.spec.volumes.[name=config].configMap.name
+`,
+			expMatchSubstrs: []string{"kind: ReplacementTemplate", "name: config-abc123\n            name: config"},
+		},
+		{
+			desc: "success: templates are not targets of selectors without their kind",
+			templates: `
+  - kind: PatchTemplate
+    metadata:
+      name: backend-patch
+    template: PLACEHOLDER
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Service
+      targets:
+      - selector:
+          names: [backend-*]
+        fieldPaths: [template]
+`,
+			expMatchSubstrs: []string{"template: PLACEHOLDER"},
+		},
+		{
+			desc: "success: templates are targets of selectors with their kind",
+			templates: `
+  - kind: PatchTemplate
+    metadata:
+      name: backend-patch
+    template: PLACEHOLDER
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Service
+      targets:
+      - selector:
+          kinds: [PatchTemplate]
+          names: [backend-*]
+        fieldPaths: [template]
+`,
+			expMatchSubstrs: []string{"template: backend-svc"},
+		},
+		{
+			desc: "error: ambiguous source",
+			templates: `
+  - kind: ReplacementTemplate
+    metadata:
+      name: bad
+    replacements:
+    - source:
+        kind: regex:Service|ConfigMap
+      targets:
+      - fieldPaths: [metadata.name]
+`,
+			expErrSubstr: `replacement 0 of ReplacementTemplate "bad": 2 objects found for source`,
+		},
+		{
+			desc: "error: missing source",
+			templates: `
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Secret
+      targets:
+      - fieldPaths: [metadata.name]
+`,
+			expErrSubstr: "no object found for source",
+		},
+		{
+			desc: "error: missing source field",
+			templates: `
+  - kind: ReplacementTemplate
+    replacements:
+    - source:
+        kind: Service
+        fieldPath: spec.clusterIP
+      targets:
+      - fieldPaths: [metadata.name]
+`,
+			expErrSubstr: `source field path "spec.clusterIP" not found in Service "backend-svc"`,
+		},
+		{
+			desc: "error: bad phase",
+			templates: `
+  - kind: ReplacementTemplate
+    metadata:
+      name: bad
+    phase: Sometime
+`,
+			expErrSubstr: `unknown phase "Sometime"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  componentName: replace
+  objects:` + objects + tc.templates).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cfgs []ApplierConfig
+			if tc.phase != "" {
+				cfgs = append(cfgs, WithPhase(tc.phase))
+			}
+			cfgs = append(cfgs, WithIncludeTemplates(tc.includeTemplate))
+
+			newComp, err := NewApplier(cfgs...).ApplyOptions(comp, nil)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			compStr, err := converter.FromObject(newComp).ToYAMLString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tc.expMatchSubstrs {
+				if !strings.Contains(compStr, s) {
+					t.Errorf("expected component to contain %q, but got\n%s", s, compStr)
+				}
+			}
+			for _, s := range tc.expNoMatch {
+				if strings.Contains(compStr, s) {
+					t.Errorf("expected component to not contain %q, but got\n%s", s, compStr)
+				}
+			}
+		})
+	}
+}
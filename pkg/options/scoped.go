@@ -0,0 +1,112 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"sort"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+)
+
+// GlobalScope is the key in component-scoped options whose options apply to
+// every component. Because of this, 'global' can't be used to scope options
+// to a component of the same name.
+const GlobalScope = "global"
+
+// ComponentOptionsFunc returns the options to apply to a specific component.
+type ComponentOptionsFunc func(comp *bundle.Component) (JSONOptions, error)
+
+// SameForAll returns a ComponentOptionsFunc that provides the same options to
+// every component.
+func SameForAll(opts JSONOptions) ComponentOptionsFunc {
+	return func(_ *bundle.Component) (JSONOptions, error) {
+		return opts, nil
+	}
+}
+
+// ByComponentName returns a ComponentOptionsFunc for component-scoped options.
+// See ForComponent for a description of component-scoped options.
+func ByComponentName(scoped JSONOptions) ComponentOptionsFunc {
+	return func(comp *bundle.Component) (JSONOptions, error) {
+		return ForComponent(scoped, comp.Spec.ComponentName)
+	}
+}
+
+// ForComponent returns the options for a single component from
+// component-scoped options. Component-scoped options are keyed by component
+// name, with an optional GlobalScope section that applies to all components:
+//
+//	global:
+//	  Namespace: kube-system
+//	etcd:
+//	  Namespace: etcd-system
+//	kubedns:
+//	  Replicas: 2
+//
+// The component's options are deep-merged on top of the global options, as
+// described in Merge. If the scoped options are nil, nil is returned,
+// indicating that no options were provided.
+func ForComponent(scoped JSONOptions, componentName string) (JSONOptions, error) {
+	if scoped == nil {
+		return nil, nil
+	}
+	global, err := scopeSection(scoped, GlobalScope)
+	if err != nil {
+		return nil, err
+	}
+	comp, err := scopeSection(scoped, componentName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Merge(global, comp), nil
+}
+
+// UnknownScopes returns the sorted keys of component-scoped options that are
+// neither GlobalScope nor the name of one of the components. The options
+// under such keys, usually misspelled component names, would never be
+// applied.
+func UnknownScopes(scoped JSONOptions, comps []*bundle.Component) []string {
+	names := map[string]bool{GlobalScope: true}
+	for _, c := range comps {
+		names[c.Spec.ComponentName] = true
+	}
+	var unknown []string
+	for key := range scoped {
+		if !names[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// scopeSection returns the options under a single key of component-scoped
+// options.
+func scopeSection(scoped JSONOptions, key string) (map[string]interface{}, error) {
+	val, ok := scoped[key]
+	if !ok || val == nil {
+		return nil, nil
+	}
+	switch section := val.(type) {
+	case map[string]interface{}:
+		return section, nil
+	case JSONOptions:
+		return section, nil
+	default:
+		return nil, fmt.Errorf("component-scoped options for %q must be a map, but was %T", key, val)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"reflect"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestForComponent(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		scoped       JSONOptions
+		compName     string
+		exp          JSONOptions
+		expErrSubstr string
+	}{
+		{
+			desc:     "nil options",
+			compName: "etcd",
+		},
+		{
+			desc: "global only",
+			scoped: JSONOptions{
+				"global": map[string]interface{}{"Namespace": "kube-system"},
+			},
+			compName: "etcd",
+			exp:      JSONOptions{"Namespace": "kube-system"},
+		},
+		{
+			desc: "component overrides global",
+			scoped: JSONOptions{
+				"global":  map[string]interface{}{"Namespace": "kube-system", "Foo": "bar"},
+				"etcd":    map[string]interface{}{"Namespace": "etcd-system"},
+				"kubedns": map[string]interface{}{"Namespace": "dns-system"},
+			},
+			compName: "etcd",
+			exp:      JSONOptions{"Namespace": "etcd-system", "Foo": "bar"},
+		},
+		{
+			desc:     "no matching section",
+			scoped:   JSONOptions{"kubedns": map[string]interface{}{"Namespace": "dns-system"}},
+			compName: "etcd",
+			exp:      JSONOptions{},
+		},
+		{
+			desc:         "error: section not a map",
+			scoped:       JSONOptions{"etcd": "foo"},
+			compName:     "etcd",
+			expErrSubstr: "must be a map",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ForComponent(tc.scoped, tc.compName)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got options %v, expected %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestUnknownScopes(t *testing.T) {
+	comps := []*bundle.Component{
+		{Spec: bundle.ComponentSpec{ComponentName: "etcd"}},
+		{Spec: bundle.ComponentSpec{ComponentName: "kubedns"}},
+	}
+	scoped := JSONOptions{
+		"global":  map[string]interface{}{"Namespace": "kube-system"},
+		"etcd":    map[string]interface{}{"Namespace": "etcd-system"},
+		"kubdns":  map[string]interface{}{"Replicas": 2},
+		"kubeapi": map[string]interface{}{"Replicas": 3},
+	}
+	exp := []string{"kubdns", "kubeapi"}
+	if got := UnknownScopes(scoped, comps); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got unknown scopes %v, expected %v", got, exp)
+	}
+	if got := UnknownScopes(nil, comps); got != nil {
+		t.Errorf("got unknown scopes %v for nil options, expected none", got)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package starlarktmpl creates objects from ObjectTemplate objects for
+// ObjectTemplates of type "starlark". Once the options are applied, the
+// ObjectTemplate is removed from the component's list of objects.
+//
+// The template is a Starlark script (https://github.com/bazelbuild/starlark).
+// The options are provided to the script as a frozen dict named 'options',
+// and the script must assign the objects it generates to a global named
+// 'objects', either as a list of dicts or as a single dict. For example:
+//
+//	objects = [
+//	    {
+//	        "apiVersion": "v1",
+//	        "kind": "ConfigMap",
+//	        "metadata": {"name": "config-%d" % i},
+//	    }
+//	    for i in range(options["count"])
+//	]
+//
+// Scripts are sandboxed: the Starlark language has no access to the
+// filesystem, network, or clock, 'load' statements are rejected, and the
+// number of execution steps is bounded.
+package starlarktmpl
+
+import (
+	"fmt"
+	"math"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options/openapi"
+)
+
+const (
+	// DefaultMaxExecutionSteps is the default bound on the number of Starlark
+	// execution steps for a single template.
+	DefaultMaxExecutionSteps uint64 = 1000000
+
+	// optionsVar is the predeclared name containing the options.
+	optionsVar = "options"
+
+	// objectsVar is the global that the script must assign its objects to.
+	objectsVar = "objects"
+)
+
+// ApplierConfig is a config option that can be passed to NewApplier.
+type ApplierConfig func(*applier)
+
+// applier applies options to Starlark ObjectTemplates.
+type applier struct {
+	maxSteps uint64
+}
+
+// WithMaxExecutionSteps modifies NewApplier so that the returned Applier
+// bounds each script to the given number of execution steps.
+func WithMaxExecutionSteps(steps uint64) ApplierConfig {
+	return func(a *applier) {
+		a.maxSteps = steps
+	}
+}
+
+// NewApplier creates a new options applier instance using the specified
+// ApplierConfigs.
+func NewApplier(opts ...ApplierConfig) options.Applier {
+	a := &applier{maxSteps: DefaultMaxExecutionSteps}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// ApplyOptions runs each Starlark ObjectTemplate with the options and replaces
+// the template with the objects it generates.
+func (a *applier) ApplyOptions(comp *bundle.Component, opts options.JSONOptions) (*bundle.Component, error) {
+	comp = comp.DeepCopy()
+
+	matched, notMatched := options.PartitionObjectTemplates(comp.Spec.Objects, string(bundle.TemplateTypeStarlark))
+
+	newObjs, err := options.ApplyCommon(comp.ComponentReference(), matched, opts, a.applyOptions)
+	if err != nil {
+		return comp, err
+	}
+	comp.Spec.Objects = append(notMatched, newObjs...)
+	return comp, nil
+}
+
+func (a *applier) applyOptions(obj *unstructured.Unstructured, ref bundle.ComponentReference, opts options.JSONOptions) ([]*unstructured.Unstructured, error) {
+	objTmpl := &bundle.ObjectTemplate{}
+	err := converter.FromUnstructured(obj).ToObject(objTmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if objTmpl.OptionsSchema != nil {
+		opts, err = openapi.ApplyDefaults(opts, objTmpl.OptionsSchema)
+		if err != nil {
+			return nil, fmt.Errorf("applying schema defaults for object template named %q: %v", obj.GetName(), err)
+		}
+	}
+
+	optsVal, err := toStarlark(map[string]interface{}(opts))
+	if err != nil {
+		return nil, fmt.Errorf("converting options for object template %q: %v", obj.GetName(), err)
+	}
+	optsVal.Freeze()
+
+	name := ref.ComponentName + "-" + obj.GetName()
+	thread := &starlark.Thread{
+		Name: name,
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("load of %q is not allowed in starlark templates", module)
+		},
+		Print: func(_ *starlark.Thread, _ string) {},
+	}
+	thread.SetMaxExecutionSteps(a.maxSteps)
+
+	fileOpts := &syntax.FileOptions{
+		Set:             true,
+		TopLevelControl: true,
+		GlobalReassign:  true,
+	}
+	globals, err := starlark.ExecFileOptions(fileOpts, thread, name+".star", objTmpl.Template, starlark.StringDict{optionsVar: optsVal})
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return nil, fmt.Errorf("error executing starlark template for object %q: %s", obj.GetName(), evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("error executing starlark template for object %q: %v", obj.GetName(), err)
+	}
+
+	objsVal, ok := globals[objectsVar]
+	if !ok {
+		return nil, fmt.Errorf("starlark template for object %q did not set %q", obj.GetName(), objectsVar)
+	}
+	var results []starlark.Value
+	switch v := objsVal.(type) {
+	case *starlark.Dict:
+		results = []starlark.Value{v}
+	case *starlark.List:
+		for i := 0; i < v.Len(); i++ {
+			results = append(results, v.Index(i))
+		}
+	case starlark.Tuple:
+		results = v
+	default:
+		return nil, fmt.Errorf("starlark template for object %q: %q must be a list or dict, but was %s", obj.GetName(), objectsVar, objsVal.Type())
+	}
+
+	var out []*unstructured.Unstructured
+	for i, r := range results {
+		conv, err := fromStarlark(r)
+		if err != nil {
+			return nil, fmt.Errorf("starlark template for object %q, object %d: %v", obj.GetName(), i, err)
+		}
+		m, ok := conv.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("starlark template for object %q, object %d: must be a dict, but was %s", obj.GetName(), i, r.Type())
+		}
+		out = append(out, &unstructured.Unstructured{Object: m})
+	}
+	return out, nil
+}
+
+// toStarlark converts a JSON value into a Starlark value.
+func toStarlark(val interface{}) (starlark.Value, error) {
+	switch v := val.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int32:
+		return starlark.MakeInt64(int64(v)), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case float64:
+		// JSON numbers are decoded as floats; present whole numbers as ints so
+		// that they can be used with range() and indexing.
+		if v == math.Trunc(v) && math.Abs(v) < (1<<53) {
+			return starlark.MakeInt64(int64(v)), nil
+		}
+		return starlark.Float(v), nil
+	case []interface{}:
+		var elems []starlark.Value
+		for _, e := range v {
+			se, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, se)
+		}
+		return starlark.NewList(elems), nil
+	case map[string]interface{}:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			se, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.SetKey(starlark.String(k), se); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	case options.JSONOptions:
+		return toStarlark(map[string]interface{}(v))
+	default:
+		return nil, fmt.Errorf("unsupported option value %v of type %T", val, val)
+	}
+}
+
+// fromStarlark converts a Starlark value into a JSON value.
+func fromStarlark(val starlark.Value) (interface{}, error) {
+	switch v := val.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, fmt.Errorf("integer %s is out of range", v)
+		}
+		return i, nil
+	case starlark.Float:
+		return float64(v), nil
+	case *starlark.List:
+		out := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			e, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, e)
+		}
+		return out, nil
+	case starlark.Tuple:
+		out := []interface{}{}
+		for _, e := range v {
+			je, err := fromStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, je)
+		}
+		return out, nil
+	case *starlark.Dict:
+		out := make(map[string]interface{})
+		for _, item := range v.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings, but found key %s of type %s", item[0], item[0].Type())
+			}
+			e, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			out[string(k)] = e
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %s of type %s", val, val.Type())
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starlarktmpl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestStarlarkApplier(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		component    string
+		opts         options.JSONOptions
+		maxSteps     uint64
+		expNames     []string
+		expErrSubstr string
+	}{
+		{
+			desc: "success: list comprehension over options",
+			component: `
+kind: Component
+spec:
+  componentName: data-component
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      objects = [
+          {
+              "apiVersion": "v1",
+              "kind": "ConfigMap",
+              "metadata": {"name": "config-" + name, "namespace": options["Namespace"]},
+              "data": {"replicas": str(options["Replicas"])},
+          }
+          for name in options["Names"]
+      ]
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: untouched-pod
+  - kind: ObjectTemplate
+    type: go-template
+    metadata:
+      name: untouched-go-template
+    template: "kind: Pod"`,
+			opts: options.JSONOptions{
+				"Namespace": "foo",
+				"Replicas":  float64(3),
+				"Names":     []interface{}{"a", "b"},
+			},
+			expNames: []string{"untouched-pod", "untouched-go-template", "config-a", "config-b"},
+		},
+		{
+			desc: "success: single dict and schema defaults",
+			component: `
+kind: Component
+spec:
+  componentName: data-component
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    optionsSchema:
+      properties:
+        Name:
+          type: string
+          default: defaulted
+    template: |
+      def make(name):
+          return {"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": name}}
+      objects = make(options["Name"])`,
+			opts:     options.JSONOptions{},
+			expNames: []string{"defaulted"},
+		},
+		{
+			desc: "error: objects not set",
+			component: `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: "x = 1"`,
+			opts:         options.JSONOptions{},
+			expErrSubstr: `did not set "objects"`,
+		},
+		{
+			desc: "error: options are frozen",
+			component: `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      options["Foo"] = "bar"
+      objects = []`,
+			opts:         options.JSONOptions{},
+			expErrSubstr: "frozen",
+		},
+		{
+			desc: "error: load is not allowed",
+			component: `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      load("foo.star", "bar")
+      objects = []`,
+			opts:         options.JSONOptions{},
+			expErrSubstr: "not allowed",
+		},
+		{
+			desc: "error: step limit",
+			component: `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      objects = []
+      for i in range(1000000):
+          objects.append({"kind": "Pod"})`,
+			opts:         options.JSONOptions{},
+			maxSteps:     1000,
+			expErrSubstr: "too many steps",
+		},
+		{
+			desc: "error: non-dict object",
+			component: `
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      objects = ["foo"]`,
+			opts:         options.JSONOptions{},
+			expErrSubstr: "must be a dict",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			comp, err := converter.FromYAMLString(tc.component).ToComponent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cfgs []ApplierConfig
+			if tc.maxSteps > 0 {
+				cfgs = append(cfgs, WithMaxExecutionSteps(tc.maxSteps))
+			}
+			newComp, err := NewApplier(cfgs...).ApplyOptions(comp, tc.opts)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+
+			var names []string
+			for _, o := range newComp.Spec.Objects {
+				names = append(names, o.GetName())
+			}
+			if !reflect.DeepEqual(names, tc.expNames) {
+				t.Errorf("got object names %v, expected %v", names, tc.expNames)
+			}
+		})
+	}
+}
+
+func TestStarlarkApplierObjectContents(t *testing.T) {
+	comp, err := converter.FromYAMLString(`
+kind: Component
+spec:
+  objects:
+  - kind: ObjectTemplate
+    type: starlark
+    template: |
+      objects = [{
+          "apiVersion": "apps/v1",
+          "kind": "Deployment",
+          "metadata": {"name": "foo"},
+          "spec": {"replicas": options["Replicas"], "paused": False, "selector": None},
+      }]`).ToComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newComp, err := NewApplier().ApplyOptions(comp, options.JSONOptions{"Replicas": float64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec":       map[string]interface{}{"replicas": int64(2), "paused": false, "selector": nil},
+	}
+	if got := newComp.Spec.Objects[0].Object; !reflect.DeepEqual(got, exp) {
+		t.Errorf("got object %v, expected %v", got, exp)
+	}
+}
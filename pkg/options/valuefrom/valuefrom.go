@@ -0,0 +1,327 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package valuefrom resolves options values that reference secrets, rather
+// than containing them in plaintext. An option value that is a map with a
+// single 'valueFrom' key is replaced by the referenced value:
+//
+//	DatabasePassword:
+//	  valueFrom:
+//	    file: /var/run/secrets/db-password
+//	APIToken:
+//	  valueFrom:
+//	    env: API_TOKEN
+//	TLSKey:
+//	  valueFrom:
+//	    secretKeyRef:
+//	      file: secrets/tls-secret.yaml
+//	      key: tls.key
+//
+// A 'file' reference resolves to the contents of the file, an 'env' reference
+// to the value of the environment variable, and a 'secretKeyRef' to the value
+// of a key in a Kubernetes Secret manifest on the local filesystem. Resolved
+// values are always strings.
+//
+// Because resolved values are sensitive, the resolver returns a Redactor that
+// should be used to scrub the values from any errors or log messages produced
+// while applying the options.
+package valuefrom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/files"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+)
+
+// ValueFromKey is the key that marks an option value as a reference.
+const ValueFromKey = "valueFrom"
+
+// Redacted is the text that replaces resolved values in redacted strings.
+const Redacted = "<redacted>"
+
+// minRedactedLength is the length below which resolved values aren't
+// redacted. Short values such as "1" or "true" are unlikely to be secrets,
+// and replacing them would scrub unrelated text from every message.
+const minRedactedLength = 6
+
+// Source describes where a referenced value comes from. Exactly one field
+// must be set.
+type Source struct {
+	// File is the path of a file whose contents are the value.
+	File string `json:"file,omitempty"`
+
+	// Env is the name of an environment variable whose value is the value.
+	Env string `json:"env,omitempty"`
+
+	// SecretKeyRef selects a key in a Kubernetes Secret manifest.
+	SecretKeyRef *SecretKeyRef `json:"secretKeyRef,omitempty"`
+}
+
+// SecretKeyRef selects a key of a Secret stored in a local manifest file.
+type SecretKeyRef struct {
+	// File is the path of the Secret manifest, in YAML or JSON.
+	File string `json:"file"`
+
+	// Key is the key in the Secret's data or stringData.
+	Key string `json:"key"`
+}
+
+// Resolver resolves valueFrom references in options.
+type Resolver struct {
+	// rw reads referenced files.
+	rw files.FileReaderWriter
+
+	// lookupEnv looks up environment variables.
+	lookupEnv func(string) (string, bool)
+}
+
+// NewResolver creates a Resolver that reads files with the given reader and
+// looks up environment variables in the process environment.
+func NewResolver(rw files.FileReaderWriter) *Resolver {
+	return &Resolver{rw: rw, lookupEnv: os.LookupEnv}
+}
+
+// Resolve returns a copy of the options where every valueFrom reference is
+// replaced by its value, along with a Redactor for the resolved values. The
+// input options are not modified. Errors never contain resolved values.
+func (r *Resolver) Resolve(ctx context.Context, opts options.JSONOptions) (options.JSONOptions, *Redactor, error) {
+	red := &Redactor{}
+	if opts == nil {
+		return nil, red, nil
+	}
+	out, err := r.resolve(ctx, map[string]interface{}(opts), "", red)
+	if err != nil {
+		return nil, nil, err
+	}
+	return options.JSONOptions(out.(map[string]interface{})), red, nil
+}
+
+// resolve resolves a single value located at path.
+func (r *Resolver) resolve(ctx context.Context, val interface{}, path string, red *Redactor) (interface{}, error) {
+	switch v := val.(type) {
+	case options.JSONOptions:
+		return r.resolve(ctx, map[string]interface{}(v), path, red)
+	case map[string]interface{}:
+		if ref, ok := v[ValueFromKey]; ok && len(v) == 1 {
+			s, err := r.resolveRef(ctx, ref, path)
+			if err != nil {
+				return nil, err
+			}
+			red.add(s)
+			return s, nil
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			re, err := r.resolve(ctx, e, joinPath(path, k), red)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = re
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for i, e := range v {
+			re, err := r.resolve(ctx, e, fmt.Sprintf("%s[%d]", path, i), red)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, re)
+		}
+		return out, nil
+	default:
+		return val, nil
+	}
+}
+
+// resolveRef resolves the valueFrom reference at path.
+func (r *Resolver) resolveRef(ctx context.Context, ref interface{}, path string) (string, error) {
+	b, err := converter.FromObject(ref).ToJSON()
+	if err != nil {
+		return "", fmt.Errorf("option %q: invalid %s: %v", path, ValueFromKey, err)
+	}
+	src := &Source{}
+	if err := converter.FromJSON(b).ToObject(src); err != nil {
+		return "", fmt.Errorf("option %q: invalid %s: %v", path, ValueFromKey, err)
+	}
+
+	var set []string
+	if src.File != "" {
+		set = append(set, "file")
+	}
+	if src.Env != "" {
+		set = append(set, "env")
+	}
+	if src.SecretKeyRef != nil {
+		set = append(set, "secretKeyRef")
+	}
+	if len(set) != 1 {
+		return "", fmt.Errorf("option %q: %s must set exactly one of file, env or secretKeyRef, but had %v", path, ValueFromKey, set)
+	}
+
+	switch {
+	case src.File != "":
+		b, err := r.rw.ReadFile(ctx, src.File)
+		if err != nil {
+			return "", fmt.Errorf("option %q: reading file %q: %v", path, src.File, err)
+		}
+		return string(b), nil
+
+	case src.Env != "":
+		v, ok := r.lookupEnv(src.Env)
+		if !ok {
+			return "", fmt.Errorf("option %q: environment variable %q is not set", path, src.Env)
+		}
+		return v, nil
+
+	default:
+		return r.resolveSecretKey(ctx, src.SecretKeyRef, path)
+	}
+}
+
+// resolveSecretKey reads a key from a Secret manifest.
+func (r *Resolver) resolveSecretKey(ctx context.Context, ref *SecretKeyRef, path string) (string, error) {
+	if ref.File == "" || ref.Key == "" {
+		return "", fmt.Errorf("option %q: secretKeyRef must set both file and key", path)
+	}
+	b, err := r.rw.ReadFile(ctx, ref.File)
+	if err != nil {
+		return "", fmt.Errorf("option %q: reading secret file %q: %v", path, ref.File, err)
+	}
+	secret := &corev1.Secret{}
+	// Don't include the decoding error, since it may quote the file contents.
+	if err := converter.FromFileName(ref.File, b).ToObject(secret); err != nil {
+		return "", fmt.Errorf("option %q: secret file %q could not be parsed as a Secret", path, ref.File)
+	}
+	if secret.Kind != "Secret" {
+		return "", fmt.Errorf("option %q: file %q must contain a Secret, but had kind %q", path, ref.File, secret.Kind)
+	}
+	if v, ok := secret.StringData[ref.Key]; ok {
+		return v, nil
+	}
+	if v, ok := secret.Data[ref.Key]; ok {
+		return string(v), nil
+	}
+	return "", fmt.Errorf("option %q: key %q not found in Secret %q from file %q", path, ref.Key, secret.Name, ref.File)
+}
+
+// joinPath appends a key to a dot-separated option path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Redactor scrubs resolved secret values from text. The zero value redacts
+// nothing. A nil Redactor is valid and also redacts nothing.
+type Redactor struct {
+	// values are the secret values, longest first, so that a value that
+	// contains another is fully redacted.
+	values []string
+}
+
+// add adds a value to be redacted. Values shorter than minRedactedLength are
+// ignored. Trailing newlines, which are common in secret files, are also
+// redacted in the trimmed form of the value.
+func (r *Redactor) add(val string) {
+	for _, v := range []string{val, strings.TrimRight(val, "\r\n")} {
+		if len(v) < minRedactedLength {
+			continue
+		}
+		found := false
+		for _, e := range r.values {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.values = append(r.values, v)
+		}
+	}
+	sort.SliceStable(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+}
+
+// Redact replaces every occurrence of a resolved value in s.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.Replace(s, v, Redacted, -1)
+	}
+	return s
+}
+
+// RedactError returns an error whose message has been redacted. The returned
+// error wraps err, so typed errors can still be matched with errors.As. A nil
+// error is returned as nil, and errors that contain no resolved values are
+// returned unmodified.
+func (r *Redactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if red := r.Redact(msg); red != msg {
+		return &redactedError{msg: red, err: err}
+	}
+	return err
+}
+
+// redactedError is an error whose message has been redacted. It wraps the
+// original error so that callers can match it with errors.Is and errors.As;
+// only Error() is redacted.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original, unredacted error.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactingApplier wraps an Applier and redacts the errors it returns.
+type redactingApplier struct {
+	applier  options.Applier
+	redactor *Redactor
+}
+
+// NewRedactingApplier wraps an options Applier so that any errors it returns
+// have resolved values redacted.
+func NewRedactingApplier(a options.Applier, r *Redactor) options.Applier {
+	return &redactingApplier{applier: a, redactor: r}
+}
+
+// ApplyOptions applies the options with the wrapped Applier.
+func (a *redactingApplier) ApplyOptions(comp *bundle.Component, opts options.JSONOptions) (*bundle.Component, error) {
+	out, err := a.applier.ApplyOptions(comp, opts)
+	return out, a.redactor.RedactError(err)
+}
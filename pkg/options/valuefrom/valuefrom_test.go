@@ -0,0 +1,251 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package valuefrom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+var secretManifest = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db-creds
+data:
+  password: aHVudGVyMg==
+stringData:
+  user: admin
+`
+
+func newTestResolver() *Resolver {
+	rw := testutil.NewEmptyReaderWriter()
+	rw.AddReadFile(&testutil.FilePair{Path: "token.txt", Contents: "s3cr3t-token\n"})
+	rw.AddReadFile(&testutil.FilePair{Path: "secret.yaml", Contents: secretManifest})
+	rw.AddReadFile(&testutil.FilePair{Path: "cm.yaml", Contents: "kind: ConfigMap\nmetadata:\n  name: foo"})
+	env := map[string]string{"API_KEY": "env-key-value"}
+	return &Resolver{
+		rw: rw,
+		lookupEnv: func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		opts         options.JSONOptions
+		exp          options.JSONOptions
+		expErrSubstr string
+	}{
+		{
+			desc: "no references",
+			opts: options.JSONOptions{"foo": "bar", "biff": []interface{}{1.0}},
+			exp:  options.JSONOptions{"foo": "bar", "biff": []interface{}{1.0}},
+		},
+		{
+			desc: "file, env and secret",
+			opts: options.JSONOptions{
+				"Token": map[string]interface{}{
+					"valueFrom": map[string]interface{}{"file": "token.txt"},
+				},
+				"Nested": map[string]interface{}{
+					"Key": map[string]interface{}{
+						"valueFrom": map[string]interface{}{"env": "API_KEY"},
+					},
+					"Other": "plain",
+				},
+				"Creds": []interface{}{
+					map[string]interface{}{
+						"valueFrom": map[string]interface{}{
+							"secretKeyRef": map[string]interface{}{"file": "secret.yaml", "key": "password"},
+						},
+					},
+					map[string]interface{}{
+						"valueFrom": map[string]interface{}{
+							"secretKeyRef": map[string]interface{}{"file": "secret.yaml", "key": "user"},
+						},
+					},
+				},
+			},
+			exp: options.JSONOptions{
+				"Token": "s3cr3t-token\n",
+				"Nested": map[string]interface{}{
+					"Key":   "env-key-value",
+					"Other": "plain",
+				},
+				"Creds": []interface{}{"hunter2", "admin"},
+			},
+		},
+		{
+			desc: "valueFrom with siblings is not a reference",
+			opts: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": "x", "other": "y"},
+			},
+			exp: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": "x", "other": "y"},
+			},
+		},
+		{
+			desc: "error: missing env",
+			opts: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": map[string]interface{}{"env": "NOPE"}},
+			},
+			expErrSubstr: `option "Foo": environment variable "NOPE" is not set`,
+		},
+		{
+			desc: "error: multiple sources",
+			opts: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": map[string]interface{}{"env": "API_KEY", "file": "token.txt"}},
+			},
+			expErrSubstr: "exactly one of",
+		},
+		{
+			desc: "error: missing secret key",
+			opts: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{"file": "secret.yaml", "key": "nope"},
+				}},
+			},
+			expErrSubstr: `key "nope" not found in Secret "db-creds"`,
+		},
+		{
+			desc: "error: not a secret",
+			opts: options.JSONOptions{
+				"Foo": map[string]interface{}{"valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{"file": "cm.yaml", "key": "foo"},
+				}},
+			},
+			expErrSubstr: `must contain a Secret, but had kind "ConfigMap"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, _, err := newTestResolver().Resolve(context.Background(), tc.opts)
+			if cerr := testutil.CheckErrorCases(err, tc.expErrSubstr); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("got %v, expected %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+type failingApplier struct{}
+
+func (*failingApplier) ApplyOptions(comp *bundle.Component, opts options.JSONOptions) (*bundle.Component, error) {
+	return nil, fmt.Errorf("bad value %v", opts["Token"])
+}
+
+func TestRedaction(t *testing.T) {
+	opts := options.JSONOptions{
+		"Token": map[string]interface{}{
+			"valueFrom": map[string]interface{}{"file": "token.txt"},
+		},
+	}
+	resolved, red, err := newTestResolver().Resolve(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := red.Redact("token is s3cr3t-token."); got != "token is <redacted>." {
+		t.Errorf("got redacted string %q", got)
+	}
+
+	_, err = NewRedactingApplier(&failingApplier{}, red).ApplyOptions(&bundle.Component{}, resolved)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("error %q contains the secret value", err)
+	}
+
+	var nilRed *Redactor
+	if got := nilRed.Redact("s3cr3t-token"); got != "s3cr3t-token" {
+		t.Errorf("nil Redactor redacted %q", got)
+	}
+}
+
+func TestRedaction_ShortValues(t *testing.T) {
+	opts := options.JSONOptions{
+		"User": map[string]interface{}{
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"file": "secret.yaml", "key": "user"},
+			},
+		},
+		"Key": map[string]interface{}{
+			"valueFrom": map[string]interface{}{"env": "API_KEY"},
+		},
+	}
+	_, red, err := newTestResolver().Resolve(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := red.Redact("admin uses env-key-value")
+	if exp := "admin uses <redacted>"; got != exp {
+		t.Errorf("got redacted string %q, expected %q", got, exp)
+	}
+}
+
+type limitApplier struct{}
+
+func (*limitApplier) ApplyOptions(comp *bundle.Component, opts options.JSONOptions) (*bundle.Component, error) {
+	return nil, fmt.Errorf("with %v: %w", opts["Token"], &options.TemplateLimitError{
+		Template: "tmpl",
+		Limit:    options.TemplateDepthLimit,
+		Limits:   options.TemplateLimits{MaxDepth: 1},
+	})
+}
+
+func TestRedaction_KeepsErrorChain(t *testing.T) {
+	opts := options.JSONOptions{
+		"Token": map[string]interface{}{
+			"valueFrom": map[string]interface{}{"file": "token.txt"},
+		},
+	}
+	resolved, red, err := newTestResolver().Resolve(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewRedactingApplier(&limitApplier{}, red).ApplyOptions(&bundle.Component{}, resolved)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("error %q contains the secret value", err)
+	}
+	var lerr *options.TemplateLimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("got error %v, expected a TemplateLimitError", err)
+	}
+	if lerr.Limit != options.TemplateDepthLimit {
+		t.Errorf("got limit %q, expected %q", lerr.Limit, options.TemplateDepthLimit)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"fmt"
+	"path/filepath"
+
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/blang/semver"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	// componentNamePolicies are the valid ComponentNamePolicy values of a
+	// BundleBuilder.
+	componentNamePolicies = map[string]bool{
+		"":                true,
+		"Component":       true,
+		"SetAndComponent": true,
+	}
+
+	// templateTypes are the valid template types of template files.
+	templateTypes = map[bundle.TemplateType]bool{
+		bundle.TemplateTypeUndefined: true,
+		bundle.TemplateTypeGo:        true,
+		bundle.TemplateTypeJsonnet:   true,
+		bundle.TemplateTypeStarlark:  true,
+	}
+)
+
+// BundleBuilder validates a bundle builder. The component files aren't read,
+// so only their URLs are validated.
+func BundleBuilder(b *bundle.BundleBuilder) field.ErrorList {
+	errs := field.ErrorList{}
+	p := field.NewPath("BundleBuilder")
+
+	api := b.APIVersion
+	if !apiVersionPattern.MatchString(api) {
+		errs = append(errs, field.Invalid(p.Child("APIVersion"), api, "must have an apiVersion of the form \"bundle.gke.io/<version>\""))
+	}
+
+	expType := "BundleBuilder"
+	if k := b.Kind; k != expType {
+		errs = append(errs, field.Invalid(p.Child("Kind"), k, "must be BundleBuilder"))
+	}
+
+	if n := b.SetName; n == "" {
+		errs = append(errs, field.Required(p.Child("SetName"), "setName is required"))
+	} else {
+		errs = append(errs, validateName(p.Child("SetName"), n)...)
+	}
+
+	if ver := b.Version; ver == "" {
+		errs = append(errs, field.Required(p.Child("Version"), "version is required"))
+	} else if _, err := semver.Parse(ver); err != nil {
+		errs = append(errs, field.Invalid(p.Child("Version"), ver, fmt.Sprintf("must be a SemVer version: %v", err)))
+	}
+
+	if pol := b.ComponentNamePolicy; !componentNamePolicies[pol] {
+		errs = append(errs, field.NotSupported(p.Child("ComponentNamePolicy"), pol, []string{"Component", "SetAndComponent"}))
+	}
+
+	errs = append(errs, validateFiles(p.Child("ComponentFiles"), b.ComponentFiles)...)
+	return errs
+}
+
+// ComponentBuilder validates a component builder. The files of the builder
+// aren't read, so only their URLs are validated, along with the names of the
+// raw text file groups and the types of the template files.
+func ComponentBuilder(c *bundle.ComponentBuilder) field.ErrorList {
+	errs := field.ErrorList{}
+	p := field.NewPath("ComponentBuilder")
+
+	api := c.APIVersion
+	if !apiVersionPattern.MatchString(api) {
+		errs = append(errs, field.Invalid(p.Child("APIVersion"), api, "must have an apiVersion of the form \"bundle.gke.io/<version>\""))
+	}
+
+	expType := "ComponentBuilder"
+	if k := c.Kind; k != expType {
+		errs = append(errs, field.Invalid(p.Child("Kind"), k, "must be ComponentBuilder"))
+	}
+
+	if n := c.ComponentName; n == "" {
+		errs = append(errs, field.Required(p.Child("ComponentName"), "componentName is required"))
+	} else {
+		errs = append(errs, validateName(p.Child("ComponentName"), n)...)
+	}
+
+	// The version is optional for component builders.
+	if ver := c.Version; ver != "" {
+		if _, err := semver.Parse(ver); err != nil {
+			errs = append(errs, field.Invalid(p.Child("Version"), ver, fmt.Sprintf("must be a SemVer version: %v", err)))
+		}
+	}
+
+	errs = append(errs, validateFiles(p.Child("ObjectFiles"), c.ObjectFiles)...)
+
+	for i, ts := range c.TemplateFiles {
+		tp := p.Child("TemplateFiles").Index(i)
+		if !templateTypes[ts.TemplateType] {
+			errs = append(errs, field.NotSupported(tp.Child("TemplateType"), ts.TemplateType,
+				[]string{string(bundle.TemplateTypeGo), string(bundle.TemplateTypeJsonnet), string(bundle.TemplateTypeStarlark)}))
+		}
+		errs = append(errs, validateFiles(tp.Child("Files"), ts.Files)...)
+	}
+
+	groups := make(map[string]bool)
+	for i, fg := range c.RawTextFiles {
+		gp := p.Child("RawTextFiles").Index(i)
+		// Each file group becomes a ConfigMap with the name of the group, and
+		// data keys from the base names of the files.
+		if fg.Name == "" {
+			errs = append(errs, field.Required(gp.Child("Name"), "file groups must have a name"))
+		} else {
+			for _, e := range validation.IsDNS1123Subdomain(fg.Name) {
+				errs = append(errs, field.Invalid(gp.Child("Name"), fg.Name, e))
+			}
+			if groups[fg.Name] {
+				errs = append(errs, field.Duplicate(gp.Child("Name"), fg.Name))
+			}
+			groups[fg.Name] = true
+		}
+
+		fileErrs := validateFiles(gp.Child("Files"), fg.Files)
+		errs = append(errs, fileErrs...)
+		if len(fileErrs) > 0 {
+			continue
+		}
+		keys := make(map[string]bool)
+		for j, f := range fg.Files {
+			fp := gp.Child("Files").Index(j).Child("URL")
+			key := filepath.Base(f.URL)
+			for _, e := range validation.IsConfigMapKey(key) {
+				errs = append(errs, field.Invalid(fp, f.URL, fmt.Sprintf("file name %q is not a valid ConfigMap key: %s", key, e)))
+			}
+			if keys[key] {
+				errs = append(errs, field.Invalid(fp, f.URL, fmt.Sprintf("file name %q is used by another file in the group", key)))
+			}
+			keys[key] = true
+		}
+	}
+
+	return errs
+}
+
+// validateFiles validates the URLs of a list of files.
+func validateFiles(p *field.Path, files []bundle.File) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, f := range files {
+		fp := p.Index(i).Child("URL")
+		if f.URL == "" {
+			errs = append(errs, field.Required(fp, "files must have a URL"))
+			continue
+		}
+		if _, err := f.ParsedURL(); err != nil {
+			errs = append(errs, field.Invalid(fp, f.URL, err.Error()))
+		}
+	}
+	return errs
+}
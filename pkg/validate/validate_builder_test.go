@@ -0,0 +1,229 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestValidateBundleBuilder(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		builderConfig  string
+		expectedErrors int
+		errorDesc      string
+	}{
+		{
+			desc: "basic bundle builder validation",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: BundleBuilder
+        setName: foo-set
+        version: 1.0.2
+        componentNamePolicy: SetAndComponent
+        componentFiles:
+        - url: file:///etcd/etcd-component.yaml
+        - url: gs://some-bucket/component.yaml`,
+		},
+		{
+			desc: "missing set name and version",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: BundleBuilder`,
+			expectedErrors: 2,
+			errorDesc:      "version is required",
+		},
+		{
+			desc: "invalid kind",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: foo-set
+        version: 1.0.2`,
+			expectedErrors: 1,
+			errorDesc:      "must be BundleBuilder",
+		},
+		{
+			desc: "invalid component name policy",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: BundleBuilder
+        setName: foo-set
+        version: 1.0.2
+        componentNamePolicy: Zork`,
+			expectedErrors: 1,
+			errorDesc:      "BundleBuilder.ComponentNamePolicy: Unsupported value",
+		},
+		{
+			desc: "invalid component files",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: BundleBuilder
+        setName: foo-set
+        version: 1.0.2
+        componentFiles:
+        - url: file:///etcd/etcd-component.yaml
+        - hash: abcd
+        - url: "http://[::1"`,
+			expectedErrors: 2,
+			errorDesc:      "BundleBuilder.ComponentFiles[1].URL: Required value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			b, err := converter.FromYAMLString(tc.builderConfig).ToBundleBuilder()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			errs := BundleBuilder(b)
+			if len(errs) != tc.expectedErrors {
+				t.Fatalf("got %v errors, expected %v: %v", len(errs), tc.expectedErrors, errs)
+			}
+			if err := testutil.CheckErrorCases(errs.ToAggregate(), tc.errorDesc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateComponentBuilder(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		builderConfig  string
+		expectedErrors int
+		errorDesc      string
+	}{
+		{
+			desc: "basic component builder validation",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        componentName: foo-comp
+        version: 1.0.2
+        objectFiles:
+        - url: ./foo-pod.yaml
+        templateFiles:
+        - files:
+          - url: ./default-template.yaml
+        - templateType: jsonnet
+          files:
+          - url: ./template.jsonnet
+        rawTextFiles:
+        - name: foo-group
+          files:
+          - url: ./data/foo.txt
+          - url: ./data/bar.txt
+        - name: bar-group
+          files:
+          - url: ./data/foo.txt`,
+		},
+		{
+			desc: "version is optional",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        componentName: foo-comp`,
+		},
+		{
+			desc: "missing component name, invalid version",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        version: 1.0`,
+			expectedErrors: 2,
+			errorDesc:      "componentName is required",
+		},
+		{
+			desc: "invalid api version",
+			builderConfig: `
+        apiVersion: v1
+        kind: ComponentBuilder
+        componentName: foo-comp`,
+			expectedErrors: 1,
+			errorDesc:      "must have an apiVersion of the form",
+		},
+		{
+			desc: "invalid object and template files",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        componentName: foo-comp
+        objectFiles:
+        - hash: abcd
+        templateFiles:
+        - templateType: mustache
+          files:
+          - url: ./template.mustache`,
+			expectedErrors: 2,
+			errorDesc:      "ComponentBuilder.TemplateFiles[0].TemplateType: Unsupported value: \"mustache\"",
+		},
+		{
+			desc: "invalid file groups",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        componentName: foo-comp
+        rawTextFiles:
+        - files:
+          - url: ./data/foo.txt
+        - name: Foo_Group
+          files:
+          - url: ./data/foo.txt
+        - name: foo-group
+          files:
+          - url: ./data/foo.txt
+        - name: foo-group
+          files:
+          - url: ./data/foo.txt`,
+			expectedErrors: 3,
+			errorDesc:      "ComponentBuilder.RawTextFiles[3].Name: Duplicate value",
+		},
+		{
+			desc: "invalid file group keys",
+			builderConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: ComponentBuilder
+        componentName: foo-comp
+        rawTextFiles:
+        - name: foo-group
+          files:
+          - url: ./data/foo.txt
+          - url: ./other/foo.txt
+          - url: ./data/foo$bar.txt`,
+			expectedErrors: 2,
+			errorDesc:      `file name "foo.txt" is used by another file in the group`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c, err := converter.FromYAMLString(tc.builderConfig).ToComponentBuilder()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			errs := ComponentBuilder(c)
+			if len(errs) != tc.expectedErrors {
+				t.Fatalf("got %v errors, expected %v: %v", len(errs), tc.expectedErrors, errs)
+			}
+			if err := testutil.CheckErrorCases(errs.ToAggregate(), tc.errorDesc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Bundle validates a bundle, its components, and the ComponentSet derived from
+// it. Components must be unique by name and version, but a bundle may contain
+// several versions of the same component.
+func Bundle(b *bundle.Bundle) field.ErrorList {
+	errs := field.ErrorList{}
+	p := field.NewPath("Bundle")
+
+	api := b.APIVersion
+	if !apiVersionPattern.MatchString(api) {
+		errs = append(errs, field.Invalid(p.Child("APIVersion"), api, "must have an apiVersion of the form \"bundle.gke.io/<version>\""))
+	}
+
+	expType := "Bundle"
+	if k := b.Kind; k != expType {
+		errs = append(errs, field.Invalid(p.Child("Kind"), k, "must be Bundle"))
+	}
+
+	errs = append(errs, Components(b.Components)...)
+
+	if b.SetName == "" {
+		errs = append(errs, field.Required(p.Child("SetName"), "setName is required"))
+	}
+	if b.Version == "" {
+		errs = append(errs, field.Required(p.Child("Version"), "version is required"))
+	}
+	cs := b.ComponentSet()
+	if b.SetName == "" || b.Version == "" {
+		// The rest of the ComponentSet validation would repeat the errors above,
+		// but duplicate components are still reported.
+		return append(errs, duplicateComponents(field.NewPath("ComponentSet", "Spec", "Components"), cs.Spec.Components)...)
+	}
+
+	// The ComponentSet validation checks the set name and version, and that the
+	// components are unique.
+	return append(errs, ComponentSet(cs)...)
+}
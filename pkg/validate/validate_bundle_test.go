@@ -0,0 +1,166 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
+)
+
+func TestValidateBundle(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		bundleConfig   string
+		expectedErrors int
+		errorDesc      string
+	}{
+		{
+			desc: "basic bundle validation",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: foo-set
+        version: 1.0.2
+        components:
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.0.2
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.1.0`,
+		},
+		{
+			desc: "missing set name and version",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle`,
+			expectedErrors: 2,
+			errorDesc:      "setName is required",
+		},
+		{
+			desc: "invalid set name",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: Foo_Set!
+        version: 1.0.2`,
+			expectedErrors: 1,
+			errorDesc:      "ComponentSet.Spec.SetName",
+		},
+		{
+			desc: "invalid version",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: foo-set
+        version: 1.0`,
+			expectedErrors: 1,
+			errorDesc:      "must be a SemVer version",
+		},
+		{
+			desc: "invalid kind and api version",
+			bundleConfig: `
+        apiVersion: zork.gke.io/v1alpha1
+        kind: Zork
+        setName: foo-set
+        version: 1.0.2`,
+			expectedErrors: 2,
+			errorDesc:      "must be Bundle",
+		},
+		{
+			desc: "invalid component",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: foo-set
+        version: 1.0.2
+        components:
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp`,
+			expectedErrors: 1,
+			errorDesc:      "components must have a Version",
+		},
+		{
+			desc: "duplicate components",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        setName: foo-set
+        version: 1.0.2
+        components:
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.0.2
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: bar-comp
+            version: 1.0.2
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.0.2`,
+			expectedErrors: 1,
+			errorDesc:      "ComponentSet.Spec.Components[2]: Duplicate value",
+		},
+		{
+			desc: "duplicate components without set name",
+			bundleConfig: `
+        apiVersion: bundle.gke.io/v1alpha1
+        kind: Bundle
+        version: 1.0.2
+        components:
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.0.2
+        - apiVersion: bundle.gke.io/v1alpha1
+          kind: Component
+          spec:
+            componentName: foo-comp
+            version: 1.0.2`,
+			expectedErrors: 2,
+			errorDesc:      "ComponentSet.Spec.Components[1]: Duplicate value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			b, err := converter.FromYAMLString(tc.bundleConfig).ToBundle()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			errs := Bundle(b)
+			if len(errs) != tc.expectedErrors {
+				t.Fatalf("got %v errors, expected %v: %v", len(errs), tc.expectedErrors, errs)
+			}
+			if err := testutil.CheckErrorCases(errs.ToAggregate(), tc.errorDesc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
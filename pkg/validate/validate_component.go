@@ -59,7 +59,7 @@ func Component(c *bundle.Component) field.ErrorList {
 
 	p := cPath(c.ComponentReference())
 
-	if nameErrs := validateName(p.Child("Spec", "ComponentName"), n); len(errs) > 0 {
+	if nameErrs := validateName(p.Child("Spec", "ComponentName"), n); len(nameErrs) > 0 {
 		errs = append(errs, nameErrs...)
 	}
 
@@ -81,7 +81,8 @@ func Component(c *bundle.Component) field.ErrorList {
 	return errs
 }
 
-// ComponentSet validates a component.
+// ComponentSet validates a component set. The components in the set must be
+// unique based on the combination of ComponentName and Version.
 func ComponentSet(cs *bundle.ComponentSet) field.ErrorList {
 	p := field.NewPath("ComponentSet")
 
@@ -99,6 +100,8 @@ func ComponentSet(cs *bundle.ComponentSet) field.ErrorList {
 		errs = append(errs, field.Required(p.Child("Spec", "Version"), "version is required"))
 	}
 
+	errs = append(errs, duplicateComponents(p.Child("Spec", "Components"), cs.Spec.Components)...)
+
 	if n == "" || ver == "" {
 		// Other validation relies on components having a unique name+version pair.
 		return errs
@@ -125,6 +128,23 @@ func ComponentSet(cs *bundle.ComponentSet) field.ErrorList {
 	return errs
 }
 
+// duplicateComponents validates that the component references of a
+// ComponentSet are unique. Only exact name and version pairs are duplicates: a
+// set may contain several versions of a component, for example so that a
+// bundle can carry both the current and the next version during an upgrade,
+// and the versions are told apart with find.ComponentFinder.Matching.
+func duplicateComponents(p *field.Path, refs []bundle.ComponentReference) field.ErrorList {
+	errs := field.ErrorList{}
+	seen := make(map[bundle.ComponentReference]bool)
+	for i, ref := range refs {
+		if seen[ref] {
+			errs = append(errs, field.Duplicate(p.Index(i), ref))
+		}
+		seen[ref] = true
+	}
+	return errs
+}
+
 func cPath(ref bundle.ComponentReference) *field.Path {
 	return field.NewPath("Component").Key(fmt.Sprintf("%v", ref))
 }
@@ -153,20 +153,24 @@ func (bw *BundleWrapper) AllComponents() []*bundle.Component {
 }
 
 // ExportAsObjects will export a Bundle as a ComponentSet and Components,
-// or a Component as unstructured Objects.
+// or a Component as unstructured Objects. If opts is non-nil, the options are
+// applied to every component before exporting.
 func (bw *BundleWrapper) ExportAsObjects(opts options.JSONOptions) ([]*unstructured.Unstructured, error) {
+	return bw.ExportAsObjectsWithOptionsFunc(options.SameForAll(opts))
+}
+
+// ExportAsObjectsWithOptionsFunc exports in the same manner as
+// ExportAsObjects, except that each component is rendered with the options
+// returned by optsFn. If optsFn returns nil options for a component, that
+// component is exported without applying options.
+func (bw *BundleWrapper) ExportAsObjectsWithOptionsFunc(optsFn options.ComponentOptionsFunc) ([]*unstructured.Unstructured, error) {
 	switch bw.Kind() {
 	case "Component":
-		components := bw.Component()
-		if opts != nil {
-			applier := multi.NewDefaultApplier()
-			comp, err := applier.ApplyOptions(components, opts)
-			if err != nil {
-				return nil, fmt.Errorf("failed to apply options: %v", err)
-			}
-			components = comp
+		comp, err := applyComponentOptions(bw.Component(), optsFn)
+		if err != nil {
+			return nil, err
 		}
-		return components.Spec.Objects, nil
+		return comp.Spec.Objects, nil
 	case "Bundle":
 		bun := bw.Bundle()
 		y, err := converter.FromObject(bun.ComponentSet()).ToYAML()
@@ -180,6 +184,10 @@ func (bw *BundleWrapper) ExportAsObjects(opts options.JSONOptions) ([]*unstructu
 		var objs []*unstructured.Unstructured
 		objs = append(objs, o)
 		for _, c := range bun.Components {
+			c, err := applyComponentOptions(c, optsFn)
+			if err != nil {
+				return nil, err
+			}
 			y, err := converter.FromObject(c).ToYAML()
 			if err != nil {
 				return nil, err
@@ -195,3 +203,20 @@ func (bw *BundleWrapper) ExportAsObjects(opts options.JSONOptions) ([]*unstructu
 		return nil, fmt.Errorf("bundle kind %q not supported for exporting", bw.Kind())
 	}
 }
+
+// applyComponentOptions applies the options for a component using the default
+// multi-applier, returning the original component if there are no options.
+func applyComponentOptions(comp *bundle.Component, optsFn options.ComponentOptionsFunc) (*bundle.Component, error) {
+	opts, err := optsFn(comp)
+	if err != nil {
+		return nil, fmt.Errorf("getting options for component %v: %v", comp.ComponentReference(), err)
+	}
+	if opts == nil {
+		return comp, nil
+	}
+	newComp, err := multi.NewDefaultApplier().ApplyOptions(comp, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply options: %v", err)
+	}
+	return newComp, nil
+}
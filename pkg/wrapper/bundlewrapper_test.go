@@ -19,6 +19,9 @@ package wrapper
 import (
 	"testing"
 
+	bundle "github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/apis/bundle/v1alpha1"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/converter"
+	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/options"
 	"github.com/GoogleCloudPlatform/k8s-cluster-bundle/pkg/testutil"
 )
 
@@ -113,3 +116,76 @@ kind: Component`,
 		})
 	}
 }
+
+func TestBundleWrapper_ExportAsObjectsWithOptionsFunc(t *testing.T) {
+	bun := `
+apiVersion: bundle.gke.io/v1alpha1
+kind: Bundle
+setName: test-set
+version: 1.0.0
+components:
+- apiVersion: bundle.gke.io/v1alpha1
+  kind: Component
+  spec:
+    componentName: comp-a
+    version: 1.0.0
+    objects:
+    - apiVersion: bundle.gke.io/v1alpha1
+      kind: ObjectTemplate
+      type: go-template
+      template: |
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: a-config
+          namespace: {{.Namespace}}
+- apiVersion: bundle.gke.io/v1alpha1
+  kind: Component
+  spec:
+    componentName: comp-b
+    version: 1.0.0
+    objects:
+    - apiVersion: bundle.gke.io/v1alpha1
+      kind: ObjectTemplate
+      type: go-template
+      template: |
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: b-config
+          namespace: {{.Namespace}}
+`
+	bw, err := FromRaw("yaml", []byte(bun))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped := options.JSONOptions{
+		"global": map[string]interface{}{"Namespace": "global-ns"},
+		"comp-b": map[string]interface{}{"Namespace": "b-ns"},
+	}
+	objs, err := bw.ExportAsObjectsWithOptionsFunc(options.ByComponentName(scoped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 3 {
+		t.Fatalf("got %d objects, expected a ComponentSet and two Components", len(objs))
+	}
+
+	expNamespaces := map[string]string{
+		"comp-a": "global-ns",
+		"comp-b": "b-ns",
+	}
+	for _, o := range objs[1:] {
+		comp := &bundle.Component{}
+		if err := converter.FromUnstructured(o).ToObject(comp); err != nil {
+			t.Fatal(err)
+		}
+		if len(comp.Spec.Objects) != 1 {
+			t.Fatalf("got %d objects for component %q, expected 1", len(comp.Spec.Objects), comp.Spec.ComponentName)
+		}
+		if got, exp := comp.Spec.Objects[0].GetNamespace(), expNamespaces[comp.Spec.ComponentName]; got != exp {
+			t.Errorf("got namespace %q for component %q, expected %q", got, comp.Spec.ComponentName, exp)
+		}
+	}
+}